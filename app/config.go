@@ -0,0 +1,619 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krmmzs/subtitle-matcher/subtitlematcher"
+)
+
+// Config holds the command line configuration, as parsed by ParseArgs
+type Config struct {
+	Directory   string
+	ExecuteMode bool
+	Diff        bool   // Print the dry run plan as a diff
+	DiffFile    string // Write the diff to this file instead of stdout
+	Script      bool   // Print the dry run plan as a rename script
+	ScriptFile  string // Write the script to this file instead of stdout
+	Plan        bool   // Print the dry run plan as a JSON plan for apply
+	PlanFile    string // Write the plan to this file instead of stdout
+	Table       bool   // Print the results as aligned tables instead of progress text
+	ByVideo     bool   // Print the results as JSON grouped by video instead of progress text
+	ByVideoFile string // Write the results grouped by video to this file instead of stdout
+	ApplyPlan   string // Execute the renames of this plan file
+	Calibrate   bool   // Run threshold calibration instead of matching
+	Check       bool   // Report the library's health instead of matching
+	Want        string // Comma-separated languages every video should have, reported by check
+	HistoryFile string // Record runs in this history file (an SQLite database for .db, .sqlite and .sqlite3)
+	ListRuns    bool   // List the runs recorded in the history file
+	ShowRun     string // Show the renames of this recorded run
+	UndoRun     string // Undo the renames of this recorded run
+	UndoFile    string // Undo the most recent recorded rename of this file
+	WebhookURL  string // POST a JSON summary here when subtitles matched
+	NtfyURL     string // Publish a summary to this ntfy topic when subtitles matched
+	Schedule    string // Run as a daemon on this cron schedule
+	MetricsAddr string // Serve Prometheus metrics on this address in daemon mode
+	Stdin       bool   // Read the files to match from stdin instead of scanning
+	NoColor     bool   // Never color the output
+	Template    string // yt-dlp output template the files were downloaded with
+	OpTimeout   string // Give up on each rename after this duration
+	RunTimeout  string // Stop each run after this duration
+	MaxOps      string // Refuse runs renaming more files than this without confirmation
+	UnsafeRoot  bool   // Allow renaming in file system roots and home directories
+	Protect     string // List of directories never renamed in, separated like PATH
+	Sure        string // Score from which matches are renamed without confirmation
+	HeldFile    string // Write the matches held for confirmation to this plan file
+	Force       bool   // Evaluate subtitles processed by earlier runs again
+	ChangedOnly bool   // Only report what changed since the previous recorded run
+	LineEnding  string // Rewrite renamed subtitles with these line endings (lf or crlf)
+	BOM         string // Strip or add the UTF-8 byte order mark of renamed subtitles
+	Incomplete  bool   // Ignore empty videos and videos still being downloaded
+	Settle      string // Ignore videos modified more recently than this duration
+	Samples     bool   // Match subtitles against sample clips and extras too
+	MinSize     string // Ignore videos smaller than this size, such as 200M
+	MinDuration string // Ignore videos shorter than this duration
+	Mode        string // Matching heuristics: auto, tv or movie
+	EpisodeMap  string // File translating absolute episode numbers into seasons
+	LangOrder   string // Comma-separated languages in the order they claim a video's subtitle name
+	Multi       string // What happens when several subtitles match one video: keep-all, keep-best or prompt
+	Cleanup     bool   // Move subtitles left alone as duplicates out of the library
+	CleanupTo   string // Folder duplicates are moved into, or "trash"
+	Threshold   string // Minimum similarity for a match
+	VideoExts   string // Comma-separated video extensions, replacing the defaults
+	SubExts     string // Comma-separated subtitle extensions, replacing the defaults
+	DebugDump   string // Write a trace of the run to this file for bug reports
+	NoSymbols   bool   // Strip emoji and other symbols from titles before comparison
+	Embedded    bool   // Check matches against the videos' embedded subtitle tracks
+	Semantic    string // Match translated titles by meaning with the embeddings API at this URL
+	SemModel    string // Embedding model for -semantic ("text-embedding-3-small" if empty)
+	OCR         string // Tesseract language image subtitles are read into .srt in ("eng" for -ocr)
+	Whisper     string // Generate subtitles for videos without one with whisper.cpp and this model
+	WhisperAPI  string // Generate subtitles for videos without one with the Whisper API at this URL
+	Translate   string // Write a machine-translated copy of matched subtitles in this language
+	TransURL    string // Translate with the LibreTranslate server at this URL rather than DeepL
+	Search      bool   // Search OpenSubtitles for videos without a subtitle
+	SearchLangs string // Comma-separated languages searched for, best first (LangOrder, or any, if empty)
+	Download    bool   // Download the best subtitle found by Search
+	Help        bool   // Print usage instead of matching
+
+	FileSystem          subtitlematcher.FileSystem  // Where Directory lives, set by Run
+	Files               []string                    // Files read from stdin, set by Run
+	Color               bool                        // Whether output is colored, set by Validate
+	Preset              subtitlematcher.TitlePreset // Title normalization preset (youtube unless -preset is given)
+	MediaMode           subtitlematcher.MediaMode   // Parsed Mode, set by Validate
+	MultiMatch          subtitlematcher.MultiMatch  // Parsed Multi, set by Validate
+	OutputTemplate      *subtitlematcher.Template   // Parsed Template, set by Validate
+	OperationTimeout    time.Duration               // Parsed OpTimeout, set by Validate
+	RunTimeLimit        time.Duration               // Parsed RunTimeout, set by Validate
+	MaxOperations       int                         // Parsed MaxOps, set by Validate
+	SureThreshold       float64                     // Parsed Sure, set by Validate
+	SimilarityThreshold float64                     // Parsed Threshold, set by Validate
+	SettleTime          time.Duration               // Parsed Settle, set by Validate
+	MinVideoSize        int64                       // Parsed MinSize, set by Validate
+	MinVideoDuration    time.Duration               // Parsed MinDuration, set by Validate
+	LineEndings         subtitlematcher.LineEnding  // Parsed LineEnding, set by Validate
+	ByteOrderMark       subtitlematcher.BOMMode     // Parsed BOM, set by Validate
+}
+
+// ParseArgs parses command line arguments, without the program name, into
+// a Config. Options of the profile selected with -profile come first, so
+// that the command line overrides them. Unknown options and arguments after
+// the directory are an error, so that a mistyped safety option such as
+// -max-ops is not silently ignored.
+func ParseArgs(args []string) (Config, error) {
+	var config Config
+
+	if len(args) >= 2 && args[0] == "apply" {
+		config.ApplyPlan = args[1]
+		args = args[2:]
+	}
+
+	profile, err := profileArgs(args)
+	if err != nil {
+		return config, err
+	}
+	args = append(profile, args...)
+
+	config.Directory = "."
+	config.ExecuteMode = false
+	config.Preset = subtitlematcher.PresetYouTube
+	directory := false
+	for _, arg := range args {
+		switch {
+		case config.parseOption(arg):
+		case strings.HasPrefix(arg, "-"):
+			return config, fmt.Errorf("unknown option %s, see -help", arg)
+		case directory:
+			return config, fmt.Errorf("unexpected argument %s: only one directory can be matched, see -help", arg)
+		default:
+			config.Directory = arg
+			directory = true
+		}
+	}
+
+	return config, nil
+}
+
+// parseOption sets the field of config for one command line option, and
+// reports whether arg is an option at all. The directory and other
+// arguments without a dash are not.
+func (config *Config) parseOption(arg string) bool {
+	switch {
+	case arg == "-h" || arg == "-help" || arg == "--help":
+		config.Help = true
+	case arg == "-execute" || arg == "--execute":
+		config.ExecuteMode = true
+	case arg == "-no-color" || arg == "--no-color":
+		config.NoColor = true
+	case arg == "-" || arg == "-stdin" || arg == "--stdin":
+		config.Stdin = true
+	case arg == "-calibrate" || arg == "--calibrate":
+		config.Calibrate = true
+	case arg == "-check" || arg == "--check":
+		config.Check = true
+	case strings.HasPrefix(arg, "-template=") || strings.HasPrefix(arg, "--template="):
+		config.Template = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-op-timeout=") || strings.HasPrefix(arg, "--op-timeout="):
+		config.OpTimeout = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-run-timeout=") || strings.HasPrefix(arg, "--run-timeout="):
+		config.RunTimeout = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-max-ops=") || strings.HasPrefix(arg, "--max-ops="):
+		config.MaxOps = arg[strings.Index(arg, "=")+1:]
+	case arg == "-allow-unsafe-root" || arg == "--allow-unsafe-root":
+		config.UnsafeRoot = true
+	case strings.HasPrefix(arg, "-protect=") || strings.HasPrefix(arg, "--protect="):
+		config.Protect = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-sure=") || strings.HasPrefix(arg, "--sure="):
+		config.Sure = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-held=") || strings.HasPrefix(arg, "--held="):
+		config.HeldFile = arg[strings.Index(arg, "=")+1:]
+	case arg == "-changed-only" || arg == "--changed-only":
+		config.ChangedOnly = true
+	case strings.HasPrefix(arg, "-line-endings=") || strings.HasPrefix(arg, "--line-endings="):
+		config.LineEnding = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-bom=") || strings.HasPrefix(arg, "--bom="):
+		config.BOM = arg[strings.Index(arg, "=")+1:]
+	case arg == "-skip-incomplete" || arg == "--skip-incomplete":
+		config.Incomplete = true
+	case strings.HasPrefix(arg, "-settle=") || strings.HasPrefix(arg, "--settle="):
+		config.Settle = arg[strings.Index(arg, "=")+1:]
+	case arg == "-include-samples" || arg == "--include-samples":
+		config.Samples = true
+	case arg == "-strip-emoji" || arg == "--strip-emoji":
+		config.NoSymbols = true
+	case arg == "-verify-embedded" || arg == "--verify-embedded":
+		config.Embedded = true
+	case strings.HasPrefix(arg, "-semantic=") || strings.HasPrefix(arg, "--semantic="):
+		config.Semantic = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-semantic-model=") || strings.HasPrefix(arg, "--semantic-model="):
+		config.SemModel = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-whisper=") || strings.HasPrefix(arg, "--whisper="):
+		config.Whisper = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-whisper-api=") || strings.HasPrefix(arg, "--whisper-api="):
+		config.WhisperAPI = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-translate=") || strings.HasPrefix(arg, "--translate="):
+		config.Translate = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-translate-url=") || strings.HasPrefix(arg, "--translate-url="):
+		config.TransURL = arg[strings.Index(arg, "=")+1:]
+	case arg == "-search-subs" || arg == "--search-subs":
+		config.Search = true
+	case strings.HasPrefix(arg, "-search-subs=") || strings.HasPrefix(arg, "--search-subs="):
+		config.Search = true
+		config.SearchLangs = arg[strings.Index(arg, "=")+1:]
+	case arg == "-download-subs" || arg == "--download-subs":
+		config.Download = true
+	case arg == "-ocr" || arg == "--ocr":
+		config.OCR = "eng"
+	case strings.HasPrefix(arg, "-ocr=") || strings.HasPrefix(arg, "--ocr="):
+		config.OCR = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-min-size=") || strings.HasPrefix(arg, "--min-size="):
+		config.MinSize = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-min-duration=") || strings.HasPrefix(arg, "--min-duration="):
+		config.MinDuration = arg[strings.Index(arg, "=")+1:]
+	case arg == "-force" || arg == "--force":
+		config.Force = true
+	case strings.HasPrefix(arg, "-mode=") || strings.HasPrefix(arg, "--mode="):
+		config.Mode = arg[strings.Index(arg, "=")+1:]
+	case arg == "-cleanup" || arg == "--cleanup":
+		config.Cleanup = true
+	case strings.HasPrefix(arg, "-cleanup=") || strings.HasPrefix(arg, "--cleanup="):
+		config.Cleanup = true
+		config.CleanupTo = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-debug-dump=") || strings.HasPrefix(arg, "--debug-dump="):
+		config.DebugDump = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-threshold=") || strings.HasPrefix(arg, "--threshold="):
+		config.Threshold = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-video-ext=") || strings.HasPrefix(arg, "--video-ext="):
+		config.VideoExts = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-sub-ext=") || strings.HasPrefix(arg, "--sub-ext="):
+		config.SubExts = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-multi=") || strings.HasPrefix(arg, "--multi="):
+		config.Multi = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-lang-priority=") || strings.HasPrefix(arg, "--lang-priority="):
+		config.LangOrder = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-episode-map=") || strings.HasPrefix(arg, "--episode-map="):
+		config.EpisodeMap = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-preset=") || strings.HasPrefix(arg, "--preset="):
+		config.Preset = subtitlematcher.TitlePreset(arg[strings.Index(arg, "=")+1:])
+	case strings.HasPrefix(arg, "-want=") || strings.HasPrefix(arg, "--want="):
+		config.Want = arg[strings.Index(arg, "=")+1:]
+	case arg == "-diff" || arg == "--diff":
+		config.Diff = true
+	case strings.HasPrefix(arg, "-diff=") || strings.HasPrefix(arg, "--diff="):
+		config.Diff = true
+		config.DiffFile = arg[strings.Index(arg, "=")+1:]
+	case arg == "-script" || arg == "--script":
+		config.Script = true
+	case strings.HasPrefix(arg, "-script=") || strings.HasPrefix(arg, "--script="):
+		config.Script = true
+		config.ScriptFile = arg[strings.Index(arg, "=")+1:]
+	case arg == "-table" || arg == "--table":
+		config.Table = true
+	case arg == "-by-video" || arg == "--by-video":
+		config.ByVideo = true
+	case strings.HasPrefix(arg, "-by-video=") || strings.HasPrefix(arg, "--by-video="):
+		config.ByVideo = true
+		config.ByVideoFile = arg[strings.Index(arg, "=")+1:]
+	case arg == "-plan" || arg == "--plan":
+		config.Plan = true
+	case strings.HasPrefix(arg, "-plan=") || strings.HasPrefix(arg, "--plan="):
+		config.Plan = true
+		config.PlanFile = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-history=") || strings.HasPrefix(arg, "--history="):
+		config.HistoryFile = arg[strings.Index(arg, "=")+1:]
+	case arg == "-runs" || arg == "--runs":
+		config.ListRuns = true
+	case strings.HasPrefix(arg, "-show-run=") || strings.HasPrefix(arg, "--show-run="):
+		config.ShowRun = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-undo-run=") || strings.HasPrefix(arg, "--undo-run="):
+		config.UndoRun = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-undo-file=") || strings.HasPrefix(arg, "--undo-file="):
+		config.UndoFile = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-webhook=") || strings.HasPrefix(arg, "--webhook="):
+		config.WebhookURL = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-ntfy=") || strings.HasPrefix(arg, "--ntfy="):
+		config.NtfyURL = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-schedule=") || strings.HasPrefix(arg, "--schedule="):
+		config.Schedule = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-metrics=") || strings.HasPrefix(arg, "--metrics="):
+		config.MetricsAddr = arg[strings.Index(arg, "=")+1:]
+	case strings.HasPrefix(arg, "-profile=") || strings.HasPrefix(arg, "--profile="),
+		strings.HasPrefix(arg, "-config=") || strings.HasPrefix(arg, "--config="):
+		// Read by profileArgs
+	default:
+		return false
+	}
+	return true
+}
+
+// Validate checks the options of a parsed Config and sets the fields parsed
+// from them, such as Preset, MediaMode and OutputTemplate, and Color.
+func (config *Config) Validate() error {
+	config.Color = useColor(*config)
+
+	preset, err := subtitlematcher.ParsePreset(string(config.Preset))
+	if err != nil {
+		return err
+	}
+	config.Preset = preset
+
+	if config.Mode != "" {
+		mode, err := subtitlematcher.ParseMediaMode(config.Mode)
+		if err != nil {
+			return err
+		}
+		config.MediaMode = mode
+	}
+
+	if config.Multi != "" {
+		policy, err := subtitlematcher.ParseMultiMatch(config.Multi)
+		if err != nil {
+			return err
+		}
+		config.MultiMatch = policy
+	}
+
+	if config.OperationTimeout, err = parseTimeout(config.OpTimeout); err != nil {
+		return err
+	}
+	if config.RunTimeLimit, err = parseTimeout(config.RunTimeout); err != nil {
+		return err
+	}
+
+	if config.Threshold != "" {
+		if config.SimilarityThreshold, err = strconv.ParseFloat(config.Threshold, 64); err != nil || config.SimilarityThreshold < 0 || config.SimilarityThreshold > 1 {
+			return fmt.Errorf("invalid -threshold %q: expected a score between 0 and 1", config.Threshold)
+		}
+	}
+
+	if config.LineEndings, config.ByteOrderMark, err = textNormalization(*config); err != nil {
+		return err
+	}
+
+	if config.ChangedOnly && config.HistoryFile == "" {
+		return errors.New("-changed-only needs -history=FILE to compare against the previous run")
+	}
+
+	if config.Whisper != "" && config.WhisperAPI != "" {
+		return errors.New("-whisper and -whisper-api cannot be combined")
+	}
+	if config.SemModel != "" && config.Semantic == "" {
+		return errors.New("-semantic-model needs -semantic=URL")
+	}
+	if config.MetricsAddr != "" && config.Schedule == "" {
+		return errors.New("-metrics needs -schedule=CRON, as metrics are only served in daemon mode")
+	}
+	if config.TransURL != "" && config.Translate == "" {
+		return errors.New("-translate-url needs -translate=LANG")
+	}
+	if config.Translate != "" && config.TransURL == "" && os.Getenv("DEEPL_AUTH_KEY") == "" {
+		return errors.New("-translate needs -translate-url=URL or a DeepL key in DEEPL_AUTH_KEY")
+	}
+	if config.Download && !config.Search {
+		return errors.New("-download-subs needs -search-subs")
+	}
+	if config.Search && os.Getenv("OPENSUBTITLES_API_KEY") == "" {
+		return errors.New("-search-subs needs an OpenSubtitles API key in OPENSUBTITLES_API_KEY")
+	}
+
+	if config.Settle != "" {
+		if config.SettleTime, err = time.ParseDuration(config.Settle); err != nil || config.SettleTime < 0 {
+			return fmt.Errorf("invalid -settle %q: expected a duration such as 30s or 5m", config.Settle)
+		}
+	}
+
+	if config.MinSize != "" {
+		if config.MinVideoSize, err = parseSize(config.MinSize); err != nil {
+			return fmt.Errorf("invalid -min-size %q: expected bytes, or a number with a K, M or G suffix", config.MinSize)
+		}
+	}
+
+	if config.MinDuration != "" {
+		if config.MinVideoDuration, err = time.ParseDuration(config.MinDuration); err != nil || config.MinVideoDuration < 0 {
+			return fmt.Errorf("invalid -min-duration %q: expected a duration such as 20m", config.MinDuration)
+		}
+	}
+
+	if config.Sure != "" {
+		if config.SureThreshold, err = strconv.ParseFloat(config.Sure, 64); err != nil || config.SureThreshold < 0 || config.SureThreshold > 1 {
+			return fmt.Errorf("invalid -sure %q: expected a score between 0 and 1", config.Sure)
+		}
+	}
+
+	if config.MaxOps != "" {
+		if config.MaxOperations, err = strconv.Atoi(config.MaxOps); err != nil || config.MaxOperations < 0 {
+			return fmt.Errorf("invalid -max-ops %q: expected a number of files", config.MaxOps)
+		}
+	}
+
+	if config.Template != "" {
+		template, err := subtitlematcher.ParseOutputTemplate(config.Template)
+		if err != nil {
+			return err
+		}
+		config.OutputTemplate = template
+	}
+
+	return nil
+}
+
+// useColor reports whether output is colored: only on terminals, and never
+// when NO_COLOR is set (https://no-color.org) or -no-color is given
+func useColor(config Config) bool {
+	if config.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ConfigFile holds the named profiles of the configuration file. Each profile
+// maps command line options, without their dash, to values:
+//
+//	{"profiles": {"anime": {"preset": "scene", "mode": "tv", "threshold": 0.5}}}
+//
+// true turns a switch such as "execute" on, false leaves it off, and lists
+// are joined with commas.
+type ConfigFile struct {
+	Profiles map[string]map[string]any `json:"profiles"`
+}
+
+// defaultConfigFile returns where the configuration file is read from unless
+// -config is given
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "subtitle-matcher", "config.json")
+}
+
+// profileArgs returns the options of the profile selected with -profile,
+// read from the file given with -config or the default configuration file,
+// as command line arguments. Without -profile there are none. Keys that are
+// not an option are an error naming the key.
+func profileArgs(args []string) ([]string, error) {
+	var name, path string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-profile=") || strings.HasPrefix(arg, "--profile="):
+			name = arg[strings.Index(arg, "=")+1:]
+		case strings.HasPrefix(arg, "-config=") || strings.HasPrefix(arg, "--config="):
+			path = arg[strings.Index(arg, "=")+1:]
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if path == "" {
+		path = defaultConfigFile()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var file ConfigFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	profile, ok := file.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q in %s", name, path)
+	}
+
+	options := make([]string, 0, len(profile))
+	for option := range profile {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	var expanded []string
+	for _, option := range options {
+		var arg string
+		switch value := profile[option].(type) {
+		case bool:
+			arg = "-" + option
+		case string, float64:
+			arg = "-" + option + "=" + profileValue(value)
+		case []any:
+			values := make([]string, len(value))
+			for i, v := range value {
+				values[i] = profileValue(v)
+			}
+			arg = "-" + option + "=" + strings.Join(values, ",")
+		default:
+			return nil, fmt.Errorf("invalid value for %q in profile %q", option, name)
+		}
+
+		// A misspelled key would otherwise be dropped without a word
+		var scratch Config
+		if !scratch.parseOption(arg) {
+			return nil, fmt.Errorf("unknown option %q in profile %q of %s", option, name, path)
+		}
+		// false leaves a switch off, as if the profile did not name it
+		if on, ok := profile[option].(bool); ok && !on {
+			continue
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded, nil
+}
+
+// profileValue returns a JSON value of a profile as command line text.
+// Numbers are written in full, as options such as -min-size and -max-ops
+// don't accept the exponent form fmt uses for large floats.
+func profileValue(value any) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// extensionList parses comma-separated file extensions, lowercased and with
+// their leading dot
+func extensionList(value string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+// parseSize parses a file size in bytes, optionally with a K, M or G suffix
+// for KiB, MiB or GiB
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(strings.ToUpper(value), "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(strings.ToUpper(value), "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(strings.ToUpper(value), "G"):
+		multiplier = 1 << 30
+	}
+	digits := value
+	if multiplier > 1 {
+		digits = value[:len(value)-1]
+	}
+
+	size, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected bytes, or a number with a K, M or G suffix", value)
+	}
+	return size * multiplier, nil
+}
+
+// parseTimeout parses an -op-timeout or -run-timeout duration, 0 when empty
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: expected a duration such as 30s or 5m", timeout)
+	}
+	return d, nil
+}
+
+// textNormalization returns the line endings and byte order mark selected
+// with -line-endings and -bom
+func textNormalization(config Config) (subtitlematcher.LineEnding, subtitlematcher.BOMMode, error) {
+	ending := subtitlematcher.LineEndingUnchanged
+	switch strings.ToLower(config.LineEnding) {
+	case "":
+	case "lf":
+		ending = subtitlematcher.LineEndingLF
+	case "crlf":
+		ending = subtitlematcher.LineEndingCRLF
+	default:
+		return 0, 0, fmt.Errorf("invalid -line-endings %q: expected lf or crlf", config.LineEnding)
+	}
+
+	bom := subtitlematcher.BOMUnchanged
+	switch strings.ToLower(config.BOM) {
+	case "":
+	case "strip":
+		bom = subtitlematcher.BOMStrip
+	case "add":
+		bom = subtitlematcher.BOMAdd
+	default:
+		return 0, 0, fmt.Errorf("invalid -bom %q: expected strip or add", config.BOM)
+	}
+	return ending, bom, nil
+}
+
+// processedMode skips the subtitles earlier runs finished with, unless -force
+// is given
+func processedMode(config Config) subtitlematcher.ProcessedMode {
+	if config.Force {
+		return subtitlematcher.ProcessedRecheck
+	}
+	return subtitlematcher.ProcessedSkip
+}
+
+// cleanupMode returns where -cleanup moves duplicates: into the given folder
+// (.duplicates by default), or to the trash with -cleanup=trash
+func cleanupMode(config Config) (subtitlematcher.CleanupMode, string) {
+	switch {
+	case !config.Cleanup:
+		return subtitlematcher.CleanupOff, ""
+	case config.CleanupTo == "trash":
+		return subtitlematcher.CleanupTrash, ""
+	}
+	return subtitlematcher.CleanupMove, config.CleanupTo
+}
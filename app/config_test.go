@@ -0,0 +1,50 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	profile := `{"profiles": {"big": {"min-size": 209715200, "max-ops": 1000000, "threshold": 0.5, "include-samples": false, "execute": true}}}`
+	if err := os.WriteFile(path, []byte(profile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseArgs([]string{"-profile=big", "-config=" + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if config.MinVideoSize != 209715200 || config.MaxOperations != 1000000 || config.SimilarityThreshold != 0.5 {
+		t.Errorf("numbers parsed as min-size %d, max-ops %d, threshold %v", config.MinVideoSize, config.MaxOperations, config.SimilarityThreshold)
+	}
+	if config.Samples || !config.ExecuteMode {
+		t.Errorf("switches parsed as include-samples %v, execute %v, want false and true", config.Samples, config.ExecuteMode)
+	}
+}
+
+func TestValidateRejectsMetricsWithoutSchedule(t *testing.T) {
+	config, err := ParseArgs([]string{".", "-metrics=:19090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Error("-metrics without -schedule accepted")
+	}
+}
+
+func TestParseArgsRejectsUnknownArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{".", "-max-op=50"},
+		{"videos", "more-videos"},
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%q) accepted the arguments", args)
+		}
+	}
+}
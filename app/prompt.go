@@ -0,0 +1,78 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/krmmzs/subtitle-matcher/subtitlematcher"
+)
+
+// confirmOperations returns the prompt approving runs over the -max-ops limit,
+// or nil to abort them when nobody can answer: in daemon mode, or when stdin
+// is not a terminal
+func confirmOperations(config Config) subtitlematcher.ConfirmFunc {
+	if !canAsk(config) {
+		return nil
+	}
+	limit := config.MaxOps
+	return func(planned int) bool {
+		return ask(fmt.Sprintf("About to rename %d files, more than the limit of %s. Continue?", planned, limit))
+	}
+}
+
+// reviewMatch returns the prompt approving matches below the -sure score one
+// by one, or nil to hold them all when nobody can answer
+func reviewMatch(config Config) subtitlematcher.ReviewFunc {
+	if !canAsk(config) {
+		return nil
+	}
+	return func(result subtitlematcher.MatchResult) bool {
+		return ask(fmt.Sprintf("Rename %s to %s (%.2f similarity)?",
+			filepath.Base(result.SubtitlePath), filepath.Base(result.NewSubtitlePath), result.Similarity))
+	}
+}
+
+// chooseSubtitle returns the prompt picking which of several subtitles
+// matching one video is renamed under -multi=prompt, or nil to hold them all
+// when nobody can answer
+func chooseSubtitle(config Config) subtitlematcher.ChooseFunc {
+	if !canAsk(config) {
+		return nil
+	}
+	return func(video string, candidates []subtitlematcher.MatchResult) int {
+		fmt.Printf("Several subtitles match %s:\n", filepath.Base(video))
+		for i, candidate := range candidates {
+			fmt.Printf("  %d) %s (%.2f similarity, quality %.2f)\n",
+				i+1, filepath.Base(candidate.SubtitlePath), candidate.Similarity, candidate.Quality)
+		}
+		fmt.Printf("Rename which one? [1-%d, empty to hold all] ", len(candidates))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		choice, err := strconv.Atoi(strings.TrimSpace(answer))
+		if err != nil || choice < 1 || choice > len(candidates) {
+			return -1
+		}
+		return choice - 1
+	}
+}
+
+// canAsk reports whether questions can be answered on stdin: not in daemon
+// mode, and only when stdin is a terminal
+func canAsk(config Config) bool {
+	if config.Schedule != "" {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ask asks a yes or no question on stdin, defaulting to no
+func ask(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,481 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krmmzs/subtitle-matcher/subtitlematcher"
+)
+
+// Run runs what a validated Config asks for: a match of its directory, or
+// one of the apply, daemon, check, calibration and history commands.
+// Cancelling ctx stops it.
+func Run(ctx context.Context, config Config) error {
+	if config.ListRuns || config.ShowRun != "" || config.UndoRun != "" || config.UndoFile != "" {
+		return runHistory(config)
+	}
+
+	if err := openFileSystem(&config); err != nil {
+		return err
+	}
+
+	if config.Stdin {
+		if err := readFileList(&config); err != nil {
+			return err
+		}
+	}
+
+	// Validate directory exists
+	if err := validateDirectory(config.FileSystem, config.Directory); err != nil {
+		return err
+	}
+
+	if config.ApplyPlan != "" {
+		return runApply(ctx, config)
+	}
+
+	if config.Schedule != "" {
+		return runDaemon(ctx, config)
+	}
+
+	if config.Check {
+		return runCheck(ctx, config)
+	}
+
+	if config.Calibrate {
+		return runCalibration(ctx, config)
+	}
+
+	return runMatch(ctx, config)
+}
+
+// runMatch matches the directory, renaming the subtitles with -execute
+func runMatch(ctx context.Context, config Config) error {
+	options := []subtitlematcher.Option{
+		subtitlematcher.DryRun(!config.ExecuteMode),
+		subtitlematcher.Verbose(true),
+		subtitlematcher.UseFileSystem(config.FileSystem),
+		subtitlematcher.Files(config.Files),
+		subtitlematcher.Color(config.Color),
+		subtitlematcher.Preset(config.Preset),
+		subtitlematcher.StripSymbols(config.NoSymbols),
+		subtitlematcher.Mode(config.MediaMode),
+		subtitlematcher.EpisodeMap(config.EpisodeMap),
+		subtitlematcher.OutputTemplate(config.OutputTemplate),
+	}
+
+	if config.Diff && !config.ExecuteMode {
+		diffOutput, closeDiff, err := openPlanOutput(config.DiffFile)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closeDiff()
+		options = append(options, subtitlematcher.DiffOutput(diffOutput))
+	}
+
+	if config.Script && !config.ExecuteMode {
+		scriptOutput, closeScript, err := openPlanOutput(config.ScriptFile)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closeScript()
+		options = append(options, subtitlematcher.ScriptOutput(scriptOutput, scriptShell(config.ScriptFile)))
+	}
+
+	if config.Table {
+		options = append(options, subtitlematcher.TableOutput(os.Stdout))
+	}
+
+	if config.ByVideo {
+		groupOutput, closeGroups, err := openPlanOutput(config.ByVideoFile)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closeGroups()
+		options = append(options, subtitlematcher.VideoGroupOutput(groupOutput))
+		if config.ByVideoFile == "" {
+			options = append(options, subtitlematcher.Verbose(false))
+		}
+	}
+
+	if config.Plan && !config.ExecuteMode {
+		planOutput, closePlan, err := openPlanOutput(config.PlanFile)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closePlan()
+		options = append(options, subtitlematcher.PlanOutput(planOutput))
+	}
+
+	if config.HeldFile != "" {
+		heldOutput, closeHeld, err := openPlanOutput(config.HeldFile)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closeHeld()
+		options = append(options, subtitlematcher.HeldPlanOutput(heldOutput))
+	}
+
+	if config.DebugDump != "" {
+		debugOutput, closeDebug, err := openPlanOutput(config.DebugDump)
+		if err != nil {
+			return fmt.Errorf("error in match: %w", err)
+		}
+		defer closeDebug()
+		options = append(options, subtitlematcher.DebugOutput(debugOutput))
+	}
+
+	options = append(options, serviceOptions(config)...)
+
+	matcher := subtitlematcher.New(config.Directory, options...)
+	if _, err := matcher.MatchContext(ctx); err != nil {
+		return fmt.Errorf("error in match: %w", err)
+	}
+	return nil
+}
+
+// serviceOptions returns the history and notification options selected on
+// the command line, from the values parsed by Validate
+func serviceOptions(config Config) []subtitlematcher.Option {
+	var options []subtitlematcher.Option
+	if config.HistoryFile != "" {
+		options = append(options, subtitlematcher.History(historyStore(config.HistoryFile)))
+	}
+	if config.WebhookURL != "" {
+		options = append(options, subtitlematcher.Notify(subtitlematcher.NewWebhookNotifier(config.WebhookURL)))
+	}
+	if config.NtfyURL != "" {
+		options = append(options, subtitlematcher.Notify(subtitlematcher.NewNtfyNotifier(config.NtfyURL)))
+	}
+	if config.OpTimeout != "" {
+		options = append(options, subtitlematcher.OperationTimeout(config.OperationTimeout))
+	}
+	if config.RunTimeout != "" {
+		options = append(options, subtitlematcher.RunTimeout(config.RunTimeLimit))
+	}
+	if config.MaxOps != "" {
+		options = append(options, subtitlematcher.MaxOperations(config.MaxOperations, confirmOperations(config)))
+	}
+	if config.Sure != "" {
+		options = append(options, subtitlematcher.SureThreshold(config.SureThreshold, reviewMatch(config)))
+	}
+	if config.Threshold != "" {
+		options = append(options, subtitlematcher.SimilarityThreshold(config.SimilarityThreshold))
+	}
+	if config.VideoExts != "" {
+		options = append(options, subtitlematcher.VideoExtensions(extensionList(config.VideoExts)))
+	}
+	if config.SubExts != "" {
+		options = append(options, subtitlematcher.SubtitleExtensions(extensionList(config.SubExts)))
+	}
+	options = append(options,
+		subtitlematcher.AllowUnsafeRoot(config.UnsafeRoot),
+		subtitlematcher.ProtectedPaths(filepath.SplitList(config.Protect)...),
+		subtitlematcher.TrackProcessed(processedMode(config)),
+		subtitlematcher.SkipIncomplete(config.Incomplete),
+		subtitlematcher.ExcludeSamples(!config.Samples),
+		subtitlematcher.OCR(config.OCR),
+		subtitlematcher.VerifyEmbedded(config.Embedded),
+	)
+	if config.Whisper != "" {
+		options = append(options, subtitlematcher.GenerateSubtitles(subtitlematcher.NewWhisperCppTranscriber(config.Whisper)))
+	}
+	if config.WhisperAPI != "" {
+		transcriber := subtitlematcher.NewWhisperAPITranscriber(config.WhisperAPI, os.Getenv("OPENAI_API_KEY"))
+		options = append(options, subtitlematcher.GenerateSubtitles(transcriber))
+	}
+	if config.Semantic != "" {
+		model := config.SemModel
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		embedder := subtitlematcher.NewOpenAIEmbedder(config.Semantic, os.Getenv("OPENAI_API_KEY"), model)
+		options = append(options, subtitlematcher.SemanticMatching(embedder, 0))
+	}
+	if config.Translate != "" {
+		var translator subtitlematcher.Translator = subtitlematcher.NewDeepLTranslator(os.Getenv("DEEPL_AUTH_KEY"))
+		if config.TransURL != "" {
+			translator = subtitlematcher.NewLibreTranslateTranslator(config.TransURL, os.Getenv("LIBRETRANSLATE_API_KEY"))
+		}
+		options = append(options, subtitlematcher.Translate(translator, config.Translate))
+	}
+	if config.Search {
+		languages := config.SearchLangs
+		if languages == "" {
+			languages = config.LangOrder
+		}
+		provider := subtitlematcher.NewOpenSubtitlesProvider(os.Getenv("OPENSUBTITLES_API_KEY"))
+		provider.Token = os.Getenv("OPENSUBTITLES_TOKEN")
+		options = append(options,
+			subtitlematcher.SearchSubtitles(strings.Split(languages, ","), provider),
+			subtitlematcher.DownloadSubtitles(config.Download),
+		)
+	}
+	if config.LangOrder != "" {
+		options = append(options, subtitlematcher.LanguagePriority(strings.Split(config.LangOrder, ",")))
+	}
+	if config.Multi != "" {
+		options = append(options, subtitlematcher.MultiMatchPolicy(config.MultiMatch, chooseSubtitle(config)))
+	}
+	options = append(options, subtitlematcher.CleanupDuplicates(cleanupMode(config)))
+	if config.Settle != "" {
+		options = append(options, subtitlematcher.SettleTime(config.SettleTime))
+	}
+	if config.MinSize != "" {
+		options = append(options, subtitlematcher.MinVideoSize(config.MinVideoSize))
+	}
+	if config.MinDuration != "" {
+		options = append(options, subtitlematcher.MinVideoDuration(config.MinVideoDuration))
+	}
+	if config.ChangedOnly {
+		options = append(options, subtitlematcher.Verbose(false), subtitlematcher.ChangesOutput(os.Stdout))
+	}
+	options = append(options, subtitlematcher.LineEndings(config.LineEndings), subtitlematcher.ByteOrderMark(config.ByteOrderMark))
+	return options
+}
+
+// openPlanOutput returns the writer for the dry run diff, script, plan or other output:
+// stdout, or the given file when a path is set
+func openPlanOutput(path string) (io.Writer, func() error, error) {
+	if path == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create %s: %w", path, err)
+	}
+	return file, file.Close, nil
+}
+
+// scriptShell returns the shell a rename script is written for: PowerShell
+// for .ps1 files, bash otherwise
+func scriptShell(path string) subtitlematcher.ScriptShell {
+	if strings.EqualFold(filepath.Ext(path), ".ps1") {
+		return subtitlematcher.ScriptPowerShell
+	}
+	return subtitlematcher.ScriptBash
+}
+
+// countSuccessfulRenames counts how many files were successfully renamed
+func countSuccessfulRenames(results []subtitlematcher.MatchResult) int {
+	count := 0
+	for _, result := range results {
+		if result.Renamed && result.Error == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// runApply executes exactly the renames of a plan file written with -plan
+func runApply(ctx context.Context, config Config) error {
+	file, err := os.Open(config.ApplyPlan)
+	if err != nil {
+		return fmt.Errorf("cannot open plan: %w", err)
+	}
+	plan, err := subtitlematcher.ReadPlan(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("=== Applying %s (%d renames) ===\n", config.ApplyPlan, len(plan.Renames))
+	options := []subtitlematcher.Option{
+		subtitlematcher.DryRun(false),
+		subtitlematcher.Verbose(true),
+		subtitlematcher.UseFileSystem(config.FileSystem),
+		subtitlematcher.Color(config.Color),
+	}
+	options = append(options, serviceOptions(config)...)
+
+	matcher := subtitlematcher.New(config.Directory, options...)
+	results, err := matcher.Apply(ctx, plan)
+	if err != nil {
+		return fmt.Errorf("error applying plan: %w", err)
+	}
+
+	fmt.Printf("Successfully processed %d subtitle files\n", countSuccessfulRenames(results))
+	return nil
+}
+
+// runCheck reports videos without subtitles, subtitles without videos,
+// language coverage and misnamed subtitles per directory
+func runCheck(ctx context.Context, config Config) error {
+	fmt.Println("=== Library check ===")
+	var wanted []string
+	if config.Want != "" {
+		wanted = strings.Split(config.Want, ",")
+	}
+	matcher := subtitlematcher.New(config.Directory,
+		subtitlematcher.UseFileSystem(config.FileSystem),
+		subtitlematcher.Files(config.Files),
+		subtitlematcher.Preset(config.Preset),
+		subtitlematcher.StripSymbols(config.NoSymbols),
+		subtitlematcher.Mode(config.MediaMode),
+		subtitlematcher.EpisodeMap(config.EpisodeMap),
+		subtitlematcher.OutputTemplate(config.OutputTemplate),
+		subtitlematcher.WantedLanguages(wanted...),
+	)
+	report, err := matcher.Check(ctx)
+	if err != nil {
+		return fmt.Errorf("error in check: %w", err)
+	}
+	return subtitlematcher.WriteCheckReport(os.Stdout, report)
+}
+
+// runCalibration reports how matching behaves at several thresholds and
+// suggests one
+func runCalibration(ctx context.Context, config Config) error {
+	fmt.Println("=== Threshold calibration ===")
+	matcher := subtitlematcher.New(config.Directory,
+		subtitlematcher.Verbose(false),
+		subtitlematcher.UseFileSystem(config.FileSystem),
+		subtitlematcher.Files(config.Files),
+		subtitlematcher.Preset(config.Preset),
+		subtitlematcher.StripSymbols(config.NoSymbols),
+		subtitlematcher.Mode(config.MediaMode),
+		subtitlematcher.EpisodeMap(config.EpisodeMap),
+		subtitlematcher.OutputTemplate(config.OutputTemplate),
+	)
+	calibration, err := matcher.Calibrate(ctx, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error in calibration: %w", err)
+	}
+
+	fmt.Printf("%-10s %8s %10s\n", "Threshold", "Matches", "Ambiguous")
+	for _, stats := range calibration.Stats {
+		fmt.Printf("%-10.2f %8d %10d\n", stats.Threshold, stats.Matches, stats.Ambiguous)
+	}
+	fmt.Printf("\nSuggested threshold: %.2f\n", calibration.Suggested)
+	return nil
+}
+
+// runHistory lists the runs recorded in the history file, shows the renames
+// of a single run or undoes recorded renames
+func runHistory(config Config) error {
+	if config.HistoryFile == "" {
+		return fmt.Errorf("-runs, -show-run, -undo-run and -undo-file need -history=FILE")
+	}
+	history := historyStore(config.HistoryFile)
+
+	if config.UndoRun != "" || config.UndoFile != "" {
+		return runUndo(history, config)
+	}
+
+	if config.ShowRun != "" {
+		run, err := subtitlematcher.FindRun(history, config.ShowRun)
+		if err != nil {
+			return fmt.Errorf("error in history: %w", err)
+		}
+		fmt.Printf("Run %s in %s (%s)\n", run.ID, run.Directory, run.Started.Format("2006-01-02 15:04:05"))
+		for _, result := range run.Renames() {
+			fmt.Printf("  %s -> %s\n", result.SubtitlePath, result.NewSubtitlePath)
+		}
+		return nil
+	}
+
+	runs, err := history.Runs()
+	if err != nil {
+		return fmt.Errorf("error in history: %w", err)
+	}
+	fmt.Printf("%-24s %-19s %-8s %8s  %s\n", "Run", "Started", "Mode", "Renamed", "Directory")
+	for _, run := range runs {
+		mode := "execute"
+		if run.Settings.DryRun {
+			mode = "dry-run"
+		}
+		fmt.Printf("%-24s %-19s %-8s %8d  %s\n",
+			run.ID, run.Started.Format("2006-01-02 15:04:05"), mode, len(run.Renames()), run.Directory)
+	}
+	return nil
+}
+
+// historyStore returns the history kept in path: an SQLite database for the
+// .db, .sqlite and .sqlite3 extensions, and a JSON lines file otherwise
+func historyStore(path string) subtitlematcher.HistoryStore {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return subtitlematcher.NewSQLiteHistory(path)
+	}
+	return subtitlematcher.NewFileHistory(path)
+}
+
+// runUndo reverts a recorded run or the last recorded rename of a file
+func runUndo(history subtitlematcher.HistoryStore, config Config) error {
+	var reversals []subtitlematcher.Reversal
+	if config.UndoRun != "" {
+		var err error
+		if reversals, err = subtitlematcher.UndoRun(history, config.UndoRun); err != nil {
+			return fmt.Errorf("error in undo: %w", err)
+		}
+	} else {
+		reversal, err := subtitlematcher.UndoFile(history, config.UndoFile)
+		if err != nil {
+			return fmt.Errorf("error in undo: %w", err)
+		}
+		reversals = append(reversals, reversal)
+	}
+
+	for _, reversal := range reversals {
+		if reversal.Error != nil {
+			fmt.Printf("  SKIPPED %s: %v\n", reversal.RenamedPath, reversal.Error)
+		} else {
+			fmt.Printf("  RESTORED %s -> %s\n", reversal.RenamedPath, reversal.SubtitlePath)
+		}
+	}
+	return nil
+}
+
+// runDaemon matches the directory on the configured schedule until
+// interrupted, optionally serving Prometheus metrics
+func runDaemon(ctx context.Context, config Config) error {
+	schedule, err := subtitlematcher.ParseSchedule(config.Schedule)
+	if err != nil {
+		return fmt.Errorf("error in daemon: %w", err)
+	}
+
+	options := []subtitlematcher.Option{
+		subtitlematcher.DryRun(!config.ExecuteMode),
+		subtitlematcher.Verbose(true),
+		subtitlematcher.UseFileSystem(config.FileSystem),
+		subtitlematcher.Files(config.Files),
+		subtitlematcher.Color(config.Color),
+		subtitlematcher.Preset(config.Preset),
+		subtitlematcher.StripSymbols(config.NoSymbols),
+		subtitlematcher.Mode(config.MediaMode),
+		subtitlematcher.EpisodeMap(config.EpisodeMap),
+		subtitlematcher.OutputTemplate(config.OutputTemplate),
+	}
+	options = append(options, serviceOptions(config)...)
+
+	if config.MetricsAddr != "" {
+		metrics := subtitlematcher.NewMetrics()
+		options = append(options, subtitlematcher.CollectMetrics(metrics))
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		go func() {
+			if err := http.ListenAndServe(config.MetricsAddr, mux); err != nil {
+				fmt.Printf("Error serving metrics: %v\n", err)
+			}
+		}()
+	}
+
+	fmt.Printf("Matching %s on schedule %q (Ctrl+C to stop)\n", config.Directory, config.Schedule)
+	matcher := subtitlematcher.New(config.Directory, options...)
+	err = subtitlematcher.RunScheduled(ctx, matcher, schedule, func(results []subtitlematcher.MatchResult, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	})
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
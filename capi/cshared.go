@@ -0,0 +1,36 @@
+//go:build cgo
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import "unsafe"
+
+// SubtitleMatcherMatch matches the subtitles in directory with the options
+// given as a JSON object (NULL or "" for the defaults) and returns the
+// results as JSON. The returned string must be released with
+// SubtitleMatcherFree.
+//
+//export SubtitleMatcherMatch
+func SubtitleMatcherMatch(directory, options *C.char) *C.char {
+	var optionsJSON string
+	if options != nil {
+		optionsJSON = C.GoString(options)
+	}
+	return C.CString(match(C.GoString(directory), optionsJSON))
+}
+
+// SubtitleMatcherFree releases a string returned by SubtitleMatcherMatch.
+//
+//export SubtitleMatcherFree
+func SubtitleMatcherFree(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// SubtitleMatcherVersion returns the version of the C API.
+//
+//export SubtitleMatcherVersion
+func SubtitleMatcherVersion() C.int {
+	return apiVersion
+}
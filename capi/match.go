@@ -0,0 +1,135 @@
+// Command capi builds the matching core for use from other languages: as a C
+// shared library with
+//
+//	go build -buildmode=c-shared -o libsubtitlematcher.so ./capi
+//
+// which also writes the libsubtitlematcher.h header, or as WebAssembly with
+//
+//	GOOS=js GOARCH=wasm go build -o subtitlematcher.wasm ./capi
+//
+// Both take the options and return the results as JSON, so that the API
+// stays the same as the library grows.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/krmmzs/subtitle-matcher/subtitlematcher"
+)
+
+// apiVersion is the version of the C and WebAssembly API, increased on
+// incompatible changes.
+const apiVersion = 1
+
+// matchOptions are the options a match is run with, decoded from JSON. Unset
+// options keep the library's defaults, except that nothing is printed.
+type matchOptions struct {
+	Threshold          *float64 `json:"threshold"`           // Minimum similarity for a match
+	Recursive          *bool    `json:"recursive"`           // Whether subdirectories are scanned
+	DryRun             *bool    `json:"dry_run"`             // Whether files are left untouched (true unless set to false)
+	Verbose            bool     `json:"verbose"`             // Whether progress is printed to stdout
+	VideoExtensions    []string `json:"video_extensions"`    // Video extensions, replacing the defaults
+	SubtitleExtensions []string `json:"subtitle_extensions"` // Subtitle extensions, replacing the defaults
+	Preset             string   `json:"preset"`              // Title normalization preset
+	StripSymbols       bool     `json:"strip_symbols"`       // Whether emoji and symbols are removed from titles
+	Mode               string   `json:"mode"`                // Matching heuristics: auto, tv or movie
+	MultiMatch         string   `json:"multi_match"`         // What happens when several subtitles match one video
+}
+
+// matchResponse is the JSON a match returns: its results, the same results
+// grouped by video, and its error when it failed. Results may be set along
+// with an error when only some subtitles failed.
+type matchResponse struct {
+	Results []subtitlematcher.MatchResult `json:"results"`
+	Videos  []subtitlematcher.VideoGroup  `json:"videos"`
+	Error   string                        `json:"error,omitempty"`
+}
+
+// options converts decoded options into library options.
+func (o matchOptions) options() ([]subtitlematcher.Option, error) {
+	options := []subtitlematcher.Option{
+		subtitlematcher.Verbose(o.Verbose),
+		subtitlematcher.StripSymbols(o.StripSymbols),
+	}
+	if o.Threshold != nil {
+		options = append(options, subtitlematcher.SimilarityThreshold(*o.Threshold))
+	}
+	if o.Recursive != nil {
+		options = append(options, subtitlematcher.Recursive(*o.Recursive))
+	}
+	if o.DryRun != nil {
+		options = append(options, subtitlematcher.DryRun(*o.DryRun))
+	}
+	if o.VideoExtensions != nil {
+		options = append(options, subtitlematcher.VideoExtensions(o.VideoExtensions))
+	}
+	if o.SubtitleExtensions != nil {
+		options = append(options, subtitlematcher.SubtitleExtensions(o.SubtitleExtensions))
+	}
+	if o.Preset != "" {
+		preset, err := subtitlematcher.ParsePreset(o.Preset)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, subtitlematcher.Preset(preset))
+	}
+	if o.Mode != "" {
+		mode, err := subtitlematcher.ParseMediaMode(o.Mode)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, subtitlematcher.Mode(mode))
+	}
+	if o.MultiMatch != "" {
+		policy, err := subtitlematcher.ParseMultiMatch(o.MultiMatch)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, subtitlematcher.MultiMatchPolicy(policy, nil))
+	}
+	return options, nil
+}
+
+// match matches the subtitles in directory with the JSON options, returning
+// the JSON response. An empty optionsJSON uses the defaults.
+func match(directory, optionsJSON string) string {
+	var decoded matchOptions
+	if optionsJSON != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &decoded); err != nil {
+			return respond(nil, fmt.Errorf("invalid options: %w", err))
+		}
+	}
+	options, err := decoded.options()
+	if err != nil {
+		return respond(nil, err)
+	}
+
+	results, err := subtitlematcher.New(directory, options...).MatchContext(context.Background())
+	return respond(results, err)
+}
+
+// respond encodes results and err as the JSON response.
+func respond(results []subtitlematcher.MatchResult, err error) string {
+	response := matchResponse{Results: results, Videos: subtitlematcher.GroupByVideo(results)}
+	if response.Results == nil {
+		response.Results = []subtitlematcher.MatchResult{}
+	}
+	if response.Videos == nil {
+		response.Videos = []subtitlematcher.VideoGroup{}
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Sprintf(`{"results":[],"videos":[],"error":%q}`, err.Error())
+	}
+	return string(data)
+}
+
+func main() {
+	serve()
+}
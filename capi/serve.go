@@ -0,0 +1,6 @@
+//go:build !(js && wasm)
+
+package main
+
+// serve does nothing: the C shared library is driven by its callers.
+func serve() {}
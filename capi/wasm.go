@@ -0,0 +1,48 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// serve registers the API on the JavaScript global object and keeps the
+// program running so that it can be called:
+//
+//	await subtitleMatcher.match(directory, optionsJSON) // the results as JSON
+//	subtitleMatcher.version                             // version of the API
+//
+// Files are read through the host's fs module, so matching works under
+// Node.js and Electron. As the fs module is asynchronous, match returns a
+// Promise.
+func serve() {
+	js.Global().Set("subtitleMatcher", js.ValueOf(map[string]any{
+		"match": js.FuncOf(func(this js.Value, args []js.Value) any {
+			var directory, optionsJSON string
+			if len(args) > 0 {
+				directory = args[0].String()
+			}
+			if len(args) > 1 && args[1].Type() == js.TypeString {
+				optionsJSON = args[1].String()
+			}
+
+			var run js.Func
+			run = js.FuncOf(func(this js.Value, promise []js.Value) any {
+				resolve := promise[0]
+				go func() {
+					defer run.Release()
+					if directory == "" {
+						resolve.Invoke(respond(nil, errors.New("missing directory")))
+						return
+					}
+					resolve.Invoke(match(directory, optionsJSON))
+				}()
+				return nil
+			})
+			return js.Global().Get("Promise").New(run)
+		}),
+		"version": apiVersion,
+	}))
+	select {}
+}
@@ -0,0 +1,13 @@
+// Command subtitle-matcher renames subtitle files to match their videos.
+// Run it with -help for its options.
+package main
+
+import (
+	"os"
+
+	"github.com/krmmzs/subtitle-matcher/app"
+)
+
+func main() {
+	os.Exit(app.Main(os.Args[1:]))
+}
@@ -0,0 +1,134 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// absolutePattern matches an anime-style absolute episode number in a
+// normalized title, as in "[group] show - 134 [1080p]" or "show - 134v2".
+var absolutePattern = regexp.MustCompile(`(?:^|\s)(-\s?(\d{1,4}))(?:v\d)?(?:[^0-9a-z]|$)`)
+
+// episodeMapLinePattern matches a line of an episode map file.
+var episodeMapLinePattern = regexp.MustCompile(`^(\d+)(?:\s*-\s*(\d+))?\s*->\s*[sS](\d{1,2})(?:[eE](\d{1,4}))?$`)
+
+// episodeRange maps a range of absolute episode numbers to a season.
+type episodeRange struct {
+	First, Last  int // Absolute numbers of the range, inclusive
+	Season       int // Season the range belongs to
+	FirstEpisode int // Seasonal number of the range's first episode
+}
+
+// episodeMap translates absolute episode numbers into seasonal ones.
+type episodeMap []episodeRange
+
+// loadEpisodeMap reads an episode map file. Each non-empty line that does
+// not start with '#' has one of the forms
+//
+//	1-24 -> S01       absolute 1 to 24 are S01E01 to S01E24
+//	25-48 -> S02      absolute 25 to 48 are S02E01 to S02E24
+//	49 -> S00E01      absolute 49 is the special S00E01
+//	50-60 -> S03E05   absolute 50 to 60 are S03E05 to S03E15
+func loadEpisodeMap(path string) (episodeMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var episodes episodeMap
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := episodeMapLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("%s:%d: expected \"first-last -> S01\" or \"number -> S01E02\"", path, lineNumber)
+		}
+		r := episodeRange{FirstEpisode: 1}
+		r.First, _ = strconv.Atoi(m[1])
+		r.Last = r.First
+		if m[2] != "" {
+			r.Last, _ = strconv.Atoi(m[2])
+		}
+		r.Season, _ = strconv.Atoi(m[3])
+		if m[4] != "" {
+			r.FirstEpisode, _ = strconv.Atoi(m[4])
+		}
+		if r.Last < r.First {
+			return nil, fmt.Errorf("%s:%d: range %d-%d ends before it starts", path, lineNumber, r.First, r.Last)
+		}
+		episodes = append(episodes, r)
+	}
+	return episodes, scanner.Err()
+}
+
+// seasonal returns the seasonal episode of an absolute episode number.
+func (m episodeMap) seasonal(absolute int) (episodeNumber, bool) {
+	for _, r := range m {
+		if absolute >= r.First && absolute <= r.Last {
+			return episodeNumber{Season: r.Season, Episode: r.FirstEpisode + absolute - r.First}, true
+		}
+	}
+	return episodeNumber{}, false
+}
+
+// findEpisodeMarker returns the episode of a normalized title and the span
+// of its marker. The season comes from the title, the file's season folder,
+// or the episode map for numbers without a season. Specials keep season 0.
+// With an episode map, anime-style absolute numbers ("Show - 134") count as
+// markers too.
+func (vsm *VideoSubtitleMatcher) findEpisodeMarker(normalizedTitle, path string) (episodeNumber, [2]int, bool) {
+	episode, span, ok := findEpisode(normalizedTitle)
+	if !ok && vsm.episodeMap != nil {
+		if m := absolutePattern.FindStringSubmatchIndex(normalizedTitle); m != nil {
+			number, _ := strconv.Atoi(normalizedTitle[m[4]:m[5]])
+			episode, span, ok = episodeNumber{Episode: number}, [2]int{m[2], m[3]}, true
+		}
+	}
+	if !ok {
+		return episodeNumber{}, [2]int{}, false
+	}
+
+	if episode.Season == 0 && !episode.Special && specialsFolder(path) {
+		episode.Special = true
+	}
+	if episode.Season == 0 && !episode.Special {
+		episode.Season = folderSeason(path)
+	}
+	if episode.Season == 0 && !episode.Special {
+		if seasonal, mapped := vsm.episodeMap.seasonal(episode.Episode); mapped {
+			episode = seasonal
+		}
+	}
+	return episode, span, true
+}
+
+// alignEpisodeMarker rewrites the episode marker of a subtitle title as the
+// video's when the episode map makes them the same episode, when both name
+// the same special, or when the video holds the subtitle's episode among
+// others, so that "show - 134" compares with "show s6e14", "show sp1" with
+// "show s0e1", and "show s1e2" with "show s1e1e2", as well as the video's own
+// marker would. Air dates naming the same day are aligned the same way.
+func (vsm *VideoSubtitleMatcher) alignEpisodeMarker(subtitleTitle, subtitlePath, videoTitle, videoPath string) string {
+	if aligned, ok := alignAirDate(subtitleTitle, videoTitle); ok {
+		return aligned
+	}
+	subtitleEpisode, subtitleSpan, subtitleOK := vsm.findEpisodeMarker(subtitleTitle, subtitlePath)
+	videoEpisode, videoSpan, videoOK := vsm.findEpisodeMarker(videoTitle, videoPath)
+	if !subtitleOK || !videoOK {
+		return subtitleTitle
+	}
+	mapped := (vsm.episodeMap != nil || subtitleEpisode.Special) && subtitleEpisode == videoEpisode
+	if !mapped && !(videoEpisode.multiEpisode() && videoEpisode.covers(subtitleEpisode)) {
+		return subtitleTitle
+	}
+	return subtitleTitle[:subtitleSpan[0]] + videoTitle[videoSpan[0]:videoSpan[1]] + subtitleTitle[subtitleSpan[1]:]
+}
@@ -0,0 +1,268 @@
+package subtitlematcher
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unrarPath is the unrar executable used to read RAR archives.
+const unrarPath = "unrar"
+
+// archiveExtensions are the subtitle archive formats that can be scanned.
+var archiveExtensions = []string{".zip", ".rar"}
+
+// archiveEntry locates a subtitle inside an archive.
+type archiveEntry struct {
+	archive string // Path of the archive
+	name    string // Path of the subtitle inside the archive
+	size    int64
+}
+
+// archiveFileSystem lets subtitles inside archives take part in matching as
+// if they had been extracted next to their archive. Each subtitle gets a
+// virtual path in the archive's directory: opening it reads from the archive,
+// renaming it extracts it to the new path, and removing it is a no-op so the
+// archive stays intact. All other paths are passed through.
+type archiveFileSystem struct {
+	FileSystem
+	*archiveIndex
+
+	ctx context.Context // Kills running unrar commands when done
+}
+
+// archiveIndex holds the subtitles registered by a scan. It is shared by an
+// archiveFileSystem and the copies of it bound to a context.
+type archiveIndex struct {
+	mu      sync.Mutex
+	entries map[string]archiveEntry // Virtual path → entry
+}
+
+// newArchiveFileSystem returns an archiveFileSystem with no archives
+// registered yet, passing through to fsys.
+func newArchiveFileSystem(fsys FileSystem) *archiveFileSystem {
+	return &archiveFileSystem{FileSystem: fsys, archiveIndex: &archiveIndex{}, ctx: context.Background()}
+}
+
+// WithContext returns a copy of the file system, sharing its registered
+// subtitles, whose commands are killed when ctx is done.
+func (a *archiveFileSystem) WithContext(ctx context.Context) FileSystem {
+	return &archiveFileSystem{FileSystem: withContext(ctx, a.FileSystem), archiveIndex: a.archiveIndex, ctx: ctx}
+}
+
+// isArchive reports whether a path has an archive extension.
+func isArchive(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, archiveExt := range archiveExtensions {
+		if ext == archiveExt {
+			return true
+		}
+	}
+	return false
+}
+
+// register lists the subtitles in an archive and returns their virtual
+// paths. Subtitles whose virtual path is already taken, by a real file or
+// by another archive's subtitle, are skipped.
+func (a *archiveFileSystem) register(ctx context.Context, archivePath string, isSubtitle func(string) bool) ([]string, error) {
+	fsys := withContext(ctx, a.FileSystem)
+	entries, err := listArchive(ctx, fsys, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.entries == nil {
+		a.entries = make(map[string]archiveEntry)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !isSubtitle(entry.name) {
+			continue
+		}
+		virtual := filepath.Join(filepath.Dir(archivePath), path.Base(entry.name))
+		if _, taken := a.entries[virtual]; taken || exists(fsys, virtual) {
+			continue
+		}
+		a.entries[virtual] = entry
+		paths = append(paths, virtual)
+	}
+	return paths, nil
+}
+
+// entry returns the archive entry behind a virtual path.
+func (a *archiveIndex) entry(path string) (archiveEntry, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[path]
+	return entry, ok
+}
+
+// Open reads a subtitle from its archive, or opens a regular file.
+func (a *archiveFileSystem) Open(path string) (io.ReadCloser, error) {
+	entry, ok := a.entry(path)
+	if !ok {
+		return a.FileSystem.Open(path)
+	}
+	data, err := readArchiveEntry(a.ctx, a.FileSystem, entry)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Rename extracts a subtitle from its archive, or renames a regular file.
+func (a *archiveFileSystem) Rename(oldPath, newPath string) error {
+	entry, ok := a.entry(oldPath)
+	if !ok {
+		return a.FileSystem.Rename(oldPath, newPath)
+	}
+	data, err := readArchiveEntry(a.ctx, a.FileSystem, entry)
+	if err != nil {
+		return err
+	}
+	return a.FileSystem.WriteFile(newPath, data)
+}
+
+// Remove leaves archived subtitles alone and removes regular files.
+func (a *archiveFileSystem) Remove(path string) error {
+	if _, ok := a.entry(path); ok {
+		return nil
+	}
+	return a.FileSystem.Remove(path)
+}
+
+// Stat describes an archived subtitle or a regular file.
+func (a *archiveFileSystem) Stat(path string) (fs.FileInfo, error) {
+	entry, ok := a.entry(path)
+	if !ok {
+		return a.FileSystem.Stat(path)
+	}
+	info, err := a.FileSystem.Stat(entry.archive)
+	if err != nil {
+		return nil, err
+	}
+	return remoteFileInfo{path: path, size: entry.size, modTime: info.ModTime()}, nil
+}
+
+// Chtimes passes through to the wrapped file system when it supports it.
+func (a *archiveFileSystem) Chtimes(path string, atime, mtime time.Time) error {
+	setter, ok := a.FileSystem.(timeSetter)
+	if !ok {
+		return errTimesUnsupported
+	}
+	return setter.Chtimes(path, atime, mtime)
+}
+
+// archiveOf returns the archive a subtitle is extracted from, if any.
+func (vsm *VideoSubtitleMatcher) archiveOf(subtitlePath string) string {
+	if archives, ok := vsm.fs.(*archiveFileSystem); ok {
+		if entry, ok := archives.entry(subtitlePath); ok {
+			return entry.archive
+		}
+	}
+	return ""
+}
+
+// listArchive lists the files in a ZIP or RAR archive.
+func listArchive(ctx context.Context, fsys FileSystem, archivePath string) ([]archiveEntry, error) {
+	if strings.EqualFold(filepath.Ext(archivePath), ".rar") {
+		return listRAR(ctx, fsys, archivePath)
+	}
+
+	reader, err := openZip(fsys, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	var entries []archiveEntry
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() {
+			entries = append(entries, archiveEntry{archive: archivePath, name: file.Name, size: int64(file.UncompressedSize64)})
+		}
+	}
+	return entries, nil
+}
+
+// readArchiveEntry returns the contents of one file in an archive.
+func readArchiveEntry(ctx context.Context, fsys FileSystem, entry archiveEntry) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(entry.archive), ".rar") {
+		return runUnrar(ctx, fsys, entry.archive, "p", "-inul", entry.archive, entry.name)
+	}
+
+	reader, err := openZip(fsys, entry.archive)
+	if err != nil {
+		return nil, err
+	}
+	file, err := reader.Open(entry.name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// openZip reads a whole ZIP archive into memory. Subtitle archives are small.
+func openZip(fsys FileSystem, archivePath string) (*zip.Reader, error) {
+	data, err := readFile(fsys, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// listRAR lists the files in a RAR archive with unrar.
+func listRAR(ctx context.Context, fsys FileSystem, archivePath string) ([]archiveEntry, error) {
+	out, err := runUnrar(ctx, fsys, archivePath, "lb", archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []archiveEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			entries = append(entries, archiveEntry{archive: archivePath, name: filepath.ToSlash(name)})
+		}
+	}
+	return entries, nil
+}
+
+// runUnrar runs unrar, which needs the archive on local disk.
+func runUnrar(ctx context.Context, fsys FileSystem, archivePath string, args ...string) ([]byte, error) {
+	if _, ok := fsys.(LocalFileSystem); !ok {
+		return nil, fmt.Errorf("cannot read %s: RAR archives must be on the local file system", archivePath)
+	}
+	out, err := exec.CommandContext(ctx, unrarPath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unrar failed: %w", err)
+	}
+	return out, nil
+}
+
+// reset forgets the subtitles registered by the previous scan.
+func (a *archiveIndex) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = nil
+}
+
+// isSubtitleName reports whether a file name has a subtitle extension.
+func (vsm *VideoSubtitleMatcher) isSubtitleName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, subtitleExt := range vsm.subtitleExtensions {
+		if ext == subtitleExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ambiguityMargin is the score gap between the best and second-best video
+// below which a match is considered ambiguous during calibration.
+const ambiguityMargin = 0.1
+
+// DefaultCalibrationThresholds are the thresholds evaluated when none are given.
+var DefaultCalibrationThresholds = []float64{0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+// ThresholdStats summarizes how matching behaves at one similarity threshold.
+type ThresholdStats struct {
+	Threshold      float64 // Similarity threshold evaluated
+	Matches        int     // Subtitles that would be matched
+	Ambiguous      int     // Matches whose runner-up video scored within ambiguityMargin
+	Correct        int     // Matches agreeing with the ground truth (only with ground truth)
+	FalsePositives int     // Matches disagreeing with the ground truth (only with ground truth)
+	Missed         int     // Ground truth pairs left unmatched (only with ground truth)
+}
+
+// Calibration is the outcome of evaluating several similarity thresholds.
+type Calibration struct {
+	Stats          []ThresholdStats // One entry per threshold, in ascending order
+	Suggested      float64          // Recommended similarity threshold
+	HasGroundTruth bool             // Whether false positives were measured against a ground truth
+}
+
+// Calibrate scores every subtitle once, through the same exact, video ID,
+// fuzzy and semantic passes as a run, and reports how many matches each
+// threshold would produce, suggesting a threshold. Subtitles listed in the
+// mappings file are left out, as no threshold applies to them. No files are
+// renamed.
+//
+// truth optionally maps subtitle paths to their known correct video paths,
+// for example taken from the results of a previously verified run. With a
+// ground truth, the suggested threshold is the one producing the most
+// correct matches without false positives; without one, it is the lowest
+// threshold at which no match is ambiguous.
+func (vsm *VideoSubtitleMatcher) Calibrate(ctx context.Context, thresholds []float64, truth map[string]string) (*Calibration, error) {
+	if len(thresholds) == 0 {
+		thresholds = DefaultCalibrationThresholds
+	}
+	thresholds = append([]float64(nil), thresholds...)
+	sort.Float64s(thresholds)
+
+	vsm = vsm.Clone()
+	vsm.candidateCount = 0
+	vsm.explain = false
+	videoFiles, subtitleFiles, err := vsm.scanFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan files: %w", err)
+	}
+
+	if vsm.embedder != nil {
+		if err := vsm.embedTitles(ctx, videoFiles, subtitleFiles); err != nil {
+			return nil, fmt.Errorf("failed to embed titles: %w", err)
+		}
+	}
+
+	var mappings mappingTable
+	if vsm.mappingFile != "" {
+		if mappings, err = loadMappings(vsm.mappingFile); err != nil {
+			return nil, fmt.Errorf("failed to load mappings: %w", err)
+		}
+	}
+
+	type scored struct {
+		subtitle, video string
+		score, runnerUp float64
+	}
+	candidates := make([]scored, 0, len(subtitleFiles))
+	for _, subtitlePath := range subtitleFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, ok := mappings.lookup(vsm.directory, subtitlePath); ok {
+			continue
+		}
+		result := vsm.scoredResult(subtitlePath, videoFiles)
+		candidates = append(candidates, scored{subtitlePath, result.VideoPath, result.Similarity, result.RunnerUpSimilarity})
+	}
+
+	calibration := &Calibration{HasGroundTruth: len(truth) > 0}
+	for _, threshold := range thresholds {
+		stats := ThresholdStats{Threshold: threshold}
+		for _, c := range candidates {
+			expected, known := truth[c.subtitle]
+			if c.score < threshold || c.video == "" {
+				if known {
+					stats.Missed++
+				}
+				continue
+			}
+
+			stats.Matches++
+			if c.score-c.runnerUp < ambiguityMargin {
+				stats.Ambiguous++
+			}
+			if known && expected == c.video {
+				stats.Correct++
+			} else if known {
+				stats.FalsePositives++
+			}
+		}
+		calibration.Stats = append(calibration.Stats, stats)
+	}
+
+	calibration.Suggested = suggestThreshold(calibration)
+	return calibration, nil
+}
+
+// suggestThreshold picks the recommended threshold from calibration stats.
+func suggestThreshold(calibration *Calibration) float64 {
+	stats := calibration.Stats
+	if len(stats) == 0 {
+		return 0
+	}
+
+	if calibration.HasGroundTruth {
+		best := stats[0]
+		for _, s := range stats[1:] {
+			if s.FalsePositives < best.FalsePositives ||
+				(s.FalsePositives == best.FalsePositives && s.Correct > best.Correct) {
+				best = s
+			}
+		}
+		return best.Threshold
+	}
+
+	for _, s := range stats {
+		if s.Ambiguous == 0 {
+			return s.Threshold
+		}
+	}
+	return stats[len(stats)-1].Threshold
+}
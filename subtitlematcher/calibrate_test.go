@@ -0,0 +1,30 @@
+package subtitlematcher
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCalibrateScoresLikeARun(t *testing.T) {
+	dir := writeFiles(t, "How to code [dQw4w9WgXcQ].mkv", "Captions [dQw4w9WgXcQ].srt")
+	vsm := New(dir, Verbose(false))
+
+	results, err := vsm.Match()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := matchOf(t, results, "Captions [dQw4w9WgXcQ].srt")
+	if result.Pass != PassID {
+		t.Fatalf("matched by %s pass, want %s", result.Pass, PassID)
+	}
+
+	truth := map[string]string{result.SubtitlePath: filepath.Join(dir, "How to code [dQw4w9WgXcQ].mkv")}
+	calibration, err := vsm.Calibrate(context.Background(), []float64{0.95}, truth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := calibration.Stats[0]; stats.Matches != 1 || stats.Correct != 1 {
+		t.Errorf("at 0.95: %d matches, %d correct; want the video ID match", stats.Matches, stats.Correct)
+	}
+}
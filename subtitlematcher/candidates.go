@@ -0,0 +1,21 @@
+package subtitlematcher
+
+import "sort"
+
+// Candidate is a video considered as a match for a subtitle.
+type Candidate struct {
+	VideoPath  string  `json:"video_path"` // Candidate video file path
+	Similarity float64 `json:"similarity"` // Similarity score (0.0-1.0)
+}
+
+// topCandidates returns the n highest scoring candidates, best first.
+func topCandidates(candidates []Candidate, n int) []Candidate {
+	ranked := append([]Candidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Similarity > ranked[j].Similarity
+	})
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
@@ -0,0 +1,149 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ChangeKind classifies how a subtitle's result differs from the previous run.
+type ChangeKind string
+
+const (
+	// ChangeNew marks a subtitle the previous run did not see.
+	ChangeNew ChangeKind = "new"
+	// ChangeMatched marks a subtitle that is matched now but was unmatched,
+	// held, invalid or failed in the previous run.
+	ChangeMatched ChangeKind = "matched"
+	// ChangeBroken marks a subtitle that is unmatched, invalid or failed now
+	// but was matched in the previous run, or that failed or turned invalid
+	// since.
+	ChangeBroken ChangeKind = "broken"
+)
+
+// Change is a result that differs from the previous run.
+type Change struct {
+	Kind   ChangeKind  // How the result changed
+	Result MatchResult // Result of the current run
+}
+
+// CompareRuns returns the results that are new, newly matched or newly broken
+// compared to the results of previous runs, in the order of results. When
+// previous holds the results of several runs, oldest first, each subtitle is
+// compared to its latest result, so that subtitles skipped by later runs
+// (see TrackProcessed) are not reported as new. A subtitle a previous run
+// renamed is recognized under its new name.
+func CompareRuns(previous, results []MatchResult) []Change {
+	before := make(map[string]MatchResult, len(previous))
+	for _, result := range previous {
+		before[result.SubtitlePath] = result
+		if result.Renamed && !failed(result) {
+			before[result.NewSubtitlePath] = result
+		}
+	}
+
+	var changes []Change
+	for _, result := range results {
+		old, seen := before[result.SubtitlePath]
+		switch {
+		case !seen:
+			changes = append(changes, Change{Kind: ChangeNew, Result: result})
+		case isMatched(result) && !isMatched(old):
+			changes = append(changes, Change{Kind: ChangeMatched, Result: result})
+		case isMatched(old) && !isMatched(result), isBroken(result) && !isBroken(old):
+			changes = append(changes, Change{Kind: ChangeBroken, Result: result})
+		}
+	}
+	return changes
+}
+
+// isMatched reports whether a result found a video and nothing went wrong.
+func isMatched(result MatchResult) bool {
+	return result.NewSubtitlePath != "" && !failed(result) && !result.Invalid && !result.Held && result.DuplicateOf == ""
+}
+
+// isBroken reports whether a subtitle failed or was found invalid.
+func isBroken(result MatchResult) bool {
+	return failed(result) || result.Invalid
+}
+
+// failed reports whether an error occurred for a result, including results
+// reloaded from a HistoryStore that kept only the ErrorMessage.
+func failed(result MatchResult) bool {
+	return result.Error != nil || result.ErrorMessage != ""
+}
+
+// WriteChanges writes the changes since the run with ID since, one per line
+// with its kind and the subtitle's name, target or error:
+//
+//	Changes since run 20261016T101500Z-3fa2:
+//	  new      Episode 3.srt -> Show S01E03.srt
+//	  broken   Episode 1.srt: no match
+//
+// An empty since means there was no previous run.
+func WriteChanges(w io.Writer, since string, changes []Change) error {
+	header := "Changes since run " + since + ":"
+	switch {
+	case since == "":
+		header = "No previous run, every subtitle is new:"
+	case len(changes) == 0:
+		header = "No changes since run " + since
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		result := change.Result
+		detail := resultStatus(result)
+		if failed(result) {
+			detail = result.ErrorMessage
+			if result.Error != nil {
+				detail = result.Error.Error()
+			}
+		}
+		line := fmt.Sprintf("%s: %s", filepath.Base(result.SubtitlePath), detail)
+		if isMatched(result) {
+			line = fmt.Sprintf("%s -> %s", filepath.Base(result.SubtitlePath), filepath.Base(result.NewSubtitlePath))
+		}
+		if _, err := fmt.Fprintf(w, "  %-8s %s\n", change.Kind, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeChanges writes the changes since the runs recorded in the history for
+// the directory. It does nothing without a history.
+func (vsm *VideoSubtitleMatcher) writeChanges(results []MatchResult) error {
+	if vsm.changesOutput == nil || vsm.history == nil {
+		return nil
+	}
+
+	runs, err := vsm.history.Runs()
+	if err != nil {
+		return err
+	}
+	// Without a previous run, since stays empty and every result is new
+	since := ""
+	var previous []MatchResult
+	directory := vsm.recordedDirectory()
+	for _, run := range runs {
+		if run.Directory == directory {
+			since = run.ID
+			previous = append(previous, run.Results...)
+		}
+	}
+
+	// Compare in the form runs are recorded in, but report the results as given
+	recorded := vsm.recordedResults(results)
+	given := make(map[string]MatchResult, len(results))
+	for i, result := range recorded {
+		given[result.SubtitlePath] = results[i]
+	}
+	changes := CompareRuns(previous, recorded)
+	for i := range changes {
+		changes[i].Result = given[changes[i].Result.SubtitlePath]
+	}
+	return WriteChanges(vsm.changesOutput, since, changes)
+}
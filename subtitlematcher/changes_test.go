@@ -0,0 +1,101 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareRunsWithReloadedErrors(t *testing.T) {
+	dir := t.TempDir()
+	failedRename := MatchResult{
+		SubtitlePath:    "/media/a.srt",
+		NewSubtitlePath: "/media/Movie.srt",
+		VideoPath:       "/media/Movie.mkv",
+		Similarity:      0.9,
+		Renamed:         true,
+		Error:           errors.New("permission denied"),
+	}
+
+	history := NewFileHistory(filepath.Join(dir, "history.jsonl"))
+	if err := history.Record(Run{ID: newRunID(time.Now()), Results: []MatchResult{failedRename}}); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := history.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := runs[0].Results
+	if previous[0].ErrorMessage != "permission denied" || previous[0].Error == nil {
+		t.Fatalf("error not kept: %q, %v", previous[0].ErrorMessage, previous[0].Error)
+	}
+	if renames := runs[0].Renames(); len(renames) != 0 {
+		t.Errorf("failed rename listed as a rename: %v", renames)
+	}
+
+	// Reloaded without the error value, as other stores may do
+	previous[0].Error = nil
+
+	fixed := failedRename
+	fixed.Error = nil
+	changes := CompareRuns(previous, []MatchResult{fixed})
+	if len(changes) != 1 || changes[0].Kind != ChangeMatched {
+		t.Errorf("rename fixed since: got %v, want it matched", changes)
+	}
+
+	stillFailing := failedRename
+	stillFailing.Error = errors.New("permission denied")
+	if changes := CompareRuns(previous, []MatchResult{stillFailing}); len(changes) != 0 {
+		t.Errorf("rename failing again: got %v, want no change", changes)
+	}
+
+	// The failed rename did not move the subtitle to its new name
+	renamedSince := MatchResult{SubtitlePath: "/media/Movie.srt", NewSubtitlePath: "/media/Movie.srt"}
+	if changes := CompareRuns(previous, []MatchResult{renamedSince}); len(changes) != 1 || changes[0].Kind != ChangeNew {
+		t.Errorf("subtitle at the failed target: got %v, want it new", changes)
+	}
+}
+
+// chdir changes the working directory to dir for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestHistoryRecordsAbsolutePaths(t *testing.T) {
+	dir := writeFiles(t, "The Great Escape 1963.mkv", "great.escape.1963.srt")
+	history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	chdir(t, dir)
+
+	var changes strings.Builder
+	for i := 0; i < 2; i++ {
+		changes.Reset()
+		if _, err := New(".", Verbose(false), History(history), ChangesOutput(&changes)).Match(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := history.Runs()
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("recorded runs = %d, %v", len(runs), err)
+	}
+	result := runs[1].Results[0]
+	recorded, _ := filepath.EvalSymlinks(runs[1].Directory)
+	want, _ := filepath.EvalSymlinks(dir)
+	if recorded != want || !filepath.IsAbs(result.SubtitlePath) || !filepath.IsAbs(result.NewSubtitlePath) || !filepath.IsAbs(result.VideoPath) {
+		t.Errorf("recorded %s with %+v, want absolute paths in %s", runs[1].Directory, result, dir)
+	}
+	if strings.Contains(changes.String(), "great.escape") {
+		t.Errorf("second run reported changes:\n%s", changes.String())
+	}
+}
@@ -0,0 +1,235 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// undeterminedLanguage is the language reported for subtitles whose name
+// carries no language code.
+const undeterminedLanguage = "und"
+
+// MisnamedSubtitle is a subtitle matched to a video but not named after it.
+type MisnamedSubtitle struct {
+	Subtitle string // Path of the subtitle
+	Expected string // Path the subtitle would be renamed to
+}
+
+// VideoCoverage lists the languages a video has subtitles in.
+type VideoCoverage struct {
+	Video     string   // Path of the video
+	Languages []string // Languages of the video's subtitles, sorted ("und" when unknown)
+	Missing   []string // Wanted languages the video has no subtitle in
+}
+
+// DirectoryCheck describes the health of one directory of a library.
+type DirectoryCheck struct {
+	Directory              string             // Directory checked
+	Videos                 int                // Videos in the directory
+	Subtitles              int                // Subtitles in the directory
+	VideosWithoutSubtitles []string           // Videos no subtitle was matched to
+	SubtitlesWithoutVideos []string           // Subtitles that matched no video
+	Misnamed               []MisnamedSubtitle // Subtitles not following the naming convention
+	Languages              map[string]int     // Videos with a subtitle in each language ("und" when unknown)
+	Coverage               []VideoCoverage    // Subtitle languages of each video, sorted by video
+}
+
+// Healthy reports whether the directory has no problems.
+func (d DirectoryCheck) Healthy() bool {
+	if len(d.VideosWithoutSubtitles) > 0 || len(d.SubtitlesWithoutVideos) > 0 || len(d.Misnamed) > 0 {
+		return false
+	}
+	for _, coverage := range d.Coverage {
+		if len(coverage.Missing) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckReport is the outcome of checking a library.
+type CheckReport struct {
+	Directories []DirectoryCheck // One entry per directory with videos or subtitles, sorted by path
+}
+
+// Healthy reports whether no directory has problems.
+func (r *CheckReport) Healthy() bool {
+	for _, dir := range r.Directories {
+		if !dir.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// Check matches every subtitle like a dry run and reports, per directory,
+// videos without subtitles, subtitles without videos, language coverage and
+// subtitles not named after their video. Videos lacking a subtitle in one of
+// the WantedLanguages are reported too. No files are renamed and nothing is
+// logged. Subtitles inside archives are ignored.
+func (vsm *VideoSubtitleMatcher) Check(ctx context.Context) (*CheckReport, error) {
+	vsm = vsm.Clone()
+	vsm.verbose = false
+
+	videoFiles, subtitleFiles, err := vsm.scanFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan files: %w", err)
+	}
+
+	var mappings mappingTable
+	if vsm.mappingFile != "" {
+		if mappings, err = loadMappings(vsm.mappingFile); err != nil {
+			return nil, fmt.Errorf("failed to load mappings: %w", err)
+		}
+	}
+
+	dirs := make(map[string]*DirectoryCheck)
+	dirOf := func(path string) *DirectoryCheck {
+		dir := filepath.Dir(path)
+		if dirs[dir] == nil {
+			dirs[dir] = &DirectoryCheck{Directory: dir, Languages: make(map[string]int)}
+		}
+		return dirs[dir]
+	}
+
+	languages := make(map[string]map[string]bool)
+	for _, subtitlePath := range subtitleFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result := vsm.processSubtitleFile(ctx, subtitlePath, videoFiles, mappings)
+		if result.Archive != "" {
+			continue
+		}
+
+		dir := dirOf(subtitlePath)
+		dir.Subtitles++
+		if result.NewSubtitlePath == "" || result.VideoPath == "" {
+			dir.SubtitlesWithoutVideos = append(dir.SubtitlesWithoutVideos, subtitlePath)
+			continue
+		}
+		if !followsConvention(result) {
+			dir.Misnamed = append(dir.Misnamed, MisnamedSubtitle{Subtitle: subtitlePath, Expected: result.NewSubtitlePath})
+		}
+
+		language := result.Language
+		if language == "" {
+			language = undeterminedLanguage
+		}
+		if languages[result.VideoPath] == nil {
+			languages[result.VideoPath] = make(map[string]bool)
+		}
+		languages[result.VideoPath][language] = true
+	}
+
+	for _, videoPath := range videoFiles {
+		dir := dirOf(videoPath)
+		dir.Videos++
+		if len(languages[videoPath]) == 0 {
+			dir.VideosWithoutSubtitles = append(dir.VideosWithoutSubtitles, videoPath)
+		}
+
+		coverage := VideoCoverage{Video: videoPath}
+		for language := range languages[videoPath] {
+			dir.Languages[language]++
+			coverage.Languages = append(coverage.Languages, language)
+		}
+		sort.Strings(coverage.Languages)
+		for _, language := range vsm.wantedLanguages {
+			if _, ok := dir.Languages[language]; !ok {
+				dir.Languages[language] = 0
+			}
+			if !languages[videoPath][language] {
+				coverage.Missing = append(coverage.Missing, language)
+			}
+		}
+		dir.Coverage = append(dir.Coverage, coverage)
+	}
+
+	report := &CheckReport{}
+	for _, dir := range dirs {
+		sort.Strings(dir.VideosWithoutSubtitles)
+		sort.Strings(dir.SubtitlesWithoutVideos)
+		sort.Slice(dir.Misnamed, func(i, j int) bool { return dir.Misnamed[i].Subtitle < dir.Misnamed[j].Subtitle })
+		sort.Slice(dir.Coverage, func(i, j int) bool { return dir.Coverage[i].Video < dir.Coverage[j].Video })
+		report.Directories = append(report.Directories, *dir)
+	}
+	sort.Slice(report.Directories, func(i, j int) bool {
+		return report.Directories[i].Directory < report.Directories[j].Directory
+	})
+	return report, nil
+}
+
+// followsConvention reports whether a matched subtitle already has the name
+// it would be renamed to, optionally followed by its language code as media
+// players expect, e.g. "Movie.en.srt" for "Movie.mkv".
+func followsConvention(result MatchResult) bool {
+	if result.SubtitlePath == result.NewSubtitlePath {
+		return true
+	}
+	if result.Language == "" {
+		return false
+	}
+	ext := filepath.Ext(result.NewSubtitlePath)
+	return result.SubtitlePath == strings.TrimSuffix(result.NewSubtitlePath, ext)+"."+result.Language+ext
+}
+
+// WriteCheckReport writes a check report as text, one block per directory:
+//
+//	/videos/Show (3 videos, 3 subtitles)
+//	  Languages: en 2/3, und 1/3
+//	  Misnamed: show 1x01.srt -> Show.S01E01.srt
+//	  Without subtitles: Show.S01E03.mkv
+//	  Missing languages: Show.S01E02.mkv (zh)
+//	  Without videos: extras.srt
+//
+// Directories without problems are reported on one line ending in "OK".
+func WriteCheckReport(w io.Writer, report *CheckReport) error {
+	var b strings.Builder
+	for i, dir := range report.Directories {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%d videos, %d subtitles)", dir.Directory, dir.Videos, dir.Subtitles)
+		if dir.Healthy() {
+			b.WriteString(" OK")
+		}
+		b.WriteString("\n")
+
+		if dir.Videos > 0 && len(dir.Languages) > 0 {
+			codes := make([]string, 0, len(dir.Languages))
+			for code := range dir.Languages {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			coverage := make([]string, len(codes))
+			for j, code := range codes {
+				coverage[j] = fmt.Sprintf("%s %d/%d", code, dir.Languages[code], dir.Videos)
+			}
+			fmt.Fprintf(&b, "  Languages: %s\n", strings.Join(coverage, ", "))
+		}
+		for _, misnamed := range dir.Misnamed {
+			fmt.Fprintf(&b, "  Misnamed: %s -> %s\n", filepath.Base(misnamed.Subtitle), filepath.Base(misnamed.Expected))
+		}
+		for _, video := range dir.VideosWithoutSubtitles {
+			fmt.Fprintf(&b, "  Without subtitles: %s\n", filepath.Base(video))
+		}
+		for _, coverage := range dir.Coverage {
+			// Videos without any subtitle are already listed above
+			if len(coverage.Missing) > 0 && len(coverage.Languages) > 0 {
+				fmt.Fprintf(&b, "  Missing languages: %s (%s)\n", filepath.Base(coverage.Video), strings.Join(coverage.Missing, ", "))
+			}
+		}
+		for _, subtitle := range dir.SubtitlesWithoutVideos {
+			fmt.Fprintf(&b, "  Without videos: %s\n", filepath.Base(subtitle))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
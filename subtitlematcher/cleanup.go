@@ -0,0 +1,305 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// CleanupMode controls what happens to the subtitles left alone because a
+// better one kept the name they were planned for.
+type CleanupMode int
+
+const (
+	// CleanupOff leaves duplicates where they are.
+	CleanupOff CleanupMode = iota
+	// CleanupMove moves duplicates into a cleanup folder.
+	CleanupMove
+	// CleanupTrash moves duplicates to the user's trash.
+	CleanupTrash
+)
+
+// defaultCleanupFolder is where CleanupMove puts duplicates when no folder is
+// given, relative to the matched directory.
+const defaultCleanupFolder = ".duplicates"
+
+// inCleanupFolder reports whether path lies in the cleanup folder, whose
+// subtitles are never matched again.
+func (vsm *VideoSubtitleMatcher) inCleanupFolder(path string) bool {
+	if vsm.cleanup != CleanupMove {
+		return false
+	}
+	rel, err := filepath.Rel(vsm.cleanupFolderPath(), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cleanupFolderPath returns the cleanup folder, resolved against the matched
+// directory when relative.
+func (vsm *VideoSubtitleMatcher) cleanupFolderPath() string {
+	if filepath.IsAbs(vsm.cleanupFolder) {
+		return vsm.cleanupFolder
+	}
+	return filepath.Join(vsm.directory, vsm.cleanupFolder)
+}
+
+// cleanupDuplicate moves a duplicate subtitle out of the library, along with
+// the .sub of a VobSub index, recording where it went in CleanedUpPath.
+// Subtitles still inside an archive are left alone, as are duplicates on
+// file systems other than local disk.
+func (vsm *VideoSubtitleMatcher) cleanupDuplicate(result MatchResult) MatchResult {
+	if vsm.cleanup == CleanupOff || vsm.dryRun || result.Archive != "" || !vsm.isLocal() {
+		return result
+	}
+
+	paths := []string{result.SubtitlePath}
+	if result.PairedPath != "" {
+		paths = append(paths, result.PairedPath)
+	}
+
+	var path string
+	var err error
+	switch vsm.cleanup {
+	case CleanupMove:
+		path, err = vsm.moveToCleanupFolder(paths)
+	case CleanupTrash:
+		path, err = moveToTrash(paths)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("failed to clean up duplicate: %w", err)
+		if vsm.verbose && !vsm.writesPlan() {
+			vsm.printf(colorRed, "  Error cleaning up: %v\n", err)
+		}
+		return result
+	}
+
+	result.CleanedUpPath = path
+	if vsm.verbose && !vsm.writesPlan() {
+		vsm.printf(colorGray, "  Moved to %s\n", path)
+	}
+	return result
+}
+
+// moveToCleanupFolder moves a subtitle and its companion files into the
+// cleanup folder, keeping its path relative to the matched directory so that
+// duplicates from different folders don't collide. It returns where the
+// subtitle went.
+func (vsm *VideoSubtitleMatcher) moveToCleanupFolder(paths []string) (string, error) {
+	rel, err := filepath.Rel(vsm.directory, paths[0])
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(paths[0])
+	}
+	target, err := freePath(filepath.Join(vsm.cleanupFolderPath(), rel), paths[1:]...)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	return target, moveAll(paths, target, vsm.fs.Rename)
+}
+
+// moveToTrash moves a subtitle and its companion files to the user's trash:
+// ~/.Trash on macOS, and the freedesktop.org trash elsewhere, with the info
+// files desktop environments need to restore them. Files on another mount
+// than the home directory go to the trash at the top of that mount, as the
+// specification asks, so they need not be copied. It returns where the
+// subtitle went.
+func moveToTrash(paths []string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		target, err := freePath(filepath.Join(home, ".Trash", filepath.Base(paths[0])), paths[1:]...)
+		if err != nil {
+			return "", err
+		}
+		return target, moveAll(paths, target, moveFile)
+	case "windows":
+		return "", errors.New("moving to the trash is not supported on Windows")
+	}
+
+	absolute, err := filepath.Abs(paths[0])
+	if err != nil {
+		return "", err
+	}
+	trash := filepath.Join(home, ".local", "share", "Trash")
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		trash = filepath.Join(dataHome, "Trash")
+	}
+	topdir, onOtherMount := otherMount(absolute, home)
+	if onOtherMount {
+		trash = topdirTrash(topdir)
+	}
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(trash, dir), 0700); err != nil {
+			return "", err
+		}
+	}
+
+	target, err := freePath(filepath.Join(trash, "files", filepath.Base(absolute)), paths[1:]...)
+	if err != nil {
+		return "", err
+	}
+	var infoPaths []string
+	removeInfos := func() {
+		for _, infoPath := range infoPaths {
+			os.Remove(infoPath)
+		}
+	}
+	for _, path := range paths {
+		original, err := filepath.Abs(path)
+		if err != nil {
+			removeInfos()
+			return "", err
+		}
+		// The trash of a mount records paths relative to its top
+		if onOtherMount {
+			if rel, err := filepath.Rel(topdir, original); err == nil {
+				original = rel
+			}
+		}
+		info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: original}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		infoPath := filepath.Join(trash, "info", filepath.Base(withExt(target, filepath.Ext(path)))+".trashinfo")
+		if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+			removeInfos()
+			return "", err
+		}
+		infoPaths = append(infoPaths, infoPath)
+	}
+	if err := moveAll(paths, target, moveFile); err != nil {
+		removeInfos()
+		return "", err
+	}
+	return target, nil
+}
+
+// otherMount returns the top directory of the mount holding path, and
+// whether that is another mount than the one of the home directory.
+func otherMount(path, home string) (string, bool) {
+	device, ok := deviceOf(filepath.Dir(path))
+	homeDevice, homeOK := deviceOf(home)
+	if !ok || !homeOK || device == homeDevice {
+		return "", false
+	}
+
+	topdir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(topdir)
+		if parent == topdir {
+			return topdir, true
+		}
+		if parentDevice, ok := deviceOf(parent); !ok || parentDevice != device {
+			return topdir, true
+		}
+		topdir = parent
+	}
+}
+
+// topdirTrash returns the user's trash at the top of a mount: the user's
+// directory in an administrator-made $topdir/.Trash with the sticky bit set,
+// and otherwise $topdir/.Trash-$uid.
+func topdirTrash(topdir string) string {
+	uid := strconv.Itoa(os.Getuid())
+	shared := filepath.Join(topdir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+		return filepath.Join(shared, uid)
+	}
+	return filepath.Join(topdir, ".Trash-"+uid)
+}
+
+// moveAll moves the files at paths to target, companion files keeping their
+// own extension (the .sub of a VobSub .idx goes to target's name with .sub).
+// Files already moved are moved back when one fails.
+func moveAll(paths []string, target string, move func(from, to string) error) error {
+	for i, path := range paths {
+		if err := move(path, withExt(target, filepath.Ext(path))); err != nil {
+			for _, moved := range paths[:i] {
+				move(withExt(target, filepath.Ext(moved)), moved)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile renames a file, or copies it and removes the original when it
+// is on another device than the target.
+func moveFile(from, to string) error {
+	err := os.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(to)
+		return err
+	}
+	os.Chtimes(to, info.ModTime(), info.ModTime())
+	return os.Remove(from)
+}
+
+// freePath returns path, or when it is taken, the first of "name 2.ext",
+// "name 3.ext", ... that is free. The names of companions, the name with the
+// extension of each of them, must be free too.
+func freePath(path string, companions ...string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := path
+		if n > 1 {
+			candidate = base + " " + strconv.Itoa(n) + ext
+		}
+		free, err := pathsFree(candidate, companions)
+		if err != nil {
+			return "", err
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+}
+
+// pathsFree reports whether no file exists at path or at the path's name
+// with the extension of any of the companions.
+func pathsFree(path string, companions []string) (bool, error) {
+	for _, other := range append([]string{path}, companions...) {
+		_, err := os.Stat(withExt(path, filepath.Ext(other)))
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return true, nil
+}
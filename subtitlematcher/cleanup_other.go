@@ -0,0 +1,8 @@
+//go:build !unix
+
+package subtitlematcher
+
+// deviceOf reports false: devices are only told apart on Unix.
+func deviceOf(path string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package subtitlematcher
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceOf returns the ID of the device holding path.
+func deviceOf(path string) (uint64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
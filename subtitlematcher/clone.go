@@ -0,0 +1,43 @@
+package subtitlematcher
+
+// Clone returns a copy of the matcher with the same configuration but its
+// own per-run state, such as the caches of video titles filled by a scan.
+//
+// A configured matcher is safe for concurrent use: Match, MatchContext,
+// MatchStream and Calibrate each run on a clone of it. Outputs shared by
+// those runs (DiffOutput, ScriptOutput, NDJSONOutput, verbose logs) are
+// written to concurrently, so give each run its own writers when that matters.
+func (vsm *VideoSubtitleMatcher) Clone() *VideoSubtitleMatcher {
+	clone := *vsm
+
+	clone.normalizedVideos = &titleCache{}
+	clone.strippedVideos = &titleCache{}
+	clone.videoIDs = &titleCache{}
+	if vsm.metadataTitles != nil {
+		clone.metadataTitles = &titleCache{}
+	}
+	if vsm.nfoTitles != nil {
+		clone.nfoTitles = &titleCache{}
+	}
+	if vsm.embeddedTracks != nil {
+		clone.embeddedTracks = &trackCache{}
+	}
+	if archiveFS, ok := vsm.fs.(*archiveFileSystem); ok {
+		clone.fs = newArchiveFileSystem(archiveFS.FileSystem)
+	}
+	clone.videoIndex = nil
+	clone.titleVectors = nil
+
+	return &clone
+}
+
+// WithDirectory returns a clone of the matcher that works on directory, so
+// that one configured matcher can serve many directories:
+//
+//	base := subtitlematcher.New("", subtitlematcher.DryRun(false))
+//	results, err := base.WithDirectory("/media/tv/show").Match()
+func (vsm *VideoSubtitleMatcher) WithDirectory(directory string) *VideoSubtitleMatcher {
+	clone := vsm.Clone()
+	clone.directory = directory
+	return clone
+}
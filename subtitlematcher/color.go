@@ -0,0 +1,48 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANSI escape sequences used to color verbose output.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+)
+
+// printf prints verbose output in color when coloring is enabled. Leading
+// and trailing newlines are kept outside the colored text.
+func (vsm *VideoSubtitleMatcher) printf(color, format string, args ...any) {
+	fmt.Print(vsm.paint(color, fmt.Sprintf(format, args...)))
+}
+
+// paint wraps text in color when coloring is enabled.
+func (vsm *VideoSubtitleMatcher) paint(color, text string) string {
+	if !vsm.color || color == "" {
+		return text
+	}
+	body := strings.TrimLeft(text, "\n")
+	line := strings.TrimRight(body, "\n")
+	if line == "" {
+		return text
+	}
+	return text[:len(text)-len(body)] + color + line + colorReset + body[len(line):]
+}
+
+// statusColor returns the color of a result status as described by resultStatus.
+func statusColor(status string) string {
+	switch status {
+	case "renamed", "converted", "extracted", "downloaded":
+		return colorGreen
+	case "would rename", "held", "would download":
+		return colorYellow
+	case "error", "invalid", "rejected":
+		return colorRed
+	default:
+		return colorGray
+	}
+}
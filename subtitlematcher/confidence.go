@@ -0,0 +1,75 @@
+package subtitlematcher
+
+import "fmt"
+
+// Confidence classifies how trustworthy a match is, combining the score, the
+// margin over the runner-up video and agreement of episode numbers and years.
+type Confidence int
+
+const (
+	// ConfidenceLow marks matches that should be skipped or reviewed carefully:
+	// below the threshold, nearly tied with another video, or with conflicting
+	// episode numbers or years.
+	ConfidenceLow Confidence = iota
+	// ConfidenceMedium marks matches that are likely right but worth a review.
+	ConfidenceMedium
+	// ConfidenceHigh marks matches that are safe to apply automatically.
+	ConfidenceHigh
+)
+
+const (
+	// highConfidenceScore is the score at or above which a clear match is High.
+	highConfidenceScore = 0.9
+	// highConfidenceMargin is the lead over the runner-up needed for High on score alone.
+	highConfidenceMargin = 0.15
+	// mediumConfidenceMargin is the lead over the runner-up needed for Medium,
+	// or for High when episode numbers or years agree.
+	mediumConfidenceMargin = 0.05
+)
+
+// String returns the confidence level name.
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Confidence(%d)", int(c))
+}
+
+// MarshalText encodes the confidence level by name.
+func (c Confidence) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a confidence level name written by MarshalText.
+func (c *Confidence) UnmarshalText(text []byte) error {
+	for _, level := range []Confidence{ConfidenceLow, ConfidenceMedium, ConfidenceHigh} {
+		if string(text) == level.String() {
+			*c = level
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown confidence level %q", text)
+}
+
+// classifyConfidence determines the confidence of a match.
+// agreement is the result of signalAgreement for the subtitle and video titles.
+func classifyConfidence(score, runnerUp, threshold float64, agreement int) Confidence {
+	margin := score - runnerUp
+
+	switch {
+	case score < threshold || agreement < 0:
+		return ConfidenceLow
+	case score >= highConfidenceScore && margin >= highConfidenceMargin:
+		return ConfidenceHigh
+	case agreement > 0 && margin >= mediumConfidenceMargin:
+		return ConfidenceHigh
+	case margin >= mediumConfidenceMargin:
+		return ConfidenceMedium
+	}
+	return ConfidenceLow
+}
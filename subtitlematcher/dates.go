@@ -0,0 +1,104 @@
+package subtitlematcher
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// airDatePatterns match the air dates of daily shows in a normalized title,
+// in the order tried: "2024.3.15", "15-3-2024" (or "3-15-2024"),
+// "20240315", "15 march 2024" and "march 15, 2024". Zero padding has already
+// been stripped.
+var airDatePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[^0-9])((?:19|20)\d{2})[ .-](\d{1,2})[ .-](\d{1,2})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^0-9])(\d{1,2})[ .-](\d{1,2})[ .-]((?:19|20)\d{2})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^0-9])((?:19|20)\d{2})(\d{2})(\d{2})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^0-9])(\d{1,2})(?:st|nd|rd|th)?[ .-](jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?[ .-]((?:19|20)\d{2})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^a-z])(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?[ .-](\d{1,2})(?:st|nd|rd|th)?,?[ .-]((?:19|20)\d{2})(?:[^0-9]|$)`),
+}
+
+// monthNames are the abbreviations the air date patterns capture.
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// findAirDates returns the air dates a normalized title may carry and the
+// span of the date. A date whose day and month are both 12 or below, such as
+// "3-4-2024", may be read either way, so both readings are returned.
+func findAirDates(normalizedTitle string) ([]time.Time, [2]int, bool) {
+	for i, pattern := range airDatePatterns {
+		m := pattern.FindStringSubmatchIndex(normalizedTitle)
+		if m == nil {
+			continue
+		}
+		number := func(n int) int {
+			field := normalizedTitle[m[2*n]:m[2*n+1]]
+			if value, err := strconv.Atoi(field); err == nil {
+				return value
+			}
+			return slices.Index(monthNames, field) + 1
+		}
+
+		var dates []time.Time
+		switch i {
+		case 0, 2:
+			dates = validDates(date(number(1), number(2), number(3)))
+		case 1:
+			dates = validDates(date(number(3), number(2), number(1)), date(number(3), number(1), number(2)))
+		case 3:
+			dates = validDates(date(number(3), number(2), number(1)))
+		case 4:
+			dates = validDates(date(number(3), number(1), number(2)))
+		}
+		if len(dates) == 0 {
+			continue
+		}
+		return dates, [2]int{m[2], m[len(m)-1]}, true
+	}
+	return nil, [2]int{}, false
+}
+
+// date returns a calendar date, or the zero time for an impossible one.
+func date(year, month, day int) time.Time {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}
+	}
+	return t
+}
+
+// validDates returns the possible dates among dates, without duplicates.
+func validDates(dates ...time.Time) []time.Time {
+	var valid []time.Time
+	for _, d := range dates {
+		if !d.IsZero() && (len(valid) == 0 || !valid[0].Equal(d)) {
+			valid = append(valid, d)
+		}
+	}
+	return valid
+}
+
+// shareAirDate reports whether two lists of possible air dates have one in common.
+func shareAirDate(a, b []time.Time) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Equal(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// alignAirDate rewrites the air date of a subtitle title as the video's when
+// they name the same day, so that "show 15-3-2024" compares with
+// "show 2024.3.15" as well as "show 2024.3.15" would. It reports false when
+// either title has no date or the dates differ.
+func alignAirDate(subtitleTitle, videoTitle string) (string, bool) {
+	subtitleDates, subtitleSpan, subtitleOK := findAirDates(subtitleTitle)
+	videoDates, videoSpan, videoOK := findAirDates(videoTitle)
+	if !subtitleOK || !videoOK || !shareAirDate(subtitleDates, videoDates) {
+		return subtitleTitle, false
+	}
+	return subtitleTitle[:subtitleSpan[0]] + videoTitle[videoSpan[0]:videoSpan[1]] + subtitleTitle[subtitleSpan[1]:], true
+}
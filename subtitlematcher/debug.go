@@ -0,0 +1,84 @@
+package subtitlematcher
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// debugScoreFloor is the lowest score recorded among the pairwise scores of
+// a debug trace, which would otherwise list every subtitle against every
+// video.
+const debugScoreFloor = 0.2
+
+// DebugTrace is the structured trace of a run written to DebugOutput, meant
+// to be attached to bug reports about wrong matches.
+type DebugTrace struct {
+	Started   time.Time     `json:"started"`   // When the run started
+	Directory string        `json:"directory"` // Directory that was matched
+	Settings  RunSettings   `json:"settings"`  // Options the run used
+	Videos    []DebugFile   `json:"videos"`    // Videos found by the scan
+	Subtitles []DebugFile   `json:"subtitles"` // Subtitles found by the scan
+	Scores    []DebugScore  `json:"scores"`    // Scores of every subtitle and video pair above the floor, best first per subtitle
+	Decisions []MatchResult `json:"decisions"` // What was planned for each subtitle
+}
+
+// DebugFile is a scanned file with the title it is compared by.
+type DebugFile struct {
+	Path       string `json:"path"`       // File path
+	Normalized string `json:"normalized"` // Normalized title
+}
+
+// DebugScore is the similarity of one subtitle and video pair.
+type DebugScore struct {
+	Subtitle   string  `json:"subtitle"`   // Subtitle path
+	Video      string  `json:"video"`      // Video path
+	Similarity float64 `json:"similarity"` // Similarity score (0.0-1.0)
+}
+
+// debugTrace builds the trace of a run from its scanned files and planned
+// results.
+func (vsm *VideoSubtitleMatcher) debugTrace(started time.Time, videoFiles, subtitleFiles []string, planned []MatchResult) DebugTrace {
+	trace := DebugTrace{
+		Started:   started,
+		Directory: vsm.directory,
+		Settings:  vsm.runSettings(),
+		Videos:    make([]DebugFile, 0, len(videoFiles)),
+		Subtitles: make([]DebugFile, 0, len(subtitleFiles)),
+		Scores:    []DebugScore{},
+		Decisions: planned,
+	}
+
+	for _, videoPath := range videoFiles {
+		trace.Videos = append(trace.Videos, DebugFile{Path: videoPath, Normalized: vsm.normalizedVideo(videoPath)})
+	}
+
+	for _, subtitlePath := range subtitleFiles {
+		trace.Subtitles = append(trace.Subtitles, DebugFile{
+			Path:       subtitlePath,
+			Normalized: vsm.normalizeTitle(subtitleTitle(subtitlePath)),
+		})
+
+		score := vsm.candidateScorer(subtitlePath)
+		var scores []DebugScore
+		for _, videoPath := range videoFiles {
+			if similarity, ok := score(videoPath); ok && similarity >= debugScoreFloor {
+				scores = append(scores, DebugScore{Subtitle: subtitlePath, Video: videoPath, Similarity: similarity})
+			}
+		}
+		sort.SliceStable(scores, func(i, j int) bool {
+			return scores[i].Similarity > scores[j].Similarity
+		})
+		trace.Scores = append(trace.Scores, scores...)
+	}
+
+	return trace
+}
+
+// writeDebugTrace writes the trace of a run to the debug output as indented
+// JSON.
+func (vsm *VideoSubtitleMatcher) writeDebugTrace(started time.Time, videoFiles, subtitleFiles []string, planned []MatchResult) error {
+	encoder := json.NewEncoder(vsm.debugOutput)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(vsm.debugTrace(started, videoFiles, subtitleFiles, planned))
+}
@@ -0,0 +1,54 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// WriteDiff writes the renames in results as a diff-style plan grouped by
+// directory, with "- old name" and "+ new name" lines:
+//
+//	@@ /videos/Season 1 @@
+//	- Episode_1_-_YouTube-zh-CN-dual-double.srt
+//	+ Episode_1_[ABC123].srt
+//
+// Unmatched, invalid and already correctly named subtitles are omitted.
+func WriteDiff(w io.Writer, results []MatchResult) error {
+	var renames []MatchResult
+	for _, result := range results {
+		if result.NewSubtitlePath != "" && result.NewSubtitlePath != result.SubtitlePath && !result.Invalid {
+			renames = append(renames, result)
+		}
+	}
+
+	sort.SliceStable(renames, func(i, j int) bool {
+		di, dj := filepath.Dir(renames[i].SubtitlePath), filepath.Dir(renames[j].SubtitlePath)
+		if di != dj {
+			return di < dj
+		}
+		return renames[i].SubtitlePath < renames[j].SubtitlePath
+	})
+
+	currentDir := ""
+	for i, result := range renames {
+		dir := filepath.Dir(result.SubtitlePath)
+		if i == 0 || dir != currentDir {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "@@ %s @@\n", dir); err != nil {
+				return err
+			}
+			currentDir = dir
+		}
+
+		if _, err := fmt.Fprintf(w, "- %s\n+ %s\n", filepath.Base(result.SubtitlePath), filepath.Base(result.NewSubtitlePath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// embeddedSampleLines is how many lines of text are compared from the
+	// beginning of a subtitle and of an embedded track.
+	embeddedSampleLines = 150
+	// embeddedSampleSeconds bounds how much of a video ffmpeg reads to
+	// extract the sample of an embedded track.
+	embeddedSampleSeconds = 1200
+	// embeddedMinPhrases is the number of distinct phrases both samples
+	// need for their overlap to mean anything.
+	embeddedMinPhrases = 30
+	// embeddedConfirmOverlap is the overlap that raises a match to High
+	// confidence: the subtitle shares its lines with the video's own.
+	embeddedConfirmOverlap = 0.25
+	// embeddedRejectOverlap is the overlap below which a match is rejected:
+	// the subtitle belongs to another video.
+	embeddedRejectOverlap = 0.03
+)
+
+// embeddedTextCodecs are the subtitle codecs ffmpeg can extract as text.
+var embeddedTextCodecs = map[string]bool{
+	"subrip": true, "srt": true, "ass": true, "ssa": true,
+	"webvtt": true, "mov_text": true, "text": true,
+}
+
+// embeddedTrack is a text subtitle track stored in a video container.
+type embeddedTrack struct {
+	index    int    // Stream index within the container
+	language string // ISO 639-1 code from the track's language tag, if any
+}
+
+// probeSubtitleTracks lists the text subtitle tracks of a video using ffprobe.
+func probeSubtitleTracks(ctx context.Context, videoPath string) ([]embeddedTrack, error) {
+	out, err := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "s",
+		"-show_entries", "stream=index,codec_name:stream_tags=language",
+		"-of", "csv=p=0",
+		videoPath,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var tracks []embeddedTrack
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) < 2 || !embeddedTextCodecs[fields[1]] {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		track := embeddedTrack{index: index}
+		if len(fields) > 2 {
+			track.language = languageCodes[strings.ToLower(fields[2])]
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// extractTrackSample returns the first lines of text of an embedded subtitle
+// track, converted to SRT by ffmpeg.
+func extractTrackSample(ctx context.Context, videoPath string, track embeddedTrack) ([]string, error) {
+	out, err := exec.CommandContext(ctx, ffmpegPath,
+		"-v", "error",
+		"-i", videoPath,
+		"-map", fmt.Sprintf("0:%d", track.index),
+		"-t", strconv.Itoa(embeddedSampleSeconds),
+		"-f", "srt",
+		"-",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for len(lines) < embeddedSampleLines && scanner.Scan() {
+		if text := cueText(scanner.Text()); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines, nil
+}
+
+// trackCache remembers the embedded subtitle tracks of videos and the
+// samples extracted from them during a run, so each video is probed once
+// however many subtitles are compared with it.
+type trackCache struct {
+	mu      sync.Mutex
+	tracks  map[string][]embeddedTrack // Video path → text subtitle tracks
+	samples map[string][]string        // Video path and stream index → first lines of text
+}
+
+// reset forgets the tracks found by the previous run.
+func (c *trackCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracks, c.samples = nil, nil
+}
+
+// embeddedSamples returns the samples of the embedded text tracks of a
+// video that a subtitle in language can be compared with: the tracks in that
+// language, or every track when the language is unknown. Videos that cannot
+// be probed have no tracks.
+func (vsm *VideoSubtitleMatcher) embeddedSamples(ctx context.Context, videoPath, language string) [][]string {
+	c := vsm.embeddedTracks
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tracks, ok := c.tracks[videoPath]
+	if !ok {
+		var err error
+		if tracks, err = probeSubtitleTracks(ctx, videoPath); err != nil && vsm.verbose {
+			vsm.printf(colorRed, "  Error reading subtitle tracks of %s: %v\n", filepath.Base(videoPath), err)
+		}
+		if c.tracks == nil {
+			c.tracks, c.samples = make(map[string][]embeddedTrack), make(map[string][]string)
+		}
+		c.tracks[videoPath] = tracks
+	}
+
+	var samples [][]string
+	for _, track := range tracks {
+		if language != "" && track.language != language {
+			continue
+		}
+		key := videoPath + "#" + strconv.Itoa(track.index)
+		sample, ok := c.samples[key]
+		if !ok {
+			var err error
+			if sample, err = extractTrackSample(ctx, videoPath, track); err != nil && vsm.verbose {
+				vsm.printf(colorRed, "  Error reading subtitle track %d of %s: %v\n", track.index, filepath.Base(videoPath), err)
+			}
+			c.samples[key] = sample
+		}
+		if len(sample) > 0 {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// verifyEmbedded compares the text of a matched subtitle with the embedded
+// subtitle tracks of its video. A subtitle sharing its lines with a track
+// is a match of High confidence; one sharing almost none with a track in
+// its own language belongs to another video and is rejected. Subtitles of
+// unknown language are only compared for confirmation, as the tracks may
+// be in another language. Videos without text tracks leave the match as it is.
+func (vsm *VideoSubtitleMatcher) verifyEmbedded(ctx context.Context, result MatchResult) MatchResult {
+	if !vsm.isLocal() || isImageSubtitle(vsm.fs, result.SubtitlePath) {
+		return result
+	}
+	lines := previewLines(vsm.fs, result.SubtitlePath, embeddedSampleLines)
+	if len(lines) == 0 {
+		return result
+	}
+
+	best, compared := 0.0, false
+	for _, sample := range vsm.embeddedSamples(ctx, result.VideoPath, result.Language) {
+		if overlap, ok := textOverlap(lines, sample); ok {
+			best, compared = max(best, overlap), true
+		}
+	}
+	if !compared {
+		return result
+	}
+
+	result.EmbeddedOverlap = best
+	switch {
+	case best >= embeddedConfirmOverlap:
+		result.Confidence = ConfidenceHigh
+	case best < embeddedRejectOverlap && result.Language != "":
+		result.NewSubtitlePath = ""
+		result.Rejected = true
+	}
+	return result
+}
+
+// textOverlap returns the share of distinct phrases two samples of
+// subtitle text have in common, relative to the smaller sample. Phrases of
+// three words, rather than words, keep common words from making unrelated
+// subtitles look alike. It reports false when a sample is too short to tell.
+func textOverlap(a, b []string) (float64, bool) {
+	phrasesA, phrasesB := phrasesOf(a), phrasesOf(b)
+	smaller := min(len(phrasesA), len(phrasesB))
+	if smaller < embeddedMinPhrases {
+		return 0, false
+	}
+
+	shared := 0
+	for phrase := range phrasesA {
+		if phrasesB[phrase] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(smaller), true
+}
+
+// phrasesOf returns the distinct runs of three consecutive words in lines
+// of text. Each Chinese or Japanese character counts as a word, as those
+// scripts do not separate words with spaces.
+func phrasesOf(lines []string) map[string]bool {
+	phrases := make(map[string]bool)
+	for _, line := range lines {
+		var words []string
+		var word strings.Builder
+		flush := func() {
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		}
+		for _, r := range strings.ToLower(line) {
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+				flush()
+				words = append(words, string(r))
+			case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'':
+				word.WriteRune(r)
+			default:
+				flush()
+			}
+		}
+		flush()
+
+		for i := 2; i < len(words); i++ {
+			phrases[words[i-2]+" "+words[i-1]+" "+words[i]] = true
+		}
+	}
+	return phrases
+}
+
+// logRejected logs a match rejected by its video's embedded subtitles
+func (vsm *VideoSubtitleMatcher) logRejected(result MatchResult) {
+	if vsm.verbose && !vsm.writesPlan() {
+		vsm.printf(colorRed, "\nRejected match for: %s (%.2f similarity to %s, but %.0f%% of its text in the embedded subtitles)\n",
+			filepath.Base(result.SubtitlePath), result.Similarity, filepath.Base(result.VideoPath), result.EmbeddedOverlap*100)
+	}
+}
@@ -0,0 +1,87 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Explanation describes how a match was scored, for debugging thresholds
+// and normalization.
+type Explanation struct {
+	NormalizedSubtitle string              `json:"normalized_subtitle"`         // Subtitle title as compared
+	NormalizedVideo    string              `json:"normalized_video,omitempty"`  // Best video title as compared
+	Algorithm          string              `json:"algorithm"`                   // Similarity algorithm used
+	LCSLength          int                 `json:"lcs_length"`                  // Length of the longest common subsequence
+	Tokens             []TokenContribution `json:"tokens,omitempty"`            // Per-token breakdown of the subtitle title
+	SecondBestVideo    string              `json:"second_best_video,omitempty"` // Runner-up video, if any
+	SecondBestScore    float64             `json:"second_best_score,omitempty"` // Runner-up similarity score
+}
+
+// TokenContribution describes how one word of the subtitle title contributed to the score.
+type TokenContribution struct {
+	Token   string  `json:"token"`   // Word from the normalized subtitle title
+	Matched bool    `json:"matched"` // Whether the word also appears in the video title
+	Weight  float64 `json:"weight"`  // Share of the subtitle title's length taken by the word
+}
+
+// Algorithm names reported in explanations.
+const (
+	similarityAlgorithm     = "lcs"
+	pathSimilarityAlgorithm = "weighted-lcs+directories"
+)
+
+// titleOf returns a file's base name without its extension.
+func titleOf(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// explainMatch builds the score breakdown for a subtitle and its best video,
+// including the runner-up candidate.
+func (vsm *VideoSubtitleMatcher) explainMatch(subtitlePath, bestMatch string, videoFiles []string) *Explanation {
+	var videoPart int
+	if bestMatch != "" {
+		videoPart, _ = detectPart(vsm.normalizedVideo(bestMatch))
+	}
+	_, normalizedSubtitle := pairedPart(vsm.normalizeTitle(subtitleTitle(subtitlePath)), videoPart)
+
+	explanation := &Explanation{
+		NormalizedSubtitle: normalizedSubtitle,
+		Algorithm:          similarityAlgorithm,
+	}
+	if vsm.directoryWeight > 0 {
+		explanation.Algorithm = pathSimilarityAlgorithm
+	}
+
+	var videoTokens map[string]bool
+	if bestMatch != "" {
+		explanation.NormalizedVideo = vsm.strippedVideo(bestMatch)
+		for _, alias := range vsm.videoAliases(bestMatch) {
+			normalizedAlias := stripPart(vsm.normalizeTitle(alias))
+			if vsm.calculateSimilarity(normalizedSubtitle, normalizedAlias) > vsm.calculateSimilarity(normalizedSubtitle, explanation.NormalizedVideo) {
+				explanation.NormalizedVideo = normalizedAlias
+			}
+		}
+		explanation.LCSLength = vsm.longestCommonSubsequence(normalizedSubtitle, explanation.NormalizedVideo)
+		videoTokens = make(map[string]bool)
+		for _, token := range strings.Fields(explanation.NormalizedVideo) {
+			videoTokens[token] = true
+		}
+	}
+
+	for _, token := range strings.Fields(normalizedSubtitle) {
+		explanation.Tokens = append(explanation.Tokens, TokenContribution{
+			Token:   token,
+			Matched: videoTokens[token],
+			Weight:  float64(len(token)) / float64(len(normalizedSubtitle)),
+		})
+	}
+
+	for _, candidate := range vsm.scoreCandidates(subtitlePath, videoFiles) {
+		if candidate.VideoPath != bestMatch && candidate.Similarity > explanation.SecondBestScore {
+			explanation.SecondBestScore = candidate.Similarity
+			explanation.SecondBestVideo = candidate.VideoPath
+		}
+	}
+
+	return explanation
+}
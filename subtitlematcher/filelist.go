@@ -0,0 +1,94 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFileList reads file paths from r, one per line, e.g. the output of
+// find or fd. NUL-separated lists (find -print0, fd -0) are accepted too.
+// Blank lines are skipped.
+func ReadFileList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if bytes.IndexByte(data, 0) >= 0 {
+		scanner.Split(splitNUL)
+	}
+
+	var paths []string
+	for scanner.Scan() {
+		path := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(path) != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// splitNUL is a bufio.SplitFunc for NUL-separated input.
+func splitNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// classifyFileList passes every listed file to classify instead of scanning
+// the directory. When the list names no videos, the directory is still
+// scanned for them.
+func (vsm *VideoSubtitleMatcher) classifyFileList(ctx context.Context, classify func(path string) error, videoFiles *[]string) error {
+	seen := make(map[string]bool, len(vsm.fileList))
+	for _, path := range vsm.fileList {
+		path = filepath.Clean(path)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if _, err := vsm.fs.Stat(path); err != nil {
+			if vsm.verbose {
+				vsm.printf(colorRed, "  Error reading listed file %s: %v\n", path, err)
+			}
+			continue
+		}
+		if err := classify(path); err != nil {
+			return err
+		}
+	}
+
+	if len(*videoFiles) > 0 {
+		return nil
+	}
+	return withContext(ctx, vsm.fs).Walk(vsm.directory, vsm.recursive, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if vsm.isVideoName(path) {
+			*videoFiles = append(*videoFiles, path)
+		}
+		return nil
+	})
+}
+
+// isVideoName reports whether a file name has a video extension.
+func (vsm *VideoSubtitleMatcher) isVideoName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, videoExt := range vsm.videoExtensions {
+		if ext == videoExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// altTag marks subtitles that lost the canonical name to a preferred format.
+const altTag = ".alt"
+
+// formatRank returns the position of a subtitle's extension in the preference
+// order. Extensions not listed rank after all listed ones.
+func (vsm *VideoSubtitleMatcher) formatRank(path string) int {
+	ext := strings.ToLower(filepath.Ext(path))
+	for i, preferred := range vsm.formatPreference {
+		if ext == preferred {
+			return i
+		}
+	}
+	return len(vsm.formatPreference)
+}
+
+// preferFormats resolves subtitles planned for the same canonical name. The
+// subtitle whose format ranks highest keeps the canonical name and the others
+// are tagged ".alt", ".alt2", ... so the outcome no longer depends on scan order.
+func (vsm *VideoSubtitleMatcher) preferFormats(results []MatchResult) {
+	for _, indices := range groupByTarget(results) {
+		if len(indices) < 2 {
+			continue
+		}
+
+		sort.SliceStable(indices, func(a, b int) bool {
+			ra := vsm.formatRank(results[indices[a]].SubtitlePath)
+			rb := vsm.formatRank(results[indices[b]].SubtitlePath)
+			if ra != rb {
+				return ra < rb
+			}
+			return results[indices[a]].SubtitlePath < results[indices[b]].SubtitlePath
+		})
+
+		for n, i := range indices[1:] {
+			tag := altTag
+			if n > 0 {
+				tag = fmt.Sprintf("%s%d", altTag, n+1)
+			}
+			results[i].NewSubtitlePath = insertTag(results[i].NewSubtitlePath, tag)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// imageSubtitleExtensions are subtitle formats that store cues as bitmaps:
+// PGS from Blu-ray (.sup) and VobSub from DVD (.idx, and .sub unless it
+// holds MicroDVD text). They are matched and renamed by name only; nothing
+// reads their content as text.
+var imageSubtitleExtensions = []string{".sup", vobSubIndexExt}
+
+// isImageSubtitle reports whether a subtitle is image-based.
+func isImageSubtitle(fsys FileSystem, path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, imageExt := range imageSubtitleExtensions {
+		if ext == imageExt {
+			return true
+		}
+	}
+	return isVobSubData(fsys, path)
+}
@@ -0,0 +1,146 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultFrameRate is assumed for MicroDVD subtitles when neither the video
+// nor the subtitle declares a frame rate.
+const defaultFrameRate = 23.976
+
+// frameRateTolerance is the difference below which two frame rates are considered equal.
+const frameRateTolerance = 0.01
+
+// microDVDLinePattern matches a MicroDVD cue: {start frame}{end frame}text.
+var microDVDLinePattern = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+
+// microDVDControlPattern matches MicroDVD formatting codes such as {y:i} or {c:$0000FF}.
+var microDVDControlPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// isMicroDVD reports whether a file is a text-based MicroDVD subtitle by
+// checking its first non-empty line.
+func isMicroDVD(fsys FileSystem, path string) bool {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), utf8BOM))
+		if line != "" {
+			return microDVDLinePattern.MatchString(line)
+		}
+	}
+	return false
+}
+
+// parseMicroDVD parses MicroDVD content into cues using the given frame rate.
+// A leading "{1}{1}23.976" cue declaring the frame rate takes precedence.
+func parseMicroDVD(content string, fps float64) []srtCue {
+	content = strings.TrimPrefix(content, utf8BOM)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var cues []srtCue
+	for i, line := range strings.Split(content, "\n") {
+		m := microDVDLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		if i == 0 && m[1] == "1" && m[2] == "1" {
+			if declared, err := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); err == nil && validFrameRate(declared) {
+				fps = declared
+				continue
+			}
+		}
+
+		start, _ := strconv.Atoi(m[1])
+		end, err := strconv.Atoi(m[2])
+		if err != nil {
+			end = start
+		}
+
+		cue := srtCue{Start: framesToDuration(start, fps), End: framesToDuration(end, fps)}
+		for _, text := range strings.Split(microDVDControlPattern.ReplaceAllString(m[3], ""), "|") {
+			if text = strings.TrimSpace(text); text != "" {
+				cue.Lines = append(cue.Lines, text)
+			}
+		}
+		if len(cue.Lines) > 0 {
+			cues = append(cues, cue)
+		}
+	}
+	return cues
+}
+
+// framesToDuration converts a frame number to a timestamp at the given frame
+// rate, or at defaultFrameRate when fps is not a usable rate.
+func framesToDuration(frames int, fps float64) time.Duration {
+	if !validFrameRate(fps) {
+		fps = defaultFrameRate
+	}
+	return time.Duration(float64(frames) / fps * float64(time.Second))
+}
+
+// validFrameRate reports whether fps is a positive, finite frame rate.
+func validFrameRate(fps float64) bool {
+	return fps > 0 && !math.IsInf(fps, 1)
+}
+
+// convertMicroDVDToSRT writes a MicroDVD subtitle as SRT to newPath using the
+// given frame rate, and removes the original.
+func convertMicroDVDToSRT(fsys FileSystem, path, newPath string, fps float64) error {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return err
+	}
+
+	srt := formatSRT(parseMicroDVD(string(data), fps), "\n")
+	if err := fsys.WriteFile(newPath, []byte(srt)); err != nil {
+		return err
+	}
+	return fsys.Remove(path)
+}
+
+// retimeSRT rescales every timestamp of an SRT file so that a subtitle timed
+// for one frame rate plays in sync with a video at another, e.g. 25 → 23.976.
+// The file keeps its byte order mark and line endings. Reports whether the
+// file was rewritten.
+func retimeSRT(fsys FileSystem, path string, fromFPS, toFPS float64) (bool, error) {
+	if !validFrameRate(fromFPS) || !validFrameRate(toFPS) {
+		return false, fmt.Errorf("invalid frame rate %g → %g", fromFPS, toFPS)
+	}
+	if math.Abs(fromFPS-toFPS) < frameRateTolerance {
+		return false, nil
+	}
+
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	content := string(data)
+	doc := parseSRT(content)
+	if doc.Invalid {
+		return false, nil
+	}
+	bom, newline := textStyle(content)
+
+	factor := fromFPS / toFPS
+	for i := range doc.Cues {
+		doc.Cues[i].Start = time.Duration(float64(doc.Cues[i].Start) * factor)
+		doc.Cues[i].End = time.Duration(float64(doc.Cues[i].End) * factor)
+	}
+
+	if err := fsys.WriteFile(path, []byte(bom+formatSRT(doc.Cues, newline))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,185 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// FileSystem is the storage the matcher scans and renames files on. The
+// default works on local disk; remote backends let the matcher organize a
+// library without mounting it first.
+type FileSystem interface {
+	// Walk calls fn with the path of every regular file in dir, and in its
+	// subdirectories when recursive is set. An error from fn stops the walk
+	// and is returned.
+	Walk(dir string, recursive bool, fn func(path string) error) error
+	// Open opens a file for reading.
+	Open(path string) (io.ReadCloser, error)
+	// WriteFile creates or replaces a file with data.
+	WriteFile(path string, data []byte) error
+	// Rename moves a file, replacing any file already at newPath.
+	Rename(oldPath, newPath string) error
+	// Remove deletes a file.
+	Remove(path string) error
+	// Stat describes a file. A missing file yields an error matching fs.ErrNotExist.
+	Stat(path string) (fs.FileInfo, error)
+}
+
+// timeSetter is implemented by file systems that can set modification times.
+type timeSetter interface {
+	Chtimes(path string, atime, mtime time.Time) error
+}
+
+// contextFileSystem is implemented by file systems whose operations can be
+// stopped through a context, such as those that run external commands.
+type contextFileSystem interface {
+	WithContext(ctx context.Context) FileSystem
+}
+
+// withContext returns fsys with its operations bound to ctx, where supported.
+func withContext(ctx context.Context, fsys FileSystem) FileSystem {
+	if bindable, ok := fsys.(contextFileSystem); ok {
+		return bindable.WithContext(ctx)
+	}
+	return fsys
+}
+
+// errTimesUnsupported is returned when the file system cannot set modification times.
+var errTimesUnsupported = errors.New("file system does not support modification times")
+
+// LocalFileSystem is the FileSystem for local disk.
+type LocalFileSystem struct{}
+
+// Walk implements FileSystem.
+func (LocalFileSystem) Walk(dir string, recursive bool, fn func(path string) error) error {
+	if !recursive {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err := fn(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// Open implements FileSystem.
+func (LocalFileSystem) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// WriteFile implements FileSystem.
+func (LocalFileSystem) WriteFile(path string, data []byte) error {
+	return os.WriteFile(path, data, 0644)
+}
+
+// Rename implements FileSystem.
+func (LocalFileSystem) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// Remove implements FileSystem.
+func (LocalFileSystem) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// Stat implements FileSystem.
+func (LocalFileSystem) Stat(path string) (fs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+// Chtimes sets a file's access and modification times.
+func (LocalFileSystem) Chtimes(path string, atime, mtime time.Time) error {
+	return os.Chtimes(path, atime, mtime)
+}
+
+// isLocal reports whether the matcher works on local disk. Features that need
+// local files, such as the run lock, the rename journal and probing videos
+// with ffprobe, are skipped on other file systems.
+func (vsm *VideoSubtitleMatcher) isLocal() bool {
+	fsys := vsm.fs
+	if archives, ok := fsys.(*archiveFileSystem); ok {
+		fsys = archives.FileSystem
+	}
+	_, ok := fsys.(LocalFileSystem)
+	return ok
+}
+
+// readFile reads a whole file from fsys.
+func readFile(fsys FileSystem, path string) ([]byte, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// exists reports whether path exists on fsys.
+func exists(fsys FileSystem, path string) bool {
+	_, err := fsys.Stat(path)
+	return err == nil
+}
+
+// remoteFileInfo describes a file or directory on a remote file system.
+type remoteFileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (fi remoteFileInfo) Name() string       { return path.Base(fi.path) }
+func (fi remoteFileInfo) Size() int64        { return fi.size }
+func (fi remoteFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi remoteFileInfo) IsDir() bool        { return fi.isDir }
+func (fi remoteFileInfo) Sys() interface{}   { return nil }
+
+func (fi remoteFileInfo) Mode() fs.FileMode {
+	if fi.isDir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// cleanRemotePath normalizes a path to an absolute slash-separated form
+// without a trailing slash.
+func cleanRemotePath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// checkHTTPStatus converts error responses to errors, mapping 404 to fs.ErrNotExist.
+func checkHTTPStatus(resp *http.Response, op, name string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("server returned %s", resp.Status)}
+}
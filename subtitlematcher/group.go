@@ -0,0 +1,82 @@
+package subtitlematcher
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// VideoGroup is the video-centric view of results: a video and the
+// subtitles matched to it.
+type VideoGroup struct {
+	VideoPath string          `json:"video_path"` // Matched video file path ("" for subtitles matching no video)
+	Subtitles []VideoSubtitle `json:"subtitles"`  // Subtitles matched to the video, ordered by path
+}
+
+// VideoSubtitle describes a subtitle within a VideoGroup.
+type VideoSubtitle struct {
+	SubtitlePath    string     `json:"subtitle_path"`               // Original subtitle file path
+	NewSubtitlePath string     `json:"new_subtitle_path,omitempty"` // New subtitle file path after renaming
+	Language        string     `json:"language,omitempty"`          // ISO 639-1 language code detected from the filename, if any
+	SDH             bool       `json:"sdh,omitempty"`               // Whether the subtitle was detected as SDH
+	Similarity      float64    `json:"similarity"`                  // Similarity score (0.0-1.0)
+	Confidence      Confidence `json:"confidence"`                  // How trustworthy the match is
+	Action          string     `json:"action"`                      // What happened or would happen, as in the STATUS column of WriteTable
+	Error           string     `json:"error,omitempty"`             // Error that occurred during renaming, if any
+}
+
+// GroupByVideo groups results by the video they matched, ordered by video
+// path, so that applications showing each video with its subtitles do not
+// have to rebuild that view. Duplicates left alone are listed under their
+// video too. Subtitles matching no video are gathered in a last group with
+// an empty VideoPath.
+func GroupByVideo(results []MatchResult) []VideoGroup {
+	sorted := append([]MatchResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, vj := sorted[i].VideoPath, sorted[j].VideoPath
+		if (vi == "") != (vj == "") {
+			return vj == ""
+		}
+		if vi != vj {
+			return vi < vj
+		}
+		return sorted[i].SubtitlePath < sorted[j].SubtitlePath
+	})
+
+	var groups []VideoGroup
+	for _, result := range sorted {
+		if len(groups) == 0 || groups[len(groups)-1].VideoPath != result.VideoPath {
+			groups = append(groups, VideoGroup{VideoPath: result.VideoPath})
+		}
+		subtitle := VideoSubtitle{
+			SubtitlePath:    result.SubtitlePath,
+			NewSubtitlePath: result.NewSubtitlePath,
+			Language:        result.Language,
+			SDH:             result.SDH,
+			Similarity:      result.Similarity,
+			Confidence:      result.Confidence,
+			Action:          resultStatus(result),
+		}
+		if result.Error != nil {
+			subtitle.Error = result.Error.Error()
+		}
+		group := &groups[len(groups)-1]
+		group.Subtitles = append(group.Subtitles, subtitle)
+	}
+	return groups
+}
+
+// WriteVideoGroups writes the results grouped by video as indented JSON.
+// See GroupByVideo for the grouping.
+func WriteVideoGroups(w io.Writer, results []MatchResult) error {
+	groups := GroupByVideo(results)
+	if groups == nil {
+		groups = []VideoGroup{}
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,121 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UnsafeRootError is returned by runs that would rename files in a dangerous
+// directory: a file system root, the user's home directory or the directory
+// holding the home directories, or a protected path. Nothing is renamed.
+type UnsafeRootError struct {
+	Directory string // Directory of the run
+	Reason    string // Why the directory is refused
+}
+
+// Error describes the refused directory.
+func (e *UnsafeRootError) Error() string {
+	return fmt.Sprintf("refusing to rename files in %s: %s; nothing renamed", e.Directory, e.Reason)
+}
+
+// checkRoot refuses runs renaming files in a dangerous directory, as happens
+// when a script passes an empty variable as the directory. Dry runs are not
+// checked, and AllowUnsafeRoot only lifts the check of roots and home
+// directories, never that of protected paths.
+func (vsm *VideoSubtitleMatcher) checkRoot() error {
+	if vsm.dryRun {
+		return nil
+	}
+	return vsm.checkDirectory(vsm.cleanPath(vsm.directory), vsm.recursive)
+}
+
+// checkPlannedPaths refuses runs that would rename files from or into a
+// dangerous directory, checking the directory of every planned source and
+// target like checkRoot does the run's. File lists (see Files) and edited
+// plans (see Apply) can name files outside the run's directory.
+func (vsm *VideoSubtitleMatcher) checkPlannedPaths(planned []MatchResult) error {
+	if vsm.dryRun {
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, result := range planned {
+		if result.NewSubtitlePath == "" {
+			continue
+		}
+		for _, path := range []string{result.SubtitlePath, result.NewSubtitlePath, result.PairedPath} {
+			if path == "" {
+				continue
+			}
+			directory := vsm.cleanPath(filepath.Dir(path))
+			if checked[directory] {
+				continue
+			}
+			checked[directory] = true
+			if err := vsm.checkDirectory(directory, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkDirectory refuses renaming files in a protected path, a file system
+// root or a home directory. With recursive, directories holding a protected
+// path are refused too.
+func (vsm *VideoSubtitleMatcher) checkDirectory(directory string, recursive bool) error {
+	for _, protected := range vsm.protectedPaths {
+		protected = vsm.cleanPath(protected)
+		if isWithin(directory, protected) {
+			return &UnsafeRootError{Directory: directory, Reason: "inside protected path " + protected}
+		}
+		if recursive && isWithin(protected, directory) {
+			return &UnsafeRootError{Directory: directory, Reason: "recursive scan includes protected path " + protected}
+		}
+	}
+
+	if vsm.allowUnsafeRoot {
+		return nil
+	}
+	if filepath.Dir(directory) == directory {
+		return &UnsafeRootError{Directory: directory, Reason: "file system root"}
+	}
+	if !vsm.isLocal() {
+		return nil
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		home = filepath.Clean(home)
+		switch directory {
+		case home:
+			return &UnsafeRootError{Directory: directory, Reason: "home directory"}
+		case filepath.Dir(home):
+			return &UnsafeRootError{Directory: directory, Reason: "directory of the home directories"}
+		}
+	}
+	return nil
+}
+
+// cleanPath cleans a path, making it absolute on local disk.
+func (vsm *VideoSubtitleMatcher) cleanPath(path string) string {
+	path = filepath.Clean(path)
+	if vsm.isLocal() {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return path
+}
+
+// isWithin reports whether path is dir or lies inside it. Both paths must be
+// clean.
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
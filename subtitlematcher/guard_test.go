@@ -0,0 +1,58 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesOutsideDirectoryInProtectedPath(t *testing.T) {
+	dir := writeFiles(t)
+	protected := writeFiles(t, "Movie.2020.mkv", "movie 2020.srt")
+	subtitle := filepath.Join(protected, "movie 2020.srt")
+
+	_, err := New(dir, DryRun(false), Verbose(false), ProtectedPaths(protected),
+		Files([]string{filepath.Join(protected, "Movie.2020.mkv"), subtitle})).Match()
+	var unsafe *UnsafeRootError
+	if !errors.As(err, &unsafe) {
+		t.Fatalf("Match returned %v, want an *UnsafeRootError", err)
+	}
+	if _, err := os.Stat(subtitle); err != nil {
+		t.Errorf("subtitle in protected path was moved: %v", err)
+	}
+}
+
+func TestApplyRefusesTargetInProtectedPath(t *testing.T) {
+	dir := writeFiles(t, "Movie.2020.mkv", "movie 2020.srt")
+	protected := writeFiles(t)
+	subtitle := filepath.Join(dir, "movie 2020.srt")
+	plan := &Plan{Directory: dir, Renames: []PlanEntry{
+		{Subtitle: subtitle, Target: filepath.Join(protected, "Movie.2020.srt")},
+	}}
+
+	_, err := New(dir, DryRun(false), Verbose(false), ProtectedPaths(protected)).Apply(context.Background(), plan)
+	var unsafe *UnsafeRootError
+	if !errors.As(err, &unsafe) {
+		t.Fatalf("Apply returned %v, want an *UnsafeRootError", err)
+	}
+	if _, err := os.Stat(subtitle); err != nil {
+		t.Errorf("subtitle was moved into protected path: %v", err)
+	}
+}
+
+func TestCheckPlannedPathsSkipsDryRuns(t *testing.T) {
+	protected := writeFiles(t)
+	planned := []MatchResult{{
+		SubtitlePath:    filepath.Join(protected, "a.srt"),
+		NewSubtitlePath: filepath.Join(protected, "b.srt"),
+	}}
+
+	if err := New(protected, ProtectedPaths(protected)).checkPlannedPaths(planned); err != nil {
+		t.Errorf("dry run refused: %v", err)
+	}
+	if err := New(protected, DryRun(false), ProtectedPaths(protected)).checkPlannedPaths(planned); err == nil {
+		t.Error("rename in protected path allowed")
+	}
+}
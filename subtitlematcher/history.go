@@ -0,0 +1,283 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrRunNotFound is returned when a run ID is not in the history.
+var ErrRunNotFound = errors.New("run not found")
+
+// Run is one recorded invocation of MatchContext.
+type Run struct {
+	ID        string        `json:"id"`        // Unique run ID, e.g. "20261016T101500Z-3fa2"
+	Started   time.Time     `json:"started"`   // When the run started
+	Directory string        `json:"directory"` // Directory that was matched
+	Settings  RunSettings   `json:"settings"`  // Options the run used
+	Results   []MatchResult `json:"results"`   // Every result, including failed renames
+}
+
+// RunSettings records the options that affect which files a run renames.
+type RunSettings struct {
+	SimilarityThreshold float64     `json:"similarity_threshold"`
+	Recursive           bool        `json:"recursive"`
+	DryRun              bool        `json:"dry_run"`
+	VideoExtensions     []string    `json:"video_extensions"`
+	SubtitleExtensions  []string    `json:"subtitle_extensions"`
+	MappingFile         string      `json:"mapping_file,omitempty"`
+	Strict              bool        `json:"strict,omitempty"`
+	Preset              TitlePreset `json:"preset,omitempty"`
+	FileSystem          string      `json:"file_system,omitempty"` // Backend the run used ("webdav", "s3", "rclone" or "custom"), "" for local disk
+	Renamer             string      `json:"renamer,omitempty"`     // Renamer the run used ("recording", "copy", "link" or "custom"), "" for moving files
+}
+
+// Renames returns the results of a run whose subtitle was actually moved.
+func (r Run) Renames() []MatchResult {
+	var renames []MatchResult
+	for _, result := range r.Results {
+		if result.Renamed && !failed(result) && result.SubtitlePath != result.NewSubtitlePath {
+			renames = append(renames, result)
+		}
+	}
+	return renames
+}
+
+// CleanedUp returns the results of a run whose duplicate was moved by
+// CleanupDuplicates.
+func (r Run) CleanedUp() []MatchResult {
+	var cleaned []MatchResult
+	for _, result := range r.Results {
+		if result.CleanedUpPath != "" {
+			cleaned = append(cleaned, result)
+		}
+	}
+	return cleaned
+}
+
+// HistoryStore persists runs so past operations can be audited or undone.
+type HistoryStore interface {
+	// Record appends a finished run to the history.
+	Record(run Run) error
+	// Runs returns every recorded run, oldest first.
+	Runs() ([]Run, error)
+}
+
+// FileHistory is a HistoryStore that appends one JSON document per run to a
+// file. It needs no database and the file stays readable with standard tools.
+type FileHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileHistory returns a history stored in the file at path. The file is
+// created on the first recorded run.
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{path: path}
+}
+
+// Record appends run to the history file and syncs it to disk.
+func (h *FileHistory) Record(run Run) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Runs reads every run from the history file, oldest first. A missing file
+// is an empty history.
+func (h *FileHistory) Runs() ([]Run, error) {
+	file, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", h.path, line, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// FindRun returns the run with the given ID from store.
+func FindRun(store HistoryStore, id string) (Run, error) {
+	runs, err := store.Runs()
+	if err != nil {
+		return Run{}, err
+	}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return Run{}, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+}
+
+// newRunID returns a sortable, practically unique run ID.
+func newRunID(started time.Time) string {
+	return fmt.Sprintf("%s-%04x", started.UTC().Format("20060102T150405Z"), rand.Intn(0x10000))
+}
+
+// recordRun stores a run in the configured history. A nil history is a no-op.
+// Runs on local disk are recorded with absolute paths, so that they can be
+// undone and compared from any working directory.
+func (vsm *VideoSubtitleMatcher) recordRun(started time.Time, results []MatchResult) error {
+	if vsm.history == nil {
+		return nil
+	}
+
+	return vsm.history.Record(Run{
+		ID:        newRunID(started),
+		Started:   started,
+		Directory: vsm.recordedDirectory(),
+		Settings:  vsm.runSettings(),
+		Results:   vsm.recordedResults(results),
+	})
+}
+
+// recordedDirectory returns the directory as recorded in the history:
+// absolute on local disk, and as given on other file systems.
+func (vsm *VideoSubtitleMatcher) recordedDirectory() string {
+	if !vsm.isLocal() {
+		return vsm.directory
+	}
+	return absolutePath(vsm.directory)
+}
+
+// recordedResults returns results as recorded in the history: with absolute
+// paths on local disk, and as they are on other file systems.
+func (vsm *VideoSubtitleMatcher) recordedResults(results []MatchResult) []MatchResult {
+	if !vsm.isLocal() {
+		return results
+	}
+	recorded := make([]MatchResult, len(results))
+	for i, result := range results {
+		recorded[i] = absoluteResult(result)
+	}
+	return recorded
+}
+
+// absoluteResult returns result with every path it holds made absolute.
+func absoluteResult(result MatchResult) MatchResult {
+	for _, path := range []*string{
+		&result.SubtitlePath, &result.VideoPath, &result.NewSubtitlePath, &result.MergedSubtitlePath,
+		&result.JoinedSubtitlePath, &result.OCRSubtitlePath, &result.TranslationPath, &result.Archive,
+		&result.PairedPath, &result.DuplicateOf, &result.CleanedUpPath,
+	} {
+		*path = absolutePath(*path)
+	}
+	if result.SplitSubtitlePaths != nil {
+		split := make([]string, len(result.SplitSubtitlePaths))
+		for i, path := range result.SplitSubtitlePaths {
+			split[i] = absolutePath(path)
+		}
+		result.SplitSubtitlePaths = split
+	}
+	if result.Candidates != nil {
+		candidates := make([]Candidate, len(result.Candidates))
+		for i, candidate := range result.Candidates {
+			candidate.VideoPath = absolutePath(candidate.VideoPath)
+			candidates[i] = candidate
+		}
+		result.Candidates = candidates
+	}
+	return result
+}
+
+// absolutePath returns path made absolute against the working directory.
+// Empty paths, and paths that cannot be resolved, are returned unchanged.
+func absolutePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// runSettings returns the options recorded with a run.
+func (vsm *VideoSubtitleMatcher) runSettings() RunSettings {
+	return RunSettings{
+		SimilarityThreshold: vsm.similarityThreshold,
+		Recursive:           vsm.recursive,
+		DryRun:              vsm.dryRun,
+		VideoExtensions:     vsm.videoExtensions,
+		SubtitleExtensions:  vsm.subtitleExtensions,
+		MappingFile:         vsm.mappingFile,
+		Strict:              vsm.strict,
+		Preset:              vsm.preset,
+		FileSystem:          fileSystemName(vsm.fs),
+		Renamer:             renamerName(vsm.renamer),
+	}
+}
+
+// fileSystemName returns the name a run records for its file system, "" for
+// local disk.
+func fileSystemName(fsys FileSystem) string {
+	if archives, ok := fsys.(*archiveFileSystem); ok {
+		fsys = archives.FileSystem
+	}
+	switch fsys.(type) {
+	case LocalFileSystem:
+		return ""
+	case *WebDAVFileSystem:
+		return "webdav"
+	case *S3FileSystem:
+		return "s3"
+	case *RcloneFileSystem:
+		return "rclone"
+	default:
+		return "custom"
+	}
+}
+
+// renamerName returns the name a run records for its Renamer, "" for moving
+// files on the file system.
+func renamerName(r Renamer) string {
+	switch r.(type) {
+	case nil:
+		return ""
+	case *RecordingRenamer:
+		return "recording"
+	case CopyRenamer, *CopyRenamer:
+		return "copy"
+	case LinkRenamer, *LinkRenamer:
+		return "link"
+	default:
+		return "custom"
+	}
+}
@@ -0,0 +1,44 @@
+package subtitlematcher
+
+// ReviewFunc decides whether a match scoring below the SureThreshold is
+// renamed anyway, given its result. Matches it declines are held.
+type ReviewFunc func(result MatchResult) bool
+
+// holdUnsure holds the planned renames scoring below the sure threshold,
+// unless the review approves them. Mapped subtitles are never held, and dry
+// runs hold without asking so that their report shows what would be held.
+func (vsm *VideoSubtitleMatcher) holdUnsure(planned []MatchResult) {
+	if vsm.sureThreshold <= 0 {
+		return
+	}
+
+	for i := range planned {
+		result := &planned[i]
+		if !willRename(*result) || result.Mapped || result.Similarity >= vsm.sureThreshold {
+			continue
+		}
+		if !vsm.dryRun && vsm.reviewMatch != nil && vsm.reviewMatch(*result) {
+			continue
+		}
+		result.Held = true
+	}
+}
+
+// countHeld counts the matches held for confirmation.
+func countHeld(results []MatchResult) int {
+	count := 0
+	for _, result := range results {
+		if result.Held {
+			count++
+		}
+	}
+	return count
+}
+
+// heldPlan builds the plan of the held renames in results, to be confirmed
+// by reviewing it and executing it with Apply.
+func heldPlan(directory string, results []MatchResult) *Plan {
+	return newPlan(directory, results, func(result MatchResult) bool {
+		return result.Held
+	})
+}
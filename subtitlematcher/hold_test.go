@@ -0,0 +1,49 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHeldPairIsNotMerged(t *testing.T) {
+	cues := []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n")
+	tests := []struct {
+		name       string
+		options    []Option
+		wantMerged bool
+	}{
+		{"renamed", nil, true},
+		{"held", []Option{SureThreshold(0.99, nil)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, "The Great Escape 1963.mkv")
+			for _, name := range []string{"great.escape.1963.en.srt", "great.escape.1963.zh.srt"} {
+				if err := os.WriteFile(filepath.Join(dir, name), cues, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			options := append([]Option{Verbose(false), DryRun(false), MergeBilingual("zh", "en")}, tt.options...)
+			results, err := New(dir, options...).Match()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, result := range results {
+				if result.Held == tt.wantMerged {
+					t.Errorf("%s: Held = %v (%.3f)", filepath.Base(result.SubtitlePath), result.Held, result.Similarity)
+				}
+				if (result.MergedSubtitlePath != "") != tt.wantMerged {
+					t.Errorf("%s: MergedSubtitlePath = %q", filepath.Base(result.SubtitlePath), result.MergedSubtitlePath)
+				}
+			}
+
+			_, err = os.Stat(filepath.Join(dir, "The Great Escape 1963.zh-en.srt"))
+			if merged := err == nil; merged != tt.wantMerged {
+				t.Errorf("merged subtitle written = %v, want %v", merged, tt.wantMerged)
+			}
+		})
+	}
+}
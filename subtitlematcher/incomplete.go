@@ -0,0 +1,65 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// partialSuffixes are appended to a file's name by download clients while
+// they write it, e.g. "Movie.mkv.part" next to "Movie.mkv".
+var partialSuffixes = []string{".part", ".!qb", ".crdownload", ".!ut", ".aria2"}
+
+// partialTarget returns the file a partial download marker belongs to,
+// reporting whether path is such a marker.
+func partialTarget(path string) (string, bool) {
+	lower := strings.ToLower(path)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return path[:len(path)-len(suffix)], true
+		}
+	}
+	return "", false
+}
+
+// completeVideos drops the videos that are still being downloaded: empty
+// files, files with a partial download marker next to them, and files
+// modified within the settle time. partials holds the files that have such a
+// marker.
+func (vsm *VideoSubtitleMatcher) completeVideos(videoFiles []string, partials map[string]bool) []string {
+	if !vsm.skipIncomplete && vsm.settleTime <= 0 {
+		return videoFiles
+	}
+
+	complete := videoFiles[:0:0]
+	for _, videoPath := range videoFiles {
+		if reason := vsm.incompleteReason(videoPath, partials); reason != "" {
+			if vsm.verbose {
+				vsm.printf(colorGray, "Skipping incomplete video %s: %s\n", filepath.Base(videoPath), reason)
+			}
+			continue
+		}
+		complete = append(complete, videoPath)
+	}
+	return complete
+}
+
+// incompleteReason explains why a video looks incomplete, or returns "".
+func (vsm *VideoSubtitleMatcher) incompleteReason(videoPath string, partials map[string]bool) string {
+	if vsm.skipIncomplete && partials[videoPath] {
+		return "partial download marker present"
+	}
+
+	info, err := vsm.fs.Stat(videoPath)
+	if err != nil {
+		return ""
+	}
+	if vsm.skipIncomplete && info.Size() == 0 {
+		return "empty file"
+	}
+	if age := time.Since(info.ModTime()); vsm.settleTime > 0 && age < vsm.settleTime {
+		return fmt.Sprintf("modified %s ago", age.Round(time.Second))
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// joinSubtitleParts writes one SRT for every single-file video matched by all parts
+// of a multi-part subtitle, e.g. "Movie.part1.srt" and "Movie.part2.srt" for
+// a joined "Movie.mkv", and for every multi-episode video matched by the
+// subtitles of all its episodes, e.g. "Show S01E01.srt" and "Show S01E02.srt"
+// for "Show S01E01E02.mkv". The parts are renamed as usual. A joined name
+// that exists or is planned for another subtitle is left alone.
+func (vsm *VideoSubtitleMatcher) joinSubtitleParts(ctx context.Context, results []MatchResult) {
+	groups := make(map[string]map[int]int)
+	totals := make(map[string]int)
+	var joinedPaths []string
+
+	for i, result := range results {
+		if result.NewSubtitlePath == "" || result.Invalid || result.Held || result.DuplicateOf != "" || subtitleFormat(result) != ".srt" {
+			continue
+		}
+		joinedPath, part, total, ok := vsm.joinedPath(result)
+		if !ok {
+			continue
+		}
+		totals[joinedPath] = total
+		parts, ok := groups[joinedPath]
+		if !ok {
+			parts = make(map[int]int)
+			groups[joinedPath] = parts
+			joinedPaths = append(joinedPaths, joinedPath)
+		}
+		if _, taken := parts[part]; taken {
+			// Two subtitles claim the same part; joining either would be a guess
+			parts[part] = -1
+			continue
+		}
+		parts[part] = i
+	}
+
+	for _, joinedPath := range joinedPaths {
+		indexes, ok := completeParts(groups[joinedPath], totals[joinedPath])
+		if !ok {
+			continue
+		}
+		if err := vsm.outputTaken(joinedPath); err != nil {
+			// Joined by an earlier run, or a subtitle of its own
+			vsm.logJoinSkipped(results[indexes[0]], err)
+			continue
+		}
+		vsm.plannedNames[joinedPath] = true
+
+		paths := make([]string, len(indexes))
+		videos := make([]string, len(indexes))
+		for n, i := range indexes {
+			paths[n] = results[i].SubtitlePath
+			videos[n] = results[i].VideoPath
+		}
+		estimated, err := vsm.writeJoinedSubtitle(ctx, paths, videos, joinedPath)
+		for _, i := range indexes {
+			results[i].JoinedSubtitlePath = joinedPath
+			if err != nil {
+				results[i].Error = fmt.Errorf("failed to join subtitle parts: %w", err)
+			}
+		}
+		vsm.logJoin(results, indexes, estimated, err)
+	}
+}
+
+// joinedPath returns the path of the subtitle joined from a multi-part
+// subtitle matched to a single-file video, or from single-episode subtitles
+// matched to a multi-episode video, with the part number and the number of
+// parts expected (0 when unknown). It reports false for subtitles that are
+// not a part, or whose video is split the same way.
+func (vsm *VideoSubtitleMatcher) joinedPath(result MatchResult) (string, int, int, bool) {
+	videoBaseName := strings.TrimSuffix(filepath.Base(result.VideoPath), filepath.Ext(result.VideoPath))
+	videoPart, _ := detectPart(vsm.normalizeTitle(videoBaseName))
+	part, _ := pairedPart(vsm.normalizeTitle(titleOf(result.SubtitlePath)), videoPart)
+	total := 0
+	suffix := partSuffix(part, videoPart)
+	if suffix == "" {
+		subtitleEpisode, videoEpisode, ok := vsm.resultEpisodes(result)
+		if !ok {
+			return "", 0, 0, false
+		}
+		suffix = episodeSuffix(subtitleEpisode, videoEpisode)
+		part = subtitleEpisode.Episode - videoEpisode.Episode + 1
+		total = videoEpisode.last() - videoEpisode.Episode + 1
+	}
+	if suffix == "" {
+		return "", 0, 0, false
+	}
+
+	targetName := vsm.targetBaseName(result.VideoPath)
+	name := filepath.Base(result.NewSubtitlePath)
+	if !strings.HasPrefix(name, targetName+suffix) {
+		return "", 0, 0, false
+	}
+	name = targetName + strings.TrimPrefix(name, targetName+suffix)
+	return filepath.Join(filepath.Dir(result.NewSubtitlePath), name), part, total, true
+}
+
+// completeParts returns the result indexes of parts 1 to n in order, when
+// there are at least two parts, none is missing and none is claimed twice.
+// total, when not 0, is the number of parts there must be.
+func completeParts(parts map[int]int, total int) ([]int, bool) {
+	if len(parts) < 2 || (total > 0 && len(parts) != total) {
+		return nil, false
+	}
+	numbers := make([]int, 0, len(parts))
+	for part := range parts {
+		numbers = append(numbers, part)
+	}
+	sort.Ints(numbers)
+
+	indexes := make([]int, len(numbers))
+	for n, part := range numbers {
+		if part != n+1 || parts[part] < 0 {
+			return nil, false
+		}
+		indexes[n] = parts[part]
+	}
+	return indexes, true
+}
+
+// writeJoinedSubtitle concatenates SRT parts into one SRT file. Each part is
+// shifted by the duration of the video parts before it, probed from the
+// videos named like the subtitle parts. When a video part cannot be found or
+// probed, the next part starts where the previous part's last cue ends
+// instead, which is early by any credits or silence after that cue; the
+// first such error is returned as estimated. videos are the matched videos
+// of the parts. Nothing is written in dry run mode.
+func (vsm *VideoSubtitleMatcher) writeJoinedSubtitle(ctx context.Context, paths, videos []string, joinedPath string) (estimated, err error) {
+	if vsm.dryRun {
+		return nil, nil
+	}
+
+	var joined []srtCue
+	var offset time.Duration
+	for n, path := range paths {
+		doc, err := readSRT(vsm.fs, path)
+		if err != nil {
+			return nil, err
+		}
+		end := offset
+		for _, cue := range doc.Cues {
+			cue.Start += offset
+			cue.End += offset
+			joined = append(joined, cue)
+			end = max(end, cue.End)
+		}
+		if n == len(paths)-1 {
+			break
+		}
+
+		duration, err := vsm.partVideoDuration(ctx, path, videos[n])
+		if err != nil {
+			if estimated == nil {
+				estimated = err
+			}
+			offset = end
+			continue
+		}
+		offset += duration
+	}
+
+	return estimated, vsm.fs.WriteFile(joinedPath, []byte(formatSRT(joined, "\n")))
+}
+
+// partVideoDuration probes the duration of the video part a subtitle part
+// was made for: a video named like the subtitle, next to it or to the joined
+// video it was matched to.
+func (vsm *VideoSubtitleMatcher) partVideoDuration(ctx context.Context, subtitlePath, videoPath string) (time.Duration, error) {
+	if !vsm.isLocal() {
+		return 0, fmt.Errorf("cannot probe video parts: not on the local file system")
+	}
+	dirs := []string{filepath.Dir(subtitlePath)}
+	if dir := filepath.Dir(videoPath); dir != dirs[0] {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		for _, ext := range vsm.videoExtensions {
+			partPath := filepath.Join(dir, titleOf(subtitlePath)+ext)
+			if exists(vsm.fs, partPath) {
+				return probeDuration(ctx, partPath)
+			}
+		}
+	}
+	return 0, fmt.Errorf("no video part named like %s", filepath.Base(subtitlePath))
+}
+
+// logJoin logs the outcome of joining the parts of a subtitle
+func (vsm *VideoSubtitleMatcher) logJoin(results []MatchResult, indexes []int, estimated, err error) {
+	if !vsm.verbose {
+		return
+	}
+
+	first := results[indexes[0]]
+	fmt.Printf("\nJoining parts for %s:\n", filepath.Base(first.VideoPath))
+	for n, i := range indexes {
+		fmt.Printf("  Part %d: %s\n", n+1, filepath.Base(results[i].SubtitlePath))
+	}
+	fmt.Printf("  Joined: %s\n", filepath.Base(first.JoinedSubtitlePath))
+	if estimated != nil {
+		vsm.printf(colorYellow, "  ! Parts start after the previous part's last cue, timing may be early: %v\n", estimated)
+	}
+
+	switch {
+	case err != nil:
+		vsm.printf(colorRed, "  Error joining: %v\n", err)
+	case !vsm.dryRun:
+		vsm.printf(colorGreen, "  ✓ Joined successfully\n")
+	}
+}
+
+// logJoinSkipped logs a join left out because its name is taken
+func (vsm *VideoSubtitleMatcher) logJoinSkipped(first MatchResult, err error) {
+	if vsm.verbose {
+		vsm.printf(colorGray, "\nSkipping joining parts for %s: %v\n", filepath.Base(first.VideoPath), err)
+	}
+}
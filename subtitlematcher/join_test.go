@@ -0,0 +1,70 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJoinParts(t *testing.T) {
+	const (
+		part1 = "1\n00:00:01,000 --> 00:00:02,000\nFirst part\n"
+		part2 = "1\n00:00:01,000 --> 00:00:02,000\nSecond part\n"
+		whole = "1\n00:00:01,000 --> 00:00:02,000\nA complete subtitle of its own\n"
+	)
+	tests := []struct {
+		name       string
+		subtitles  map[string]string
+		only       bool
+		mappings   string
+		wantJoined bool
+	}{
+		{"joined", map[string]string{"Movie.part1.srt": part1, "Movie.part2.srt": part2}, false, "", true},
+		// Only the parts are matched, so Movie.srt stays where it is
+		{"existing", map[string]string{"Movie.part1.srt": part1, "Movie.part2.srt": part2, "Movie.srt": whole}, true, "", false},
+		{"planned", map[string]string{"Movie.part1.srt": part1, "Movie.part2.srt": part2, "complete.srt": whole}, false, "complete.srt -> Movie.mkv\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, "Movie.mkv")
+			writeSubtitles(t, dir, tt.subtitles)
+
+			options := []Option{Verbose(false), DryRun(false), JoinParts(true)}
+			if tt.only {
+				options = append(options, Files([]string{filepath.Join(dir, "Movie.part1.srt"), filepath.Join(dir, "Movie.part2.srt")}))
+			}
+			if tt.mappings != "" {
+				mappings := filepath.Join(t.TempDir(), "mappings.txt")
+				if err := os.WriteFile(mappings, []byte(tt.mappings), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				options = append(options, MappingFile(mappings))
+			}
+
+			results, _ := New(dir, options...).Match()
+			for _, name := range []string{"Movie.part1.srt", "Movie.part2.srt"} {
+				result := matchOf(t, results, name)
+				if joined := result.JoinedSubtitlePath != ""; joined != tt.wantJoined {
+					t.Errorf("%s: JoinedSubtitlePath = %q, want joined %v", name, result.JoinedSubtitlePath, tt.wantJoined)
+				}
+				if tt.wantJoined && result.Error != nil {
+					t.Errorf("%s: Error = %v", name, result.Error)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "Movie.srt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantJoined {
+				if !strings.Contains(string(data), "First part") || !strings.Contains(string(data), "Second part") {
+					t.Errorf("Movie.srt = %q, want both parts", data)
+				}
+			} else if !strings.Contains(string(data), "of its own") {
+				t.Errorf("Movie.srt = %q, want the complete subtitle of its own", data)
+			}
+		})
+	}
+}
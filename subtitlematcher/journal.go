@@ -0,0 +1,274 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// journalFileName is the intent journal written in the target directory
+// before any file is renamed.
+const journalFileName = ".subtitle-matcher.journal"
+
+// RecoveryMode controls how renames left unfinished by an interrupted run are
+// resolved when the next run starts.
+type RecoveryMode int
+
+const (
+	// RecoveryComplete performs the renames the interrupted run had planned
+	// but not finished.
+	RecoveryComplete RecoveryMode = iota
+	// RecoveryRollback moves subtitles the interrupted run had already renamed
+	// back to their original names. Format conversions cannot be rolled back
+	// because the original file no longer exists; they are reported in
+	// verbose output and left converted.
+	RecoveryRollback
+)
+
+// errConversionKept is returned by rollbackRename for a finished format
+// conversion, whose original file no longer exists.
+var errConversionKept = errors.New("cannot roll back conversion: original file is gone")
+
+// journalEntry is one line of the journal: either an intent to rename From to
+// To, written before any rename happens, or a Done marker for a finished rename.
+type journalEntry struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Convert bool   `json:"convert,omitempty"`
+	Done    string `json:"done,omitempty"`
+}
+
+// renameJournal records planned renames so an interrupted run can be resolved.
+type renameJournal struct {
+	path string
+	file *os.File
+}
+
+// journaled reports whether the run journals its renames. Recovery moves files
+// on local disk, so runs on other file systems or through a Renamer, which
+// may copy, link or only record, are not journaled.
+func (vsm *VideoSubtitleMatcher) journaled() bool {
+	return !vsm.dryRun && vsm.isLocal() && vsm.renamer == nil
+}
+
+// openJournal writes the intent of every rename in results to the journal and
+// syncs it to disk before returning. Returns nil when nothing will be renamed.
+func (vsm *VideoSubtitleMatcher) openJournal(results []MatchResult) (*renameJournal, error) {
+	var intents []journalEntry
+	for _, result := range results {
+		// Extractions leave the archive untouched, so there is nothing to recover
+		if !willRename(result) || result.Archive != "" {
+			continue
+		}
+		intents = append(intents, journalEntry{
+			From:    result.SubtitlePath,
+			To:      result.NewSubtitlePath,
+			Convert: vsm.needsConversion(result),
+		})
+		if result.PairedPath != "" {
+			intents = append(intents, journalEntry{From: result.PairedPath, To: withExt(result.NewSubtitlePath, vobSubDataExt)})
+		}
+	}
+	if len(intents) == 0 {
+		return nil, nil
+	}
+
+	path := filepath.Join(vsm.directory, journalFileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &renameJournal{path: path, file: file}
+	for _, intent := range intents {
+		if err := journal.write(intent); err != nil {
+			journal.close()
+			return nil, err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		journal.close()
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+// write appends one entry to the journal.
+func (j *renameJournal) write(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+// markDone records that a subtitle has been renamed. A nil journal is a no-op.
+func (j *renameJournal) markDone(result MatchResult) error {
+	if j == nil || !result.Renamed || result.SubtitlePath == result.NewSubtitlePath {
+		return nil
+	}
+	if err := j.write(journalEntry{Done: result.SubtitlePath}); err != nil {
+		return err
+	}
+	if result.PairedPath != "" {
+		if err := j.write(journalEntry{Done: result.PairedPath}); err != nil {
+			return err
+		}
+	}
+	return j.file.Sync()
+}
+
+// close closes and removes the journal once every rename has been attempted.
+// A nil journal is a no-op.
+func (j *renameJournal) close() error {
+	if j == nil {
+		return nil
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.path)
+}
+
+// readJournal returns the renames in the journal at path in the order they
+// were planned. Renames marked done are left out unless includeDone is set.
+func readJournal(path string, includeDone bool) ([]journalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var intents []journalEntry
+	done := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry journalEntry
+		// A torn last line means the run died while writing it; the rename it
+		// describes never started
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Done != "" {
+			done[entry.Done] = true
+		} else {
+			intents = append(intents, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if includeDone {
+		return intents, nil
+	}
+
+	pending := intents[:0]
+	for _, intent := range intents {
+		if !done[intent.From] {
+			pending = append(pending, intent)
+		}
+	}
+	return pending, nil
+}
+
+// recoverJournal resolves renames left unfinished by an interrupted run,
+// according to the configured recovery mode, and removes the journal.
+func (vsm *VideoSubtitleMatcher) recoverJournal() error {
+	path := filepath.Join(vsm.directory, journalFileName)
+	if !fileExists(path) {
+		return nil
+	}
+
+	// Rolling back also undoes the renames the run had finished, latest first
+	// so that a name freed by one rename is restored before it is reused
+	rollback := vsm.recovery == RecoveryRollback
+	pending, err := readJournal(path, rollback)
+	if err != nil {
+		return err
+	}
+	if rollback {
+		slices.Reverse(pending)
+	}
+
+	if vsm.verbose && len(pending) > 0 {
+		fmt.Printf("Recovering %d renames from an interrupted run\n", len(pending))
+	}
+
+	var errs []error
+	for _, entry := range pending {
+		var err error
+		if rollback {
+			err = rollbackRename(entry)
+		} else {
+			err = completeRename(entry)
+		}
+		if errors.Is(err, errConversionKept) {
+			// Retrying cannot bring the original back, so keeping the journal
+			// would only make every later run fail
+			if vsm.verbose {
+				vsm.printf(colorYellow, "  ! Kept conversion of %s to %s: original file is gone\n",
+					filepath.Base(entry.From), filepath.Base(entry.To))
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.From, err))
+			if vsm.verbose {
+				vsm.printf(colorRed, "  Error recovering %s: %v\n", filepath.Base(entry.From), err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		// Keep the journal so the next run can try again
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// completeRename finishes an interrupted rename. A conversion that was
+// interrupted after writing the target is redone, since the target may be
+// incomplete.
+func completeRename(entry journalEntry) error {
+	if !fileExists(entry.From) {
+		return nil // already finished
+	}
+	if !entry.Convert {
+		return os.Rename(entry.From, entry.To)
+	}
+	if !isMicroDVD(LocalFileSystem{}, entry.From) {
+		return convertVTTToSRT(LocalFileSystem{}, entry.From, entry.To)
+	}
+	// MicroDVD needs the video frame rate, which is no longer known here;
+	// fall back to the default like a failed probe would
+	return convertMicroDVDToSRT(LocalFileSystem{}, entry.From, entry.To, defaultFrameRate)
+}
+
+// rollbackRename undoes an interrupted rename.
+func rollbackRename(entry journalEntry) error {
+	switch {
+	case fileExists(entry.From) && entry.Convert:
+		// The conversion may have written a partial target
+		if err := os.Remove(entry.To); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	case fileExists(entry.From):
+		return nil // never renamed
+	case entry.Convert:
+		return errConversionKept
+	}
+	return os.Rename(entry.To, entry.From)
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
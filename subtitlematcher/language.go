@@ -0,0 +1,108 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// languageCodes maps language tags commonly found in subtitle filenames to
+// ISO 639-1 codes.
+var languageCodes = map[string]string{
+	"en": "en", "eng": "en", "english": "en",
+	"zh": "zh", "chi": "zh", "zho": "zh", "chs": "zh", "cht": "zh", "chinese": "zh", "简体": "zh", "繁体": "zh", "中文": "zh",
+	"ja": "ja", "jpn": "ja", "japanese": "ja",
+	"ko": "ko", "kor": "ko", "korean": "ko",
+	"fr": "fr", "fre": "fr", "fra": "fr", "french": "fr",
+	"de": "de", "ger": "de", "deu": "de", "german": "de",
+	"es": "es", "spa": "es", "spanish": "es",
+	"it": "it", "ita": "it", "italian": "it",
+	"pt": "pt", "por": "pt", "portuguese": "pt",
+	"ru": "ru", "rus": "ru", "russian": "ru",
+	"ar": "ar", "ara": "ar", "arabic": "ar",
+	"nl": "nl", "dut": "nl", "nld": "nl", "dutch": "nl",
+	"sv": "sv", "swe": "sv", "swedish": "sv",
+	"pl": "pl", "pol": "pl", "polish": "pl",
+	"tr": "tr", "tur": "tr", "turkish": "tr",
+	"vi": "vi", "vie": "vi", "vietnamese": "vi",
+	"th": "th", "tha": "th", "thai": "th",
+}
+
+// languageNames are the spelled-out language names accepted anywhere in a filename.
+var languageNames = map[string]bool{
+	"english": true, "chinese": true, "japanese": true, "korean": true, "french": true,
+	"german": true, "spanish": true, "italian": true, "portuguese": true, "russian": true,
+	"arabic": true, "dutch": true, "swedish": true, "polish": true, "turkish": true,
+	"vietnamese": true, "thai": true, "简体": true, "繁体": true, "中文": true,
+}
+
+// youtubeLanguagePattern matches the language in YouTube subtitle names such
+// as "-_YouTube-zh-CN-dual-double".
+var youtubeLanguagePattern = regexp.MustCompile(`(?i)youtube-([a-z]{2,3})(?:-|$)`)
+
+// languageTokenPattern splits filenames into word tokens.
+var languageTokenPattern = regexp.MustCompile(`[\p{L}]+`)
+
+// maxLanguageSuffixes is how many dot-separated suffixes are checked for a language tag.
+const maxLanguageSuffixes = 3
+
+// detectLanguage returns the ISO 639-1 code of the language a subtitle file is
+// tagged with, or an empty string if no language tag is found.
+//
+// Recognized tags are dot-separated suffixes ("Movie.en.srt", "Movie.zh-CN.forced.srt"),
+// YouTube subtitle suffixes ("-_YouTube-zh-CN") and spelled-out language names.
+func detectLanguage(subtitlePath string) string {
+	name := strings.TrimSuffix(filepath.Base(subtitlePath), filepath.Ext(subtitlePath))
+
+	segments := strings.Split(name, ".")
+	if i := languageSuffix(segments); i > 0 {
+		return suffixLanguage(segments[i])
+	}
+
+	if m := youtubeLanguagePattern.FindStringSubmatch(name); m != nil {
+		if code, ok := languageCodes[strings.ToLower(m[1])]; ok {
+			return code
+		}
+	}
+
+	for _, token := range languageTokenPattern.FindAllString(strings.ToLower(name), -1) {
+		if languageNames[token] {
+			return languageCodes[token]
+		}
+	}
+
+	return ""
+}
+
+// suffixLanguage returns the ISO 639-1 code of a dot-separated filename
+// suffix such as "en" or "zh-CN", or an empty string if it is not a language tag.
+func suffixLanguage(suffix string) string {
+	tag := strings.ToLower(suffix)
+	if j := strings.IndexAny(tag, "-_"); j > 0 {
+		tag = tag[:j]
+	}
+	return languageCodes[tag]
+}
+
+// languageSuffix returns the index of the last of the checked suffixes of a
+// filename split at its dots that is a language tag, or -1 if there is none.
+func languageSuffix(segments []string) int {
+	for i := len(segments) - 1; i > 0 && i >= len(segments)-maxLanguageSuffixes; i-- {
+		if suffixLanguage(segments[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// withoutLanguageTag removes the dot-separated language tag from a path
+// without extension, e.g. "dir/Movie" for "dir/Movie.zh".
+func withoutLanguageTag(path string) string {
+	dir, name := filepath.Split(path)
+	segments := strings.Split(name, ".")
+	i := languageSuffix(segments)
+	if i < 0 {
+		return path
+	}
+	return dir + strings.Join(append(segments[:i:i], segments[i+1:]...), ".")
+}
@@ -0,0 +1,51 @@
+package subtitlematcher
+
+import (
+	"sort"
+)
+
+// languageRank returns the position of a language in the priority order.
+// Unlisted and undetected languages rank after all listed ones.
+func (vsm *VideoSubtitleMatcher) languageRank(language string) int {
+	for i, preferred := range vsm.languagePriority {
+		if language == preferred {
+			return i
+		}
+	}
+	return len(vsm.languagePriority)
+}
+
+// preferLanguages resolves subtitles of different languages planned for the
+// same canonical name. The subtitle whose language ranks highest keeps the
+// canonical name, along with any others of its language; the rest are
+// renamed with their language tag, e.g. "Movie.zh.srt", or are skipped when
+// their language is unknown and report the winner in DuplicateOf.
+func (vsm *VideoSubtitleMatcher) preferLanguages(results []MatchResult) {
+	for _, indices := range groupByTarget(results) {
+		if len(indices) < 2 {
+			continue
+		}
+
+		sort.SliceStable(indices, func(a, b int) bool {
+			ra := vsm.languageRank(results[indices[a]].Language)
+			rb := vsm.languageRank(results[indices[b]].Language)
+			if ra != rb {
+				return ra < rb
+			}
+			return results[indices[a]].SubtitlePath < results[indices[b]].SubtitlePath
+		})
+
+		winner := results[indices[0]]
+		for _, i := range indices[1:] {
+			switch results[i].Language {
+			case winner.Language:
+				// Left to PreferQuality and FormatPreference
+			case "":
+				results[i].DuplicateOf = winner.SubtitlePath
+				results[i].NewSubtitlePath = ""
+			default:
+				results[i].NewSubtitlePath = insertTag(results[i].NewSubtitlePath, "."+results[i].Language)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Library matches several root directories in one call, each with its own
+// options, e.g. a TV root matched in ModeTV and a movie root with a higher
+// threshold:
+//
+//	library := subtitlematcher.NewLibrary(subtitlematcher.DryRun(false))
+//	library.AddRoot("/media/tv", subtitlematcher.Mode(subtitlematcher.ModeTV))
+//	library.AddRoot("/media/movies", subtitlematcher.SimilarityThreshold(0.8))
+//	report, err := library.Run(ctx)
+type Library struct {
+	options []Option
+	roots   []libraryRoot
+}
+
+// libraryRoot is a root directory of a Library with its matcher.
+type libraryRoot struct {
+	directory string
+	matcher   *VideoSubtitleMatcher
+}
+
+// RootReport is the outcome of matching one root of a Library.
+type RootReport struct {
+	Directory string        // Root directory
+	Results   []MatchResult // Results of the root's run
+	Summary   Summary       // Counts of the root's matched, renamed and failed subtitles
+	Err       error         // Error the root's run returned, if any
+}
+
+// LibraryReport is the outcome of matching every root of a Library.
+type LibraryReport struct {
+	Roots []RootReport // One entry per root, in the order they were added
+}
+
+// Results returns the results of all roots, merged in root order.
+func (r *LibraryReport) Results() []MatchResult {
+	var results []MatchResult
+	for _, root := range r.Roots {
+		results = append(results, root.Results...)
+	}
+	return results
+}
+
+// Summary returns the counts of all roots added up. Its Directory is empty
+// and its DryRun is set when every root was a dry run.
+func (r *LibraryReport) Summary() Summary {
+	summary := Summary{DryRun: true}
+	for _, root := range r.Roots {
+		summary.DryRun = summary.DryRun && root.Summary.DryRun
+		summary.Matched += root.Summary.Matched
+		summary.Renamed += root.Summary.Renamed
+		summary.Failed += root.Summary.Failed
+		summary.Results = append(summary.Results, root.Summary.Results...)
+	}
+	return summary
+}
+
+// NewLibrary creates an empty Library. The options apply to every root,
+// before the root's own.
+func NewLibrary(options ...Option) *Library {
+	return &Library{options: options}
+}
+
+// AddRoot adds a root directory matched with the library's options followed
+// by the given ones, which override them. Roots are matched in the order
+// they are added.
+func (l *Library) AddRoot(directory string, options ...Option) *Library {
+	all := append(append([]Option(nil), l.options...), options...)
+	l.roots = append(l.roots, libraryRoot{directory: directory, matcher: New(directory, all...)})
+	return l
+}
+
+// Run matches every root in turn. A root whose run fails does not stop the
+// others; every failure is returned, prefixed with its root, and also
+// reported in the root's Err. Cancelling ctx stops the run after the
+// current root.
+func (l *Library) Run(ctx context.Context) (*LibraryReport, error) {
+	report := &LibraryReport{}
+	var errs []error
+	for _, root := range l.roots {
+		if err := ctx.Err(); err != nil {
+			return report, errors.Join(append(errs, err)...)
+		}
+
+		results, err := root.matcher.MatchContext(ctx)
+		report.Roots = append(report.Roots, RootReport{
+			Directory: root.directory,
+			Results:   results,
+			Summary:   root.matcher.summarize(results),
+			Err:       err,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", root.directory, err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// MatchContext matches every root like Run and returns the merged results,
+// so that a Library can be used as a Matcher.
+func (l *Library) MatchContext(ctx context.Context) ([]MatchResult, error) {
+	report, err := l.Run(ctx)
+	return report.Results(), err
+}
+
+// Library implements Matcher.
+var _ Matcher = (*Library)(nil)
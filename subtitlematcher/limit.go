@@ -0,0 +1,42 @@
+package subtitlematcher
+
+import "fmt"
+
+// ConfirmFunc asks whether a run planning more renames than the
+// MaxOperations limit may go ahead, given the number of planned renames.
+type ConfirmFunc func(planned int) bool
+
+// OperationLimitError is returned by runs whose plan renames more files than
+// the MaxOperations limit allows, unless the confirmation approved it. No
+// files are renamed when it is returned.
+type OperationLimitError struct {
+	Planned int // Renames in the plan
+	Limit   int // Largest number of renames allowed
+}
+
+// Error describes the planned and allowed number of renames.
+func (e *OperationLimitError) Error() string {
+	return fmt.Sprintf("plan renames %d files, more than the limit of %d; nothing renamed", e.Planned, e.Limit)
+}
+
+// checkOperationLimit aborts runs planning more renames than allowed, unless
+// the confirmation approves them.
+func (vsm *VideoSubtitleMatcher) checkOperationLimit(planned []MatchResult) error {
+	if vsm.maxOperations <= 0 || vsm.dryRun {
+		return nil
+	}
+
+	count := 0
+	for _, result := range planned {
+		if willRename(result) {
+			count++
+		}
+	}
+	if count <= vsm.maxOperations {
+		return nil
+	}
+	if vsm.confirmOperations != nil && vsm.confirmOperations(count) {
+		return nil
+	}
+	return &OperationLimitError{Planned: count, Limit: vsm.maxOperations}
+}
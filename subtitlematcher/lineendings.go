@@ -0,0 +1,80 @@
+package subtitlematcher
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// LineEnding selects the line endings renamed text subtitles are written with.
+type LineEnding int
+
+const (
+	// LineEndingUnchanged leaves line endings as they are.
+	LineEndingUnchanged LineEnding = iota
+	// LineEndingLF writes Unix line endings ("\n").
+	LineEndingLF
+	// LineEndingCRLF writes Windows line endings ("\r\n"), which some
+	// hardware players require.
+	LineEndingCRLF
+)
+
+// BOMMode controls the UTF-8 byte order mark of renamed text subtitles.
+type BOMMode int
+
+const (
+	// BOMUnchanged leaves the byte order mark as it is.
+	BOMUnchanged BOMMode = iota
+	// BOMStrip removes the byte order mark, which breaks some hardware players.
+	BOMStrip
+	// BOMAdd adds a byte order mark to UTF-8 subtitles without one, for
+	// players that otherwise guess the wrong encoding.
+	BOMAdd
+)
+
+// normalizesText reports whether renamed subtitles have their line endings
+// or byte order mark rewritten.
+func (vsm *VideoSubtitleMatcher) normalizesText() bool {
+	return vsm.lineEnding != LineEndingUnchanged || vsm.bomMode != BOMUnchanged
+}
+
+// normalizeText rewrites the line endings and byte order mark of a text
+// subtitle in place. Image-based subtitles are left untouched, and so are
+// files that are not valid UTF-8, whose encoding a byte order mark would
+// misstate. Reports whether the file was rewritten.
+func normalizeText(fsys FileSystem, path string, ending LineEnding, bom BOMMode) (bool, error) {
+	if isImageSubtitle(fsys, path) {
+		return false, nil
+	}
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	if !utf8.Valid(data) {
+		return false, nil
+	}
+	content := string(data)
+
+	normalized := content
+	switch ending {
+	case LineEndingLF:
+		normalized = strings.ReplaceAll(normalized, "\r\n", "\n")
+	case LineEndingCRLF:
+		normalized = strings.ReplaceAll(strings.ReplaceAll(normalized, "\r\n", "\n"), "\n", "\r\n")
+	}
+	switch bom {
+	case BOMStrip:
+		normalized = strings.TrimPrefix(normalized, utf8BOM)
+	case BOMAdd:
+		if !strings.HasPrefix(normalized, utf8BOM) {
+			normalized = utf8BOM + normalized
+		}
+	}
+
+	if normalized == content {
+		return false, nil
+	}
+	if err := fsys.WriteFile(path, []byte(normalized)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
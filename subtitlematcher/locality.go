@@ -0,0 +1,41 @@
+package subtitlematcher
+
+import "path/filepath"
+
+// LocalityMode controls whether videos in the subtitle's own directory are
+// favored over videos elsewhere in the scanned tree.
+type LocalityMode int
+
+const (
+	// LocalityAny considers every scanned video equally.
+	LocalityAny LocalityMode = iota
+	// LocalityPrefer considers only videos in the subtitle's directory first,
+	// and falls back to all videos when none of them reaches the threshold.
+	LocalityPrefer
+	// LocalityOnly considers only videos in the subtitle's directory.
+	LocalityOnly
+)
+
+// localVideos returns the videos a subtitle is matched against under the
+// configured locality mode.
+func (vsm *VideoSubtitleMatcher) localVideos(subtitlePath string, videoFiles []string) []string {
+	if vsm.locality == LocalityAny {
+		return videoFiles
+	}
+
+	dir := subtitleDir(subtitlePath)
+	var local []string
+	for _, videoPath := range videoFiles {
+		if filepath.Dir(videoPath) == dir {
+			local = append(local, videoPath)
+		}
+	}
+
+	if vsm.locality == LocalityOnly {
+		return local
+	}
+	if _, score, _ := vsm.findBestMatch(subtitlePath, local); score >= vsm.similarityThreshold {
+		return local
+	}
+	return videoFiles
+}
@@ -0,0 +1,153 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockFileName is the run lock created in the target directory while renaming.
+const lockFileName = ".subtitle-matcher.lock"
+
+// lockToken identifies this process in the lock files it writes. A process
+// restarted in a container often gets the same PID, host name and boot time
+// as the one before it, so only the token tells them apart.
+var lockToken = fmt.Sprintf("%016x", rand.Uint64())
+
+// ErrLocked is returned when another run holds the lock on the target directory.
+var ErrLocked = errors.New("another run is in progress")
+
+// runLock is an exclusive lock held by a run that renames files.
+type runLock struct {
+	path  string
+	stale string // Owner of the stale lock this one replaced, if any
+}
+
+// acquireLock creates the lock file in dir. It fails with ErrLocked when the
+// file already exists, so overlapping runs (e.g. from cron) cannot race each
+// other's renames. The file records the owner's PID, host, start time and
+// token; a lock left behind by a run on this host whose process is gone, after a crash,
+// kill -9 or power loss, is taken over.
+func acquireLock(dir string) (*runLock, error) {
+	path := filepath.Join(dir, lockFileName)
+
+	var stale string
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		owner, _ := os.ReadFile(path)
+		stale = strings.TrimSpace(string(owner))
+		if !staleLock(string(owner)) {
+			return nil, fmt.Errorf("%w: %s is held by %s (remove it if no other run is active)",
+				ErrLocked, path, strings.TrimSpace(string(owner)))
+		}
+		// Only remove the lock if no other run took it over in the meantime
+		if current, _ := os.ReadFile(path); string(current) == string(owner) {
+			os.Remove(path)
+		}
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s was taken over by another run", ErrLocked, path)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(file, "pid %d on %s since %s token %s\n", os.Getpid(), lockHost(), time.Now().Format(time.RFC3339), lockToken)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	return &runLock{path: path, stale: stale}, nil
+}
+
+// lockHost returns the host name recorded in lock files.
+func lockHost() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return strings.Fields(host)[0]
+}
+
+// staleLock reports whether the owner recorded in a lock file is a process
+// of this host that no longer runs, or one started before the host last
+// booted, whose PID another process may have since been given. A lock with
+// this process's PID but not its token was left by an earlier process with
+// the same PID. Locks of other hosts, e.g. on a shared network drive, and of
+// unknown owners are never stale.
+func staleLock(owner string) bool {
+	var pid int
+	var host, since string
+	if _, err := fmt.Sscanf(owner, "pid %d on %s since %s", &pid, &host, &since); err != nil {
+		return false
+	}
+	if pid <= 0 || host != lockHost() {
+		return false
+	}
+	if pid == os.Getpid() {
+		var token string
+		if fields := strings.Fields(owner); len(fields) == 8 && fields[6] == "token" {
+			token = fields[7]
+		}
+		return token != lockToken
+	}
+	if started, err := time.Parse(time.RFC3339, since); err == nil {
+		if boot, ok := bootTime(); ok && started.Before(boot) {
+			return true
+		}
+	}
+	return !processAlive(pid)
+}
+
+// bootTime returns when the host booted, where the system tells (Linux).
+func bootTime() (time.Time, bool) {
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(stat), "\n") {
+		if seconds, ok := strings.CutPrefix(line, "btime "); ok {
+			if n, err := strconv.ParseInt(strings.TrimSpace(seconds), 10, 64); err == nil {
+				return time.Unix(n, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// processAlive reports whether a process with the given PID is running.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess only succeeds for running processes
+		process.Release()
+		return true
+	}
+	// Signal 0 checks for the process without signaling it; EPERM means it
+	// runs as another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// release removes the lock file.
+func (l *runLock) release() error {
+	if l == nil {
+		return nil
+	}
+	return os.Remove(l.path)
+}
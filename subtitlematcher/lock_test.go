@@ -0,0 +1,184 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLock writes a lock file owned by pid on host, started at since, with
+// token unless it is empty.
+func writeLock(t *testing.T, dir string, pid int, host string, since time.Time, token string) string {
+	t.Helper()
+	owner := fmt.Sprintf("pid %d on %s since %s", pid, host, since.Format(time.RFC3339))
+	if token != "" {
+		owner += " token " + token
+	}
+	owner += "\n"
+	if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte(owner), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(owner)
+}
+
+// exitedPID returns the PID of a process that has exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("liveness of other processes is not checked on Windows")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestAcquireLockTakesOverLockOfExitedProcess(t *testing.T) {
+	dir := t.TempDir()
+	owner := writeLock(t, dir, exitedPID(t), lockHost(), time.Now(), "")
+
+	lock, err := acquireLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.release()
+	if lock.stale != owner {
+		t.Errorf("stale owner is %q, want %q", lock.stale, owner)
+	}
+	content, _ := os.ReadFile(lock.path)
+	if !strings.HasPrefix(string(content), fmt.Sprintf("pid %d on ", os.Getpid())) {
+		t.Errorf("lock holds %q", content)
+	}
+}
+
+func TestAcquireLockTakesOverLockFromBeforeBoot(t *testing.T) {
+	if _, ok := bootTime(); !ok {
+		t.Skip("boot time unknown")
+	}
+	dir := t.TempDir()
+	// The PID of a run before the last boot may belong to a live process now
+	writeLock(t, dir, os.Getppid(), lockHost(), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), "")
+
+	lock, err := acquireLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.release()
+}
+
+func TestAcquireLockTakesOverLockOfEarlierProcessWithSamePID(t *testing.T) {
+	dir := t.TempDir()
+	// A container restarted after a crash gives the new run the same PID
+	writeLock(t, dir, os.Getpid(), lockHost(), time.Now(), "0123456789abcdef")
+
+	lock, err := acquireLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.release()
+}
+
+func TestAcquireLockRefusesHeldLocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		pid   int
+		host  string
+		token string
+	}{
+		{"live process", os.Getppid(), lockHost(), ""},
+		{"this process", os.Getpid(), lockHost(), lockToken},
+		{"other host", 1 << 30, "elsewhere", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeLock(t, dir, tt.pid, tt.host, time.Now(), tt.token)
+
+			if _, err := acquireLock(dir); !errors.Is(err, ErrLocked) {
+				t.Errorf("acquireLock returned %v, want ErrLocked", err)
+			}
+		})
+	}
+}
+
+func TestMatchRecoversJournalAfterTakingOverLock(t *testing.T) {
+	dir := writeFiles(t, "old.srt")
+	writeLock(t, dir, exitedPID(t), lockHost(), time.Now(), "")
+	journal := `{"from":"` + filepath.ToSlash(filepath.Join(dir, "old.srt")) + `","to":"` + filepath.ToSlash(filepath.Join(dir, "new.srt")) + `"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, journalFileName), []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(dir, DryRun(false), Verbose(false)).Match(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.srt")); err != nil {
+		t.Errorf("interrupted rename not completed: %v", err)
+	}
+	for _, name := range []string{lockFileName, journalFileName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left behind: %v", name, err)
+		}
+	}
+}
+
+func TestMatchRollsBackFinishedRenamesFromJournal(t *testing.T) {
+	dir := writeFiles(t, "Movie.srt", "b.srt")
+	writeLock(t, dir, exitedPID(t), lockHost(), time.Now(), "")
+	path := func(name string) string { return filepath.ToSlash(filepath.Join(dir, name)) }
+	journal := `{"from":"` + path("a.srt") + `","to":"` + path("Movie.srt") + `"}` + "\n" +
+		`{"from":"` + path("b.srt") + `","to":"` + path("Movie.en.srt") + `"}` + "\n" +
+		`{"done":"` + path("a.srt") + `"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, journalFileName), []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(dir, DryRun(false), Verbose(false), Recovery(RecoveryRollback)).Match(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.srt", "b.srt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not restored: %v", name, err)
+		}
+	}
+	for _, name := range []string{"Movie.srt", "Movie.en.srt", journalFileName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left behind: %v", name, err)
+		}
+	}
+}
+
+func TestMatchRollbackKeepsFinishedConversions(t *testing.T) {
+	dir := writeFiles(t, "Movie.srt", "Other.srt")
+	writeLock(t, dir, exitedPID(t), lockHost(), time.Now(), "")
+	path := func(name string) string { return filepath.ToSlash(filepath.Join(dir, name)) }
+	journal := `{"from":"` + path("movie.vtt") + `","to":"` + path("Movie.srt") + `","convert":true}` + "\n" +
+		`{"from":"` + path("other.srt") + `","to":"` + path("Other.srt") + `"}` + "\n" +
+		`{"done":"` + path("movie.vtt") + `"}` + "\n" +
+		`{"done":"` + path("other.srt") + `"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, journalFileName), []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 2; run++ {
+		if _, err := New(dir, DryRun(false), Verbose(false), Recovery(RecoveryRollback)).Match(); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	for _, name := range []string{"Movie.srt", "other.srt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalFileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal left behind: %v", err)
+	}
+}
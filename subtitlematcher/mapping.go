@@ -0,0 +1,86 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mappingTable holds manual overrides from a mappings file, keyed by
+// subtitle file name or path relative to the matcher's directory.
+type mappingTable map[string]string
+
+// loadMappings reads a mappings file. Each non-empty line that does not
+// start with '#' has the form
+//
+//	subtitle -> video
+//	subtitle -> target name
+//
+// where names are file names or paths relative to the scanned directory.
+func loadMappings(path string) (mappingTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mappings := make(mappingTable)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		subtitle, target, ok := strings.Cut(line, "->")
+		subtitle, target = strings.TrimSpace(subtitle), strings.TrimSpace(target)
+		if !ok || subtitle == "" || target == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"subtitle -> video or target name\"", path, lineNumber)
+		}
+		mappings[filepath.ToSlash(subtitle)] = target
+	}
+	return mappings, scanner.Err()
+}
+
+// lookup returns the mapping target for a subtitle, matching either its path
+// relative to root or its file name.
+func (m mappingTable) lookup(root, subtitlePath string) (string, bool) {
+	if len(m) == 0 {
+		return "", false
+	}
+	if rel, err := filepath.Rel(root, subtitlePath); err == nil {
+		if target, ok := m[filepath.ToSlash(rel)]; ok {
+			return target, true
+		}
+	}
+	target, ok := m[filepath.Base(subtitlePath)]
+	return target, ok
+}
+
+// mappedResult builds the result for a subtitle pinned by the mappings file.
+// A target naming a scanned video pairs the subtitle with it; any other target
+// is used as the new file name, keeping the subtitle's extension if it has none.
+func (vsm *VideoSubtitleMatcher) mappedResult(subtitlePath, target string, videoFiles []string) MatchResult {
+	result := MatchResult{
+		SubtitlePath: subtitlePath,
+		Similarity:   1.0,
+		Confidence:   ConfidenceHigh,
+		Mapped:       true,
+	}
+
+	for _, videoPath := range videoFiles {
+		rel, err := filepath.Rel(vsm.directory, videoPath)
+		if filepath.Base(videoPath) == target || (err == nil && filepath.ToSlash(rel) == filepath.ToSlash(target)) {
+			result.VideoPath = videoPath
+			return result
+		}
+	}
+
+	if filepath.Ext(target) == "" {
+		target += filepath.Ext(subtitlePath)
+	}
+	result.NewSubtitlePath = filepath.Join(filepath.Dir(subtitlePath), target)
+	return result
+}
@@ -0,0 +1,75 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// markupPattern matches HTML-like tags and ASS override blocks in cue text.
+var markupPattern = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+
+// assOverridePattern matches ASS override blocks such as {\an8} or {\i1}.
+var assOverridePattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// stripMarkup removes inline markup from a subtitle file in place. SRT files
+// lose HTML tags and ASS override blocks in cue text; ASS/SSA files lose
+// override blocks in Dialogue lines. Other formats are left untouched.
+// Reports whether the file was rewritten.
+func stripMarkup(fsys FileSystem, path string) (bool, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	content := string(data)
+
+	var stripped string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		stripped = stripSRTMarkup(content)
+	case ".ass", ".ssa":
+		stripped = stripASSMarkup(content)
+	default:
+		return false, nil
+	}
+
+	if stripped == content {
+		return false, nil
+	}
+	if err := fsys.WriteFile(path, []byte(stripped)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// stripSRTMarkup removes tags from SRT cue text lines, leaving indices and
+// timing lines intact.
+func stripSRTMarkup(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(strings.TrimPrefix(line, utf8BOM))
+		if cueIndexPattern.MatchString(trimmed) || lenientTimingPattern.MatchString(trimmed) {
+			continue
+		}
+		lines[i] = markupPattern.ReplaceAllString(line, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripASSMarkup removes override blocks from the text field of ASS/SSA
+// Dialogue lines.
+func stripASSMarkup(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "Dialogue:") {
+			continue
+		}
+		fields := strings.SplitN(line, ",", 10)
+		if len(fields) < 10 {
+			continue
+		}
+		fields[9] = assOverridePattern.ReplaceAllString(fields[9], "")
+		lines[i] = strings.Join(fields, ",")
+	}
+	return strings.Join(lines, "\n")
+}
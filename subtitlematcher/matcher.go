@@ -7,25 +7,123 @@
 package subtitlematcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // VideoSubtitleMatcher handles matching and renaming subtitle files to match video files.
 // It supports various video and subtitle formats and uses configurable similarity
 // algorithms to ensure accurate matching.
 type VideoSubtitleMatcher struct {
-	videoExtensions     []string // Supported video file extensions
-	subtitleExtensions  []string // Supported subtitle file extensions
-	directory           string   // Working directory
-	similarityThreshold float64  // Minimum similarity score for matching (0.0-1.0)
-	recursive           bool     // Whether to scan directories recursively
-	dryRun              bool     // Whether to perform actual file operations
-	verbose             bool     // Whether to output detailed information
-	ignoreExisting      bool     // Whether to skip files that are already correctly named
+	videoExtensions     []string      // Supported video file extensions
+	subtitleExtensions  []string      // Supported subtitle file extensions
+	directory           string        // Working directory
+	fileList            []string      // Files matched instead of scanning the directory (nil to scan)
+	similarityThreshold float64       // Minimum similarity score for matching (0.0-1.0)
+	recursive           bool          // Whether to scan directories recursively
+	dryRun              bool          // Whether to perform actual file operations
+	verbose             bool          // Whether to output detailed information
+	color               bool          // Whether verbose output and tables are colored with ANSI escape codes
+	ignoreExisting      bool          // Whether to skip files that are already correctly named
+	skipIncomplete      bool          // Whether to ignore empty videos and videos still being downloaded
+	settleTime          time.Duration // Ignore videos modified more recently than this
+	excludeSamples      bool          // Whether to ignore sample clips and extras
+	sampleSize          int64         // Size under which a video next to a much larger one is a sample
+	extrasFolders       []string      // Folders holding samples and extras rather than features
+	minVideoSize        int64         // Ignore videos smaller than this many bytes
+	minVideoDuration    time.Duration // Ignore videos shorter than this
+	processedMode       ProcessedMode // Whether subtitles earlier runs finished with are recorded and skipped
+	sdhMode             SDHMode       // How SDH (hearing-impaired) subtitles are treated
+	previewLines        int           // Number of cue lines to show for each match in verbose output
+	validate            bool          // Whether to validate SRT structure before renaming
+	repair              bool          // Whether to repair SRT cue numbering and timestamps after renaming
+	mergeTop            string        // Language shown on top in merged bilingual subtitles
+	joinParts           bool          // Whether to join multi-part subtitles of single-file videos
+	mergeBottom         string        // Language shown at the bottom in merged bilingual subtitles
+	splitBilingual      bool          // Whether to split bilingual subtitles into per-language files
+	ocrLanguage         string        // Tesseract language image subtitles are read in ("" to disable OCR)
+	transcriber         Transcriber   // Generates subtitles for videos without one (nil to disable)
+	providers           providerList  // Searched for subtitles for videos without one (empty to disable)
+	searchLanguages     []string      // Languages searched for, best first (empty for any)
+	downloadSubtitles   bool          // Whether the best subtitle found online is downloaded
+	translator          Translator    // Translates matched subtitles into translateTo (nil to disable)
+	translateTo         string        // Language matched subtitles are translated into
+	plannedNames        subtitleNames // New names of the subtitles planned in the run, never written by merging, joining, splitting, OCR or translation
+	convertVTT          bool          // Whether to convert WebVTT subtitles to SRT when renaming
+	stripMarkup         bool          // Whether to remove inline markup tags from cue text
+	lineEnding          LineEnding    // Line endings renamed text subtitles are written with
+	bomMode             BOMMode       // Whether renamed text subtitles get or lose a UTF-8 byte order mark
+	convertMicroDVD     bool          // Whether to convert frame-based MicroDVD subtitles to SRT
+	subtitleFrameRate   float64       // Frame rate SRT subtitles were timed for (0 to disable retiming)
+	diffOutput          io.Writer     // Where to write the dry run plan as a diff (nil to disable)
+	ndjsonOutput        io.Writer     // Where to stream each result as a JSON line (nil to disable)
+	debugOutput         io.Writer     // Where to write the trace of each run (nil to disable)
+	scriptOutput        io.Writer     // Where to write the dry run plan as a rename script (nil to disable)
+	scriptShell         ScriptShell   // Shell the rename script is written for
+	planOutput          io.Writer     // Where to write the dry run plan for Apply (nil to disable)
+	tableOutput         io.Writer     // Where to write the results as aligned tables (nil to disable)
+	groupOutput         io.Writer     // Where to write the results grouped by video as JSON (nil to disable)
+	explain             bool          // Whether to attach a score breakdown to each result
+	mappingFile         string        // Manual subtitle -> video/target overrides (empty to disable)
+	candidateCount      int           // Number of best candidate videos listed in each result
+	locality            LocalityMode  // Whether videos in the subtitle's directory are favored
+	mode                MediaMode     // Heuristics used to tell episodes or movies apart
+	episodeMapFile      string        // Absolute to seasonal episode numbers (empty to disable)
+	episodeMap          episodeMap    // Loaded episodeMapFile, set by scanFiles
+	directoryWeight     float64       // Weight of parent directory names in comparisons (0 to ignore them)
+	formatPreference    []string      // Subtitle extensions in order of preference for the canonical name
+	strict              bool          // Whether ambiguous plans abort execution
+	preflight           PreflightMode // What problems found by checking the plan before execution do
+	maxOperations       int           // Most renames a run may perform (0 for no limit)
+	confirmOperations   ConfirmFunc   // Approves runs exceeding maxOperations (nil to abort them)
+	allowUnsafeRoot     bool          // Whether runs may rename files in file system roots and home directories
+	protectedPaths      []string      // Directories runs never rename files in
+	sureThreshold       float64       // Score below which matches are held for review (0 to rename every match)
+	reviewMatch         ReviewFunc    // Approves held matches one by one (nil to hold them all)
+	heldOutput          io.Writer     // Where to write the plan of held matches (nil to disable)
+	changesOutput       io.Writer     // Where to write the changes since the previous run (nil to disable)
+	recovery            RecoveryMode  // How renames left unfinished by an interrupted run are resolved
+	timestamps          TimestampMode // Modification time given to renamed subtitles
+	history             HistoryStore  // Where runs are recorded, if anywhere
+	notifiers           []Notifier    // Told about finished runs that matched subtitles
+	metrics             *Metrics      // Collects counters and timings, if set
+	retryAttempts       int           // Attempts for file operations failing with transient errors
+	retryBackoff        time.Duration // Delay before the first retry, doubled after each one
+	operationTimeout    time.Duration // Longest each rename or conversion may take (0 for no limit)
+	runTimeout          time.Duration // Longest a whole run may take (0 for no limit)
+	fs                  FileSystem    // Storage the directory is scanned and renamed on
+	renamer             Renamer       // Performs renames instead of the file system (nil to rename on it)
+	extractArchives     bool          // Whether subtitles inside archives are matched and extracted
+	sniffContent        bool          // Whether files are recognized as subtitles by content
+	preset              TitlePreset   // Platform naming noise removed from titles before comparison
+	outputTemplate      *Template     // yt-dlp output template names are parsed with (nil to disable)
+	stripSymbols        bool          // Whether emoji and other symbols are removed from titles
+	videoIDs            *titleCache   // Video IDs found in video names
+	metadataTitles      *titleCache   // Title tags probed from videos (nil to match by file name only)
+	nfoTitles           *titleCache   // Titles read from Kodi .nfo sidecars (nil to ignore them)
+	nfoNaming           *Template     // Template subtitles of videos with a Kodi .nfo are named by (nil to name them after the video)
+	embeddedTracks      *trackCache   // Text subtitle tracks read from videos (nil to not verify matches against them)
+	embedder            Embedder      // Embeds titles for the semantic pass (nil to disable it)
+	semanticThreshold   float64       // Cosine similarity a video's title needs in the semantic pass
+	titleVectors        titleVectors  // Embeddings of the titles of the run, by semantic title
+	preferQuality       bool          // Whether only the best of several subtitles for one name is renamed
+	multiMatch          MultiMatch    // What happens when several subtitles are planned for one name
+	chooseMatch         ChooseFunc    // Picks the subtitle kept under MultiMatchPrompt (nil to hold them all)
+	cleanup             CleanupMode   // Where subtitles left alone as duplicates are moved
+	cleanupFolder       string        // Folder CleanupMove moves duplicates into
+	languagePriority    []string      // Languages in the order they claim a video's canonical subtitle name
+	resyncWindow        time.Duration // Largest timing offset searched when resyncing (0 to disable)
+	normalizedVideos    *titleCache   // Normalized video titles, computed once per scan
+	strippedVideos      *titleCache   // Normalized video titles without multi-part markers
+	indexVideos         bool          // Whether subtitles are only scored against videos found in a trigram index
+	wantedLanguages     []string      // Languages every video should have a subtitle in, reported by Check
+	videoIndex          *trigramIndex // Trigram index of the last scan's videos (nil when not indexing)
 }
 
 // Option defines a functional option for configuring VideoSubtitleMatcher.
@@ -40,7 +138,7 @@ func VideoExtensions(extensions []string) Option {
 }
 
 // SubtitleExtensions sets custom subtitle file extensions.
-// Default: [".srt", ".ass", ".vtt"]
+// Default: [".srt", ".ass", ".ssa", ".vtt", ".sbv", ".smi", ".sup"]
 func SubtitleExtensions(extensions []string) Option {
 	return func(vsm *VideoSubtitleMatcher) {
 		vsm.subtitleExtensions = extensions
@@ -83,11 +181,1019 @@ func Verbose(verbose bool) Option {
 	}
 }
 
+// Color enables coloring verbose output and the status column of tables:
+// green for renamed subtitles, yellow for renames a dry run would perform,
+// red for errors and gray for skipped subtitles. Only enable it when writing
+// to a terminal, and not when the NO_COLOR environment variable is set.
+// Default: false
+func Color(enabled bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.color = enabled
+	}
+}
+
 // IgnoreExisting sets whether to ignore already correctly named files.
 // Default: false
-func IgnoreExisting(ignore bool) Option {
+func IgnoreExisting(ignore bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.ignoreExisting = ignore
+	}
+}
+
+// SkipIncomplete sets whether videos that are still being downloaded are
+// ignored: empty files, and files with a download client's partial marker
+// next to them, such as "Movie.mkv.part", "Movie.mkv.!qB",
+// "Movie.mkv.crdownload" or "Movie.mkv.aria2". Subtitles are not matched
+// against half-downloaded videos and renamed prematurely.
+// Default: false
+func SkipIncomplete(skip bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.skipIncomplete = skip
+	}
+}
+
+// SettleTime ignores videos modified more recently than d, for download
+// clients that write files in place without a partial marker.
+// Default: 0 (disabled)
+func SettleTime(d time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if d >= 0 {
+			vsm.settleTime = d
+		}
+	}
+}
+
+// ExcludeSamples sets whether sample clips and extras are left out of the
+// videos subtitles are matched against: videos named as samples
+// ("sample.mkv", "Movie-sample.mkv"), videos in extras folders below the
+// directory (see ExtrasFolders), and videos under the sample size next to a
+// video at least twenty times larger.
+// Default: true
+func ExcludeSamples(exclude bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.excludeSamples = exclude
+	}
+}
+
+// SampleSize sets the size in bytes under which a video next to a much
+// larger one is taken for a sample of it. 0 disables detecting samples by
+// size.
+// Default: 100 MiB
+func SampleSize(bytes int64) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if bytes >= 0 {
+			vsm.sampleSize = bytes
+		}
+	}
+}
+
+// ExtrasFolders sets the names of the folders, compared case-insensitively,
+// whose videos are samples or extras rather than features.
+// Default: Sample, Samples, Extras, Featurettes, Behind The Scenes, Deleted
+// Scenes, Interviews, Scenes, Shorts, Trailers and Other
+func ExtrasFolders(names []string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.extrasFolders = names
+	}
+}
+
+// MinVideoSize ignores videos smaller than bytes, so that trailers and
+// clips never win a match over the feature.
+// Default: 0 (disabled)
+func MinVideoSize(bytes int64) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if bytes >= 0 {
+			vsm.minVideoSize = bytes
+		}
+	}
+}
+
+// MinVideoDuration ignores videos shorter than d. Durations are read with
+// ffprobe, so only videos on the local file system are checked, and videos
+// ffprobe cannot read are kept.
+// Default: 0 (disabled)
+func MinVideoDuration(d time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if d >= 0 {
+			vsm.minVideoDuration = d
+		}
+	}
+}
+
+// TrackProcessed sets whether runs keep track of the subtitles they have
+// finished with. Runs that rename files record each subtitle they renamed or
+// found correctly named, with its size and modification time, in a
+// .subtitle-matcher.processed file in the directory; with ProcessedSkip,
+// later runs skip those still unchanged. Only local directories are tracked.
+// Default: ProcessedOff
+func TrackProcessed(mode ProcessedMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.processedMode = mode
+	}
+}
+
+// SDH sets how subtitles for the deaf and hard of hearing are treated.
+// See SDHMode for the available behaviors.
+// Default: SDHIgnore
+func SDH(mode SDHMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.sdhMode = mode
+	}
+}
+
+// Preview sets how many lines of subtitle text are shown for each match in
+// verbose output, to help confirm language and content. Zero disables the preview.
+// Default: 0
+func Preview(lines int) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if lines >= 0 {
+			vsm.previewLines = lines
+		}
+	}
+}
+
+// Validate enables or disables SRT structure validation.
+// When enabled, .srt files are checked for broken sequence numbers, timestamps
+// and overlapping cues; files with fatal problems are flagged Invalid and not renamed.
+// Default: false
+func Validate(validate bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.validate = validate
+	}
+}
+
+// Repair enables or disables the SRT repair pass.
+// When enabled, renamed .srt files have their cues renumbered, malformed
+// timestamps rewritten and zero-length cues removed. Nothing is written in dry run mode.
+// Default: false
+func Repair(repair bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.repair = repair
+	}
+}
+
+// MergeBilingual enables merging two matched .srt subtitles of different
+// languages into a single bilingual subtitle named after the video, e.g.
+// "Movie.zh-en.srt". Languages are ISO 639-1 codes detected from the
+// subtitle filenames; top is shown above bottom in every cue. An existing
+// file of that name, or the new name of another subtitle, is never overwritten.
+// Default: disabled
+func MergeBilingual(top, bottom string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.mergeTop = strings.ToLower(top)
+		vsm.mergeBottom = strings.ToLower(bottom)
+	}
+}
+
+// JoinParts enables or disables joining multi-part .srt subtitles, such as
+// "Movie.part1.srt" and "Movie.part2.srt", into a single subtitle named after
+// a video that is one file, e.g. "Movie.srt". Each part is shifted by the
+// probed duration of the video parts before it, found by the names of the
+// subtitle parts, e.g. "Movie.part1.avi", or else to start where the previous
+// part's last cue ends. Every part from 1 on must be
+// matched to the video. The parts are still renamed with their ".cd1" style
+// suffix, and an existing subtitle of the video, or one another subtitle is
+// renamed to, is never overwritten.
+// Subtitles of single episodes matched to a multi-episode video, such as
+// "Show S01E01.srt" and "Show S01E02.srt" for "Show S01E01E02.mkv", are
+// joined the same way once every episode has one.
+// Default: false
+func JoinParts(join bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.joinParts = join
+	}
+}
+
+// SplitBilingual enables or disables splitting bilingual .srt subtitles, such as
+// YouTube "dual" subtitles with Chinese and English on alternating lines, into
+// per-language files named after the video, e.g. "Movie.zh.srt" and "Movie.en.srt".
+// The original subtitle is still renamed, and a language tag it is renamed with
+// is replaced. Existing files and the names of other matched subtitles are never
+// overwritten; the subtitle reports them in its error.
+// Default: false
+func SplitBilingual(split bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.splitBilingual = split
+	}
+}
+
+// OCR reads matched image-based subtitles, PGS (.sup) from Blu-ray and
+// VobSub (.idx/.sub) from DVD, into text .srt subtitles named like them,
+// using Tesseract. The image subtitle is kept. language is the Tesseract
+// language ("eng", "chi_sim", ...) used unless the subtitle's name, or its
+// VobSub index, tells its language; "" disables OCR. Existing files and the
+// names of other matched subtitles are never overwritten. Tesseract must be
+// installed along with the language data needed.
+// Default: "" (disabled)
+func OCR(language string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.ocrLanguage = language
+	}
+}
+
+// GenerateSubtitles sets a Transcriber, such as NewWhisperCppTranscriber or
+// NewWhisperAPITranscriber, that generates a subtitle for each video no
+// subtitle matches or is already named after. Generated subtitles are named
+// like the video with the spoken language, e.g. "Movie.en.srt", and reported
+// as results with Generated set. Transcribing needs the videos on the local
+// file system and takes a while per video; dry runs only list the videos.
+// Default: nil (disabled)
+func GenerateSubtitles(t Transcriber) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.transcriber = t
+	}
+}
+
+// SearchSubtitles sets SubtitleProviders, such as NewOpenSubtitlesProvider,
+// searched for each video no subtitle matches or is already named after.
+// They are searched by the title, and episode or year, parsed from the
+// video's name, for subtitles in languages (ISO 639-1 codes, best first;
+// none for any). Each such video is reported as a result with Fetched set
+// and up to five subtitles found, best first, in Remote; with
+// DownloadSubtitles the best one is also downloaded. Providers are searched
+// in dry runs too. Searching runs before GenerateSubtitles, which only
+// transcribes the videos nothing is downloaded for.
+// Default: none (disabled)
+func SearchSubtitles(languages []string, providers ...SubtitleProvider) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.providers = providers
+		vsm.searchLanguages = nil
+		for _, language := range languages {
+			if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+				vsm.searchLanguages = append(vsm.searchLanguages, language)
+			}
+		}
+	}
+}
+
+// DownloadSubtitles enables downloading the best subtitle SearchSubtitles
+// finds for a video without one, named like the video with its language,
+// e.g. "Movie.en.srt". An existing file is never overwritten. Dry runs only
+// list what would be downloaded.
+// Default: false
+func DownloadSubtitles(download bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.downloadSubtitles = download
+	}
+}
+
+// Translate sets a Translator, such as NewLibreTranslateTranslator or
+// NewDeepLTranslator, that writes a machine-translated copy of each matched
+// .srt subtitle into language, an ISO 639-1 code such as "zh". The copy is
+// named like the video with the language, e.g. "Movie.zh.srt", and reported
+// as the result's TranslationPath. Videos that already have a subtitle in
+// that language are skipped, and each video gets at most one translation.
+// A nil translator or empty language disables translation.
+// Default: nil (disabled)
+func Translate(t Translator, language string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if t == nil || language == "" {
+			vsm.translator, vsm.translateTo = nil, ""
+			return
+		}
+		vsm.translator, vsm.translateTo = t, strings.ToLower(language)
+	}
+}
+
+// ConvertVTT enables or disables converting matched .vtt subtitles to .srt.
+// The WEBVTT header, NOTE/STYLE/REGION blocks, cue settings and voice/class
+// tags are stripped rather than copied, and the original .vtt file is removed.
+// Default: false
+func ConvertVTT(convert bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.convertVTT = convert
+	}
+}
+
+// StripMarkup enables or disables removing inline markup from cue text.
+// HTML tags such as <i> and <font> and ASS override tags such as {\an8} are
+// removed from renamed .srt files; override tags are removed from .ass/.ssa
+// dialogue. Nothing is written in dry run mode.
+// Default: false
+func StripMarkup(strip bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.stripMarkup = strip
+	}
+}
+
+// LineEndings sets the line endings renamed text subtitles are rewritten
+// with, e.g. LineEndingCRLF for players that need Windows line endings.
+// Subtitles that are not valid UTF-8 are left untouched.
+// Default: LineEndingUnchanged
+func LineEndings(ending LineEnding) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.lineEnding = ending
+	}
+}
+
+// ByteOrderMark sets whether renamed text subtitles get or lose a UTF-8 byte
+// order mark. Stray marks break some hardware players; others need one to
+// recognize UTF-8. Subtitles that are not valid UTF-8 are left untouched.
+// Default: BOMUnchanged
+func ByteOrderMark(mode BOMMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.bomMode = mode
+	}
+}
+
+// ConvertMicroDVD enables or disables converting matched frame-based MicroDVD
+// (.sub) subtitles to .srt. Frames are converted to timestamps using the
+// video's frame rate as reported by ffprobe, falling back to a frame rate
+// declared in the subtitle or 23.976. Include ".sub" in SubtitleExtensions
+// for these files to be scanned.
+// Default: false
+func ConvertMicroDVD(convert bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.convertMicroDVD = convert
+	}
+}
+
+// SubtitleFrameRate declares the frame rate that .srt subtitles were timed for.
+// When the matched video's frame rate (probed with ffprobe) differs, e.g. a
+// 25 fps subtitle for a 23.976 fps video, the renamed subtitle is retimed to fit.
+// Zero disables retiming.
+// Default: 0
+func SubtitleFrameRate(fps float64) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if fps >= 0 {
+			vsm.subtitleFrameRate = fps
+		}
+	}
+}
+
+// DiffOutput sets a writer that receives the dry run plan as a diff grouped by
+// directory ("- old name" / "+ new name") instead of per-match progress text.
+// It has no effect outside dry run mode. See WriteDiff for the format.
+// Default: nil (disabled)
+func DiffOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.diffOutput = w
+	}
+}
+
+// ScriptOutput sets a writer that receives the dry run plan as an executable
+// script of rename commands for shell (ScriptBash or ScriptPowerShell) instead
+// of per-match progress text, so it can be reviewed and run separately. It
+// has no effect outside dry run mode. See WriteScript for the format.
+// Default: nil (disabled)
+func ScriptOutput(w io.Writer, shell ScriptShell) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.scriptOutput = w
+		vsm.scriptShell = shell
+	}
+}
+
+// TableOutput sets a writer that receives the results of each run as tables
+// grouped by directory, with columns aligned even for names mixing CJK and
+// ASCII characters. The tables replace the verbose output, in dry runs and
+// when renaming. See WriteTable for the format.
+// Default: nil (disabled)
+func TableOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.tableOutput = w
+	}
+}
+
+// VideoGroupOutput sets a writer that receives the results of each run as
+// JSON grouped by video, each video listing its subtitles with their
+// languages and actions. See GroupByVideo for the grouping.
+// Default: nil (disabled)
+func VideoGroupOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.groupOutput = w
+	}
+}
+
+// PlanOutput sets a writer that receives the dry run plan as a JSON plan
+// instead of per-match progress text. The plan can be reviewed, edited and
+// then executed exactly with Apply. It has no effect outside dry run mode.
+// See Plan for the format.
+// Default: nil (disabled)
+func PlanOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.planOutput = w
+	}
+}
+
+// SureThreshold splits matches into two bands: those scoring at least sure
+// are renamed automatically, while those between SimilarityThreshold and sure
+// are held. Each held match is passed to review, which may approve it for
+// renaming, e.g. after asking the user; with a nil review all of them stay
+// held. Held matches are reported with Held set and can be confirmed later
+// through the plan written to HeldPlanOutput. Matches found by name or video
+// ID score 1 and are never held, and neither are mapped subtitles.
+// Default: 0 (every match is renamed)
+func SureThreshold(sure float64, review ReviewFunc) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if sure >= 0 && sure <= 1 {
+			vsm.sureThreshold = sure
+			vsm.reviewMatch = review
+		}
+	}
+}
+
+// HeldPlanOutput sets a writer that receives the matches held by
+// SureThreshold or MultiMatchPolicy as a JSON plan, to be reviewed, edited and confirmed by
+// executing it with Apply.
+// Default: nil (disabled)
+func HeldPlanOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.heldOutput = w
+	}
+}
+
+// ChangesOutput sets a writer that receives, at the end of each run, the
+// subtitles that are new, newly matched or newly broken since the previous
+// run recorded in the History for the same directory, e.g. so that a nightly
+// job only reports the delta. See WriteChanges for the format. It has no
+// effect without a History.
+// Default: nil (disabled)
+func ChangesOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.changesOutput = w
+	}
+}
+
+// NDJSONOutput sets a writer that receives each result as a single line of
+// JSON as soon as it is decided, so downstream tools can react in real time.
+// Errors are encoded as strings in the "error" field.
+// Default: nil (disabled)
+func NDJSONOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.ndjsonOutput = w
+	}
+}
+
+// DebugOutput sets a writer that receives a structured JSON trace of each
+// run, for attaching to bug reports: the scanned videos and subtitles with
+// their normalized titles, the score of every subtitle and video pair above
+// 0.2, and what was decided for each subtitle. It is written once matches are
+// planned, before anything is renamed.
+// Default: nil (disabled)
+func DebugOutput(w io.Writer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.debugOutput = w
+	}
+}
+
+// Explain enables or disables attaching an Explanation to each result, with
+// the normalized titles, algorithm, per-token contributions and the
+// second-best candidate. Useful for debugging why a match scored as it did.
+// Default: false
+func Explain(explain bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.explain = explain
+	}
+}
+
+// MappingFile sets a file of manual overrides for subtitles that automatic
+// matching gets wrong. Each line has the form "subtitle -> video" or
+// "subtitle -> target name", using file names or paths relative to the
+// directory; lines starting with '#' are comments. Mapped subtitles are
+// flagged Mapped in the results.
+// Default: "" (disabled)
+func MappingFile(path string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.mappingFile = path
+	}
+}
+
+// Candidates sets how many of the best scoring videos are listed in each
+// result's Candidates, best first, so that applications can offer alternatives
+// to the chosen match. Zero disables the list.
+// Default: 0
+func Candidates(n int) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if n >= 0 {
+			vsm.candidateCount = n
+		}
+	}
+}
+
+// Locality sets whether videos in the subtitle's own directory are favored,
+// so that a subtitle in one season folder never matches a video two shows away.
+// See LocalityMode for the available behaviors.
+// Default: LocalityAny
+func Locality(mode LocalityMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.locality = mode
+	}
+}
+
+// Mode selects the heuristics used to tell videos apart: episode and season
+// numbers or air dates for TV shows, year and title for movies. See
+// MediaMode for the available modes.
+// Default: ModeAuto
+func Mode(mode MediaMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.mode = mode
+	}
+}
+
+// EpisodeMap sets a file translating absolute episode numbers, as used by
+// anime releases ("Show - 134"), into seasons and episodes ("Show S06E14"),
+// so that subtitles and videos numbered either way match. Each line maps a
+// range of absolute numbers to a season, optionally with the seasonal
+// number of its first episode:
+//
+//	1-24 -> S01
+//	25-48 -> S02
+//	49 -> S00E01
+//
+// Default: "" (disabled)
+func EpisodeMap(path string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.episodeMapFile = path
+	}
+}
+
+// DirectoryWeight includes up to two parent directory names (e.g. show and
+// season) below the scanned directory in comparisons, with each of their
+// characters weighing the given fraction of a filename character. This lets
+// "Show/Season 01/E01.mkv" match "Show S01E01.srt". Values range from 0.0
+// (directories ignored) to 1.0 (directories weigh as much as filenames).
+// Default: 0
+func DirectoryWeight(weight float64) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if weight >= 0.0 && weight <= 1.0 {
+			vsm.directoryWeight = weight
+		}
+	}
+}
+
+// FormatPreference sets the order in which subtitle formats claim a video's
+// canonical subtitle name when several subtitles match the same video, e.g.
+// FormatPreference(".ass", ".srt", ".vtt"). The remaining subtitles are renamed
+// with an ".alt" tag instead. Unlisted formats rank last.
+// Default: none (subtitles matching the same video are renamed in scan order)
+func FormatPreference(extensions ...string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.formatPreference = make([]string, 0, len(extensions))
+		for _, ext := range extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			vsm.formatPreference = append(vsm.formatPreference, ext)
+		}
+	}
+}
+
+// Strict makes execution fail instead of guessing. When any planned rename is
+// nearly tied with another video or targets an existing or shared name, for
+// example because several subtitles of one video resolve to the same name,
+// MatchContext renames nothing and returns an *AmbiguityError describing
+// every problem. Dry runs are not affected.
+// Default: false
+func Strict(enabled bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.strict = enabled
+	}
+}
+
+// Preflight sets what happens when the plan of a run that renames files is
+// checked before execution and found to have problems: targets that would
+// replace existing files or are shared by several subtitles, and, on local
+// disk, directories that are read-only, not writable or on different devices
+// than the videos' directories. PreflightWarn reports the problems and
+// renames anyway, PreflightAbort renames nothing and makes MatchContext
+// return a *PreflightError describing every problem.
+// Default: PreflightWarn
+func Preflight(mode PreflightMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.preflight = mode
+	}
+}
+
+// MaxOperations caps the number of files a run may rename, as a guard
+// against a mistyped directory queueing thousands of renames. When the plan
+// exceeds limit, confirm is called with the number of planned renames and the
+// run proceeds only if it returns true; with a nil confirm, or when it returns
+// false, MatchContext renames nothing and returns an *OperationLimitError.
+// Dry runs are not affected. Zero disables the limit.
+// Default: 0
+func MaxOperations(limit int, confirm ConfirmFunc) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if limit >= 0 {
+			vsm.maxOperations = limit
+			vsm.confirmOperations = confirm
+		}
+	}
+}
+
+// AllowUnsafeRoot lets runs rename files when the directory is a file system
+// root such as / or C:\, the user's home directory or the directory holding the
+// home directories. Such runs are refused with an *UnsafeRootError by
+// default, since they usually come from a script passing an empty variable.
+// Default: false
+func AllowUnsafeRoot(allow bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.allowUnsafeRoot = allow
+	}
+}
+
+// ProtectedPaths refuses runs renaming files in any of the given directories
+// or below them, and recursive runs whose directory contains one of them,
+// with an *UnsafeRootError. AllowUnsafeRoot does not lift this check.
+// Default: none
+func ProtectedPaths(paths ...string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.protectedPaths = append([]string(nil), paths...)
+	}
+}
+
+// Recovery sets how renames left unfinished by an interrupted run are resolved.
+// Every run that moves files on local disk first writes its planned renames
+// to a journal in the directory; if the journal is still present at the start
+// of the next run, its unfinished renames are completed or rolled back before
+// matching. Runs using a Renamer are not journaled, since recovery would move
+// files the Renamer may only have copied, linked or recorded.
+// Default: RecoveryComplete
+func Recovery(mode RecoveryMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.recovery = mode
+	}
+}
+
+// Timestamps sets the modification time of renamed subtitles, e.g.
+// TimestampsVideo to copy each video's time to its subtitle so that tools
+// ordering files by modification time are not disturbed.
+// Default: TimestampsUnchanged
+func Timestamps(mode TimestampMode) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.timestamps = mode
+	}
+}
+
+// History records every run, including its settings and results, in store so
+// past operations can be audited or undone later. Dry runs are recorded too.
+// Default: nil (runs are not recorded)
+func History(store HistoryStore) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.history = store
+	}
+}
+
+// Notify sends a summary of every finished run that matched at least one
+// subtitle to the given notifiers, e.g. a webhook, an ntfy topic or a
+// Telegram chat. Runs that match nothing send no notification.
+// Default: none
+func Notify(notifiers ...Notifier) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.notifiers = append(vsm.notifiers, notifiers...)
+	}
+}
+
+// CollectMetrics counts scanned files, matches, renames and failures, and
+// times each match, in m. Serve m over HTTP to expose them to Prometheus.
+// Default: nil (no metrics)
+func CollectMetrics(m *Metrics) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.metrics = m
+	}
+}
+
+// Retry retries renames and conversions that fail with transient errors,
+// such as a busy file or a stale NFS handle, up to attempts times in total.
+// The first retry waits backoff and each further one waits twice as long.
+// Default: 1 attempt (no retries), 500ms backoff
+func Retry(attempts int, backoff time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if attempts >= 1 {
+			vsm.retryAttempts = attempts
+		}
+		if backoff > 0 {
+			vsm.retryBackoff = backoff
+		}
+	}
+}
+
+// OperationTimeout bounds each rename, conversion or extraction, so that a
+// file operation stuck on an unresponsive network file system fails with
+// ErrOperationTimeout instead of hanging the run. Commands the operation runs,
+// such as rclone or unrar, are killed; a stuck file system call cannot be
+// interrupted and may still complete later. Zero disables the limit.
+// Default: 0
+func OperationTimeout(timeout time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if timeout >= 0 {
+			vsm.operationTimeout = timeout
+		}
+	}
+}
+
+// RunTimeout bounds a whole run, on top of any deadline of the context it is
+// given: once it expires, the run stops as if its context was cancelled and
+// returns context.DeadlineExceeded. Combine it with OperationTimeout so that a
+// single stuck operation cannot outlast it. Zero disables the limit.
+// Default: 0
+func RunTimeout(timeout time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if timeout >= 0 {
+			vsm.runTimeout = timeout
+		}
+	}
+}
+
+// UseFileSystem makes the matcher scan and rename files on fsys instead of
+// local disk, e.g. a WebDAV share. The directory passed to New is then a path
+// on fsys. The run lock, the rename journal and frame rate probing only work
+// on local disk and are skipped on other file systems.
+// Default: LocalFileSystem
+func UseFileSystem(fsys FileSystem) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if fsys != nil {
+			vsm.fs = fsys
+		}
+	}
+}
+
+// UseRenamer makes the matcher put subtitles in place with r instead of
+// moving them on the file system, e.g. a RecordingRenamer to only collect the
+// renames, a CopyRenamer or LinkRenamer to keep the originals, or a caller's
+// own remote or transactional backend. See Renamer for the operations that
+// still go through the file system. Runs using a Renamer are not journaled
+// for crash recovery.
+// Default: none (subtitles are moved on the file system)
+func UseRenamer(r Renamer) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.renamer = r
+	}
+}
+
+// ExtractArchives enables matching subtitles inside .zip and .rar archives,
+// as commonly downloaded from subtitle sites. A matched subtitle is extracted
+// next to its video under the new name and the archive is left untouched.
+// RAR archives need unrar and only work on local disk.
+// Default: false
+func ExtractArchives(extract bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.extractArchives = extract
+	}
+}
+
+// SniffContent enables recognizing subtitles by their content, so files with
+// a wrong or missing extension such as "subtitle.txt" or "captions" are
+// matched too. SRT, WebVTT, ASS/SSA, SAMI, SubViewer and MicroDVD files are
+// recognized, and the correct extension is given to them when they are
+// renamed. Every file that is neither a video nor a known subtitle is read,
+// so scans are slower.
+// Default: false
+func SniffContent(sniff bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.sniffContent = sniff
+	}
+}
+
+// MetadataTitles enables matching subtitles against the title tag stored in
+// each video container, read with ffprobe, in addition to the file name. This
+// helps when file names are meaningless (e.g. "output.mkv" from a screen
+// recorder) but the metadata is good. Only works on local disk.
+// Default: false
+func MetadataTitles(enabled bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.metadataTitles = nil
+		if enabled {
+			vsm.metadataTitles = &titleCache{}
+		}
+	}
+}
+
+// ReadNFO enables matching subtitles against the title in each video's Kodi
+// .nfo sidecar ("<video name>.nfo", or "movie.nfo" in a movie's folder) in
+// addition to the file name. Episodes are matched as "Show S01E02 Title" and
+// movies as "Title Year". Renamed subtitles still take the video's file name,
+// which is what Kodi looks for, unless NFONaming is set.
+// Default: false
+func ReadNFO(enabled bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.nfoTitles = nil
+		if enabled {
+			vsm.nfoTitles = &titleCache{}
+		}
+	}
+}
+
+// NFONaming names the subtitles of videos with a Kodi .nfo sidecar by
+// filling template, as returned by ParseOutputTemplate, with the .nfo's
+// fields instead of after the video file, e.g.
+// "%(showtitle)s S%(season)02dE%(episode)02d %(title)s" or
+// "%(title)s (%(year)d)". The fields are title, showtitle, year, season and
+// episode; those the .nfo does not give are left empty. Part, episode,
+// language and SDH tags are added as usual. Kodi looks subtitles up by the
+// video's file name, so this suits libraries whose videos are named the same
+// way. Translations keep the video's file name.
+// Default: none (subtitles are named after the video file)
+func NFONaming(template *Template) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.nfoNaming = template
+	}
+}
+
+// VerifyEmbedded enables checking matches against the text subtitle tracks
+// embedded in the videos, read with ffmpeg. The first lines of a matched
+// subtitle are compared with those of the video's tracks in its language: a
+// subtitle sharing its lines is a High confidence match, while one sharing
+// almost none belongs to another video and is rejected (Rejected set, not
+// renamed). Subtitles of unknown language are only ever confirmed. Videos
+// without text tracks and manual mappings are left as they are. Only works
+// on local disk.
+// Default: false
+func VerifyEmbedded(enabled bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.embeddedTracks = nil
+		if enabled {
+			vsm.embeddedTracks = &trackCache{}
+		}
+	}
+}
+
+// SemanticMatching sets an Embedder, such as NewOpenAIEmbedder, for a
+// semantic pass that matches subtitles by the meaning of their titles, e.g.
+// "千与千寻" to "Spirited Away (2001).mkv". It only runs for subtitles that
+// scored below the similarity threshold, comparing the embeddings of the
+// titles without their episode markers, years and release details. The
+// closest video matches when its cosine similarity reaches threshold and
+// clearly leads the runner-up; episode numbers and years must still agree.
+// The similarity threshold applies to the cosine similarity as to any other
+// score. A threshold outside (0, 1] uses 0.8, which suits most multilingual
+// models.
+// Default: nil (disabled)
+func SemanticMatching(e Embedder, threshold float64) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.embedder = e
+		vsm.semanticThreshold = semanticDefaultThreshold
+		if threshold > 0 && threshold <= 1 {
+			vsm.semanticThreshold = threshold
+		}
+	}
+}
+
+// LanguagePriority sets the order in which languages claim a video's
+// canonical subtitle name when subtitles of several languages match the same
+// video, e.g. LanguagePriority([]string{"en", "zh"}). The subtitles of other
+// languages are renamed with their language tag instead ("Movie.zh.srt"),
+// and subtitles of no detected language are skipped, reporting the winner in
+// DuplicateOf. Languages are ISO 639-1 codes or any tag detected in file
+// names ("eng", "chs"). Applied before PreferQuality and FormatPreference,
+// which then choose among the subtitles of the winning language.
+// Default: none (subtitles matching the same video are renamed in scan order)
+func LanguagePriority(languages []string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.languagePriority = make([]string, 0, len(languages))
+		for _, language := range languages {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if code, ok := languageCodes[language]; ok {
+				language = code
+			}
+			vsm.languagePriority = append(vsm.languagePriority, language)
+		}
+	}
+}
+
+// MultiMatchPolicy sets what happens when several subtitles match the same
+// video and are planned for the same name: MultiMatchKeepAll renames them all
+// with ".alt" tags, MultiMatchKeepBest renames only the best one and
+// MultiMatchPrompt renames the one choose picks. Under MultiMatchPrompt, with
+// a nil choose, or in dry runs, every candidate is held and can be confirmed
+// through the plan written to HeldPlanOutput. The subtitles left alone report
+// the kept one in DuplicateOf.
+// Default: MultiMatchReplace (each subtitle replaces the previous, in scan order)
+func MultiMatchPolicy(policy MultiMatch, choose ChooseFunc) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if policy >= MultiMatchReplace && policy <= MultiMatchPrompt {
+			vsm.multiMatch = policy
+			vsm.chooseMatch = choose
+		}
+	}
+}
+
+// CleanupDuplicates moves the subtitles left alone as duplicates of a better
+// one (see PreferQuality, MultiMatchPolicy and LanguagePriority) out of the
+// library, so that each video ends up with one subtitle per language:
+// CleanupMove moves them into folder, keeping their path relative to the
+// directory, and CleanupTrash moves them to the user's trash. A relative
+// folder is resolved against the directory, and subtitles in it are never
+// matched. Only done on local disk, and not in dry runs.
+// Default: CleanupOff (duplicates stay where they are)
+func CleanupDuplicates(mode CleanupMode, folder string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if mode < CleanupOff || mode > CleanupTrash {
+			return
+		}
+		vsm.cleanup = mode
+		vsm.cleanupFolder = folder
+		if vsm.cleanupFolder == "" {
+			vsm.cleanupFolder = defaultCleanupFolder
+		}
+	}
+}
+
+// PreferQuality makes the best subtitle win when several are planned for the
+// same name, instead of renaming them all. Subtitles are scored by cue count,
+// valid UTF-8, absence of ads and format richness (ASS over SRT); the others
+// are left alone and report the winner in DuplicateOf. Applied before
+// FormatPreference, which then has nothing left to tag.
+// Default: false
+func PreferQuality(prefer bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.preferQuality = prefer
+	}
+}
+
+// Resync enables shifting renamed .srt subtitles into sync with their video.
+// The video's soundtrack is decoded with ffmpeg and the constant offset
+// within ±maxOffset that best lines cues up with dialogue is applied, in
+// steps of 100ms. Runs after retiming, so frame rate drift is fixed first.
+// Only works on local disk. Zero disables resyncing.
+// Default: 0
+func Resync(maxOffset time.Duration) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if maxOffset >= 0 {
+			vsm.resyncWindow = maxOffset
+		}
+	}
+}
+
+// Files makes runs match the given files instead of scanning the directory,
+// e.g. a list filtered with find and read with ReadFileList. Files that are
+// neither videos nor subtitles are ignored. When the list contains no
+// videos, subtitles are matched against the videos found in the directory.
+// Default: nil (scan the directory)
+func Files(paths []string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.fileList = paths
+	}
+}
+
+// TrigramIndex enables indexing video titles by trigram (three-character
+// sequence) on every scan and scoring each subtitle only against the videos
+// sharing at least a quarter of its trigrams, instead of against every video.
+// This makes large libraries much faster to match. Videos sharing little of
+// a subtitle's title are then not listed in Candidates or scored as
+// runner-up, and subtitles sharing too little with every video are scored
+// against all of them as usual.
+// Default: false
+func TrigramIndex(enabled bool) Option {
 	return func(vsm *VideoSubtitleMatcher) {
-		vsm.ignoreExisting = ignore
+		vsm.indexVideos = enabled
+	}
+}
+
+// WantedLanguages sets the languages every video should have a subtitle in,
+// e.g. "zh" and "en". Check reports the videos missing any of them. Codes
+// and names recognized in subtitle filenames ("eng", "chinese") are accepted.
+// Default: none
+func WantedLanguages(languages ...string) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.wantedLanguages = nil
+		for _, language := range languages {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if code, ok := languageCodes[language]; ok {
+				language = code
+			}
+			if language != "" {
+				vsm.wantedLanguages = append(vsm.wantedLanguages, language)
+			}
+		}
+	}
+}
+
+// Preset selects the platform-specific naming noise removed from titles
+// before they are compared, e.g. Preset("scene") for release names such as
+// "Show.S01E01.1080p.WEB-DL.x264-GROUP". See TitlePreset for the
+// available presets; ParsePreset accepts their names. Unknown presets are
+// ignored. The YouTube rules remove any bracketed word, so they are only
+// applied when asked for with Preset(PresetYouTube).
+// Default: PresetPlain
+func Preset(preset TitlePreset) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		if _, ok := presetRules[preset]; ok {
+			vsm.preset = preset
+		}
+	}
+}
+
+// StripSymbols removes emoji and other symbols, such as ★, ♪ and ™, from
+// titles before they are compared, so that a video titled
+// "🔥 Best Moments 🔥" matches the subtitle "Best Moments". Punctuation is
+// kept.
+// Default: false
+func StripSymbols(strip bool) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.stripSymbols = strip
+	}
+}
+
+// OutputTemplate parses the names of videos and subtitles with the yt-dlp
+// output template they were downloaded with, as returned by
+// ParseOutputTemplate. Names that fit it are compared by their title field
+// alone, without the preset's rules, and their id field is used as their
+// video ID. Names that do not fit the template are compared as usual.
+// Default: none
+func OutputTemplate(template *Template) Option {
+	return func(vsm *VideoSubtitleMatcher) {
+		vsm.outputTemplate = template
 	}
 }
 
@@ -107,13 +1213,24 @@ func New(directory string, options ...Option) *VideoSubtitleMatcher {
 	// Initialize with sensible defaults
 	vsm := &VideoSubtitleMatcher{
 		videoExtensions:     []string{".mkv", ".mp4", ".avi", ".mov", ".webm"},
-		subtitleExtensions:  []string{".srt", ".ass", ".vtt"},
+		subtitleExtensions:  []string{".srt", ".ass", ".ssa", ".vtt", ".sbv", ".smi", ".sup"},
 		directory:           directory,
 		similarityThreshold: 0.6,
 		recursive:           true,
 		dryRun:              true,
 		verbose:             true,
 		ignoreExisting:      false,
+		sdhMode:             SDHIgnore,
+		preset:              PresetPlain,
+		retryAttempts:       1,
+		retryBackoff:        500 * time.Millisecond,
+		excludeSamples:      true,
+		sampleSize:          defaultSampleSize,
+		extrasFolders:       defaultExtrasFolders,
+		fs:                  LocalFileSystem{},
+		normalizedVideos:    &titleCache{},
+		strippedVideos:      &titleCache{},
+		videoIDs:            &titleCache{},
 	}
 
 	// Apply functional options
@@ -121,209 +1238,693 @@ func New(directory string, options ...Option) *VideoSubtitleMatcher {
 		option(vsm)
 	}
 
+	if vsm.extractArchives {
+		vsm.fs = newArchiveFileSystem(vsm.fs)
+	}
+
+	if vsm.tableOutput != nil {
+		vsm.verbose = false
+	}
+
 	return vsm
 }
 
 // scanFiles scans the configured directory and returns lists of video and subtitle files.
 // The scanning behavior (recursive vs non-recursive) is controlled by the recursive option.
-func (vsm *VideoSubtitleMatcher) scanFiles() ([]string, []string, error) {
-	var videoFiles, subtitleFiles []string
+func (vsm *VideoSubtitleMatcher) scanFiles(ctx context.Context) ([]string, []string, error) {
+	var videoFiles, subtitleFiles, archives []string
+	partials := make(map[string]bool)
+
+	if vsm.episodeMapFile != "" {
+		episodes, err := loadEpisodeMap(vsm.episodeMapFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load episode map: %w", err)
+		}
+		vsm.episodeMap = episodes
+	}
+
+	for _, cache := range []*titleCache{vsm.normalizedVideos, vsm.strippedVideos, vsm.videoIDs, vsm.metadataTitles, vsm.nfoTitles} {
+		if cache != nil {
+			cache.reset()
+		}
+	}
+	if vsm.embeddedTracks != nil {
+		vsm.embeddedTracks.reset()
+	}
+
+	archiveFS, scanArchives := vsm.fs.(*archiveFileSystem)
+	if scanArchives {
+		archiveFS.reset()
+	}
+
+	classify := func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if vsm.inCleanupFolder(path) {
+			return nil
+		}
+
+		if target, ok := partialTarget(path); ok {
+			partials[target] = true
+			return nil
+		}
 
-	if vsm.recursive {
-		err := filepath.Walk(vsm.directory, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
+		ext := strings.ToLower(filepath.Ext(path))
+
+		for _, videoExt := range vsm.videoExtensions {
+			if ext == videoExt {
+				videoFiles = append(videoFiles, path)
+				return nil
 			}
+		}
 
-			if info.IsDir() {
+		for _, subtitleExt := range vsm.subtitleExtensions {
+			if ext == subtitleExt {
+				subtitleFiles = append(subtitleFiles, path)
 				return nil
 			}
+		}
 
-			ext := strings.ToLower(filepath.Ext(path))
+		if scanArchives && isArchive(path) {
+			archives = append(archives, path)
+		} else if vsm.sniffContent && isSniffCandidate(path) && sniffFormat(vsm.fs, path) != "" {
+			subtitleFiles = append(subtitleFiles, path)
+		}
 
-			for _, videoExt := range vsm.videoExtensions {
-				if ext == videoExt {
-					videoFiles = append(videoFiles, path)
-					return nil
-				}
+		return nil
+	}
+
+	var err error
+	if vsm.fileList != nil {
+		err = vsm.classifyFileList(ctx, classify, &videoFiles)
+	} else {
+		err = withContext(ctx, vsm.fs).Walk(vsm.directory, vsm.recursive, classify)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	videoFiles = vsm.completeVideos(videoFiles, partials)
+	videoFiles = vsm.excludeSampleVideos(videoFiles)
+	videoFiles = vsm.dropShortVideos(ctx, videoFiles)
+
+	// Archives are read after the walk so that real subtitle files take
+	// precedence over archived ones with the same name
+	for _, archive := range archives {
+		entries, err := archiveFS.register(ctx, archive, vsm.isSubtitleName)
+		if err != nil {
+			if vsm.verbose {
+				vsm.printf(colorRed, "  Error reading archive %s: %v\n", filepath.Base(archive), err)
+			}
+			continue
+		}
+		subtitleFiles = append(subtitleFiles, entries...)
+	}
+
+	// Normalize video titles once instead of for every subtitle
+	for _, videoPath := range videoFiles {
+		vsm.strippedVideo(videoPath)
+	}
+	if err := vsm.probeMetadataTitles(ctx, videoFiles); err != nil {
+		return nil, nil, err
+	}
+	if vsm.indexVideos {
+		vsm.videoIndex = vsm.buildVideoIndex(videoFiles)
+	}
+	return videoFiles, pairVobSubs(vsm.fs, subtitleFiles), nil
+}
+
+// leadingZerosPattern matches zero padding at the start of a numeric token,
+// but not the digits after a decimal point ("2.05" is not "2.5").
+var leadingZerosPattern = regexp.MustCompile(`(^\.?|[^\d.]|\D\.)0+(\d)`)
+
+// youtubeIDPattern matches the bracketed video ID yt-dlp appends to file names.
+var youtubeIDPattern = regexp.MustCompile(`\[[A-Za-z0-9_-]+\]`)
+
+// whitespacePattern matches runs of whitespace.
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// normalizeTitle normalizes video/subtitle titles for comparison by removing
+// platform-specific patterns and standardizing the format.
+//
+// This function handles common patterns like:
+// - Platform naming noise selected by the preset (see TitlePreset)
+// - The title field of names made by the yt-dlp output template
+// - Underscores to spaces conversion
+// - Full-width and CJK punctuation to ASCII (e.g., ？ to ?, 【】 to [])
+// - Emoji and other symbols, if StripSymbols is set
+// - Numeric padding (e.g., Episode 02 to Episode 2)
+// - Roman numerals and spelled-out numbers (e.g., Part II, Part Two to part 2)
+func (vsm *VideoSubtitleMatcher) normalizeTitle(title string) string {
+	// Use the title field of names made by the yt-dlp output template, and
+	// otherwise remove platform-specific patterns such as YouTube IDs
+	if parsed := vsm.templateField(title, "title"); parsed != "" {
+		title = parsed
+	} else {
+		title = vsm.applyPreset(title)
+	}
+
+	// Replace underscores with spaces and normalize
+	title = strings.ReplaceAll(title, "_", " ")
+	title = normalizeWidth(title)
+	if vsm.stripSymbols {
+		title = stripSymbols(title)
+	}
+
+	// Strip leading zeros so "02" and "2" compare equal
+	title = leadingZerosPattern.ReplaceAllString(title, "${1}${2}")
+
+	// Remove extra spaces and convert to lowercase
+	title = strings.TrimSpace(title)
+	title = whitespacePattern.ReplaceAllString(title, " ")
+
+	return normalizeNumberWords(strings.ToLower(title))
+}
+
+// normalizedVideo returns the normalized title of a video file.
+func (vsm *VideoSubtitleMatcher) normalizedVideo(videoPath string) string {
+	return vsm.normalizedVideos.lookup(videoPath, func() string {
+		return vsm.normalizeTitle(titleOf(videoPath))
+	})
+}
+
+// strippedVideo returns the normalized title of a video file without its
+// multi-part marker, as compared with subtitles.
+func (vsm *VideoSubtitleMatcher) strippedVideo(videoPath string) string {
+	return vsm.strippedVideos.lookup(videoPath, func() string {
+		return stripPart(vsm.normalizedVideo(videoPath))
+	})
+}
+
+// findBestMatch finds the best matching video file for a given subtitle file
+// using fuzzy string matching based on the longest common subsequence algorithm.
+//
+// Returns the path of the best matching video file, its similarity score (0.0-1.0)
+// and the score of the runner-up video.
+func (vsm *VideoSubtitleMatcher) findBestMatch(subtitlePath string, videoFiles []string) (string, float64, float64) {
+	var bestMatch string
+	var bestScore, runnerUpScore float64
+
+	for _, candidate := range vsm.prunedCandidates(subtitlePath, videoFiles) {
+		if candidate.Similarity > bestScore {
+			runnerUpScore = bestScore
+			bestScore = candidate.Similarity
+			bestMatch = candidate.VideoPath
+		} else if candidate.Similarity > runnerUpScore {
+			runnerUpScore = candidate.Similarity
+		}
+	}
+
+	return bestMatch, bestScore, runnerUpScore
+}
+
+// scoreCandidates scores every video against a subtitle, in scan order.
+// Videos whose multi-part marker (CD1, Part2, ...) differs from the subtitle's
+// are never considered.
+func (vsm *VideoSubtitleMatcher) scoreCandidates(subtitlePath string, videoFiles []string) []Candidate {
+	score := vsm.candidateScorer(subtitlePath)
+
+	candidates := make([]Candidate, 0, len(videoFiles))
+	for _, videoPath := range videoFiles {
+		if similarity, ok := score(videoPath); ok {
+			candidates = append(candidates, Candidate{VideoPath: videoPath, Similarity: similarity})
+		}
+	}
+
+	return candidates
+}
+
+// candidateScorer returns a function scoring videos against a subtitle. It
+// reports false for videos whose multi-part marker differs from the subtitle's.
+func (vsm *VideoSubtitleMatcher) candidateScorer(subtitlePath string) func(videoPath string) (float64, bool) {
+	normalizedSubtitle := vsm.normalizeTitle(subtitleTitle(subtitlePath))
+	mode := vsm.mediaMode(subtitlePath, stripPart(normalizedSubtitle))
+
+	var subtitleDirs string
+	if vsm.directoryWeight > 0 {
+		subtitleDirs = vsm.directoryContext(subtitlePath)
+	}
+
+	return func(videoPath string) (float64, bool) {
+		normalizedVideo := vsm.normalizedVideo(videoPath)
+
+		videoPart, _ := detectPart(normalizedVideo)
+		subtitlePart, comparedSubtitle := pairedPart(normalizedSubtitle, videoPart)
+		if !partsCompatible(subtitlePart, videoPart) {
+			return 0, false
+		}
+		if !vsm.modeCompatible(mode, subtitlePath, comparedSubtitle, videoPath, normalizedVideo) {
+			return 0, false
+		}
+
+		similarity := func(subtitleTitle, videoTitle string) float64 {
+			if vsm.directoryWeight > 0 {
+				return vsm.pathSimilarity(subtitleDirs, subtitleTitle, vsm.directoryContext(videoPath), videoTitle)
 			}
+			return vsm.calculateSimilarity(subtitleTitle, videoTitle)
+		}
+		compare := func(videoTitle string) float64 {
+			if mode == ModeMovie {
+				return movieSimilarity(comparedSubtitle, videoTitle, similarity)
+			}
+			if mode == ModeTV {
+				return similarity(vsm.alignEpisodeMarker(comparedSubtitle, subtitlePath, videoTitle, videoPath), videoTitle)
+			}
+			return similarity(comparedSubtitle, videoTitle)
+		}
+
+		score := compare(vsm.strippedVideo(videoPath))
+		// Metadata titles stand in for uninformative file names
+		for _, alias := range vsm.videoAliases(videoPath) {
+			score = max(score, compare(stripPart(vsm.normalizeTitle(alias))))
+		}
+		return score, true
+	}
+}
 
-			for _, subtitleExt := range vsm.subtitleExtensions {
-				if ext == subtitleExt {
-					subtitleFiles = append(subtitleFiles, path)
-					return nil
+// calculateSimilarity calculates the similarity between two strings using the
+// longest common subsequence (LCS) algorithm.
+//
+// Returns a score between 0.0 (no similarity) and 1.0 (identical).
+func (vsm *VideoSubtitleMatcher) calculateSimilarity(s1, s2 string) float64 {
+	if s1 == s2 {
+		return 1.0
+	}
+
+	lcs := vsm.longestCommonSubsequence(s1, s2)
+	maxLen := len(s1)
+	if len(s2) > maxLen {
+		maxLen = len(s2)
+	}
+
+	if maxLen == 0 {
+		return 0.0
+	}
+
+	return float64(lcs) / float64(maxLen)
+}
+
+// longestCommonSubsequence calculates the length of the longest common subsequence
+// between two strings using dynamic programming.
+func (vsm *VideoSubtitleMatcher) longestCommonSubsequence(s1, s2 string) int {
+	m, n := len(s1), len(s2)
+	dp := make([][]int, m+1)
+	for i := range dp {
+		dp[i] = make([]int, n+1)
+	}
+
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			if s1[i-1] == s2[j-1] {
+				dp[i][j] = dp[i-1][j-1] + 1
+			} else {
+				if dp[i-1][j] > dp[i][j-1] {
+					dp[i][j] = dp[i-1][j]
+				} else {
+					dp[i][j] = dp[i][j-1]
 				}
 			}
+		}
+	}
+
+	return dp[m][n]
+}
+
+// MatchResult represents the result of a subtitle matching operation.
+type MatchResult struct {
+	SubtitlePath       string           `json:"subtitle_path"`                  // Original subtitle file path
+	VideoPath          string           `json:"video_path,omitempty"`           // Matched video file path
+	NewSubtitlePath    string           `json:"new_subtitle_path,omitempty"`    // New subtitle file path after renaming
+	Similarity         float64          `json:"similarity"`                     // Similarity score (0.0-1.0)
+	RunnerUpSimilarity float64          `json:"runner_up_similarity,omitempty"` // Score of the second best video, if any
+	Confidence         Confidence       `json:"confidence"`                     // How trustworthy the match is (High, Medium or Low)
+	Candidates         []Candidate      `json:"candidates,omitempty"`           // Best scoring videos, best first (only set when Candidates is enabled)
+	Language           string           `json:"language,omitempty"`             // ISO 639-1 language code detected from the filename, if any
+	SDH                bool             `json:"sdh,omitempty"`                  // Whether the subtitle was detected as SDH (only set when SDH handling is enabled)
+	Invalid            bool             `json:"invalid,omitempty"`              // Whether validation found the subtitle broken (only set when validation is enabled)
+	Issues             []string         `json:"issues,omitempty"`               // Problems found by validation, including non-fatal ones
+	Repaired           bool             `json:"repaired,omitempty"`             // Whether the subtitle content was repaired
+	Converted          bool             `json:"converted,omitempty"`            // Whether the subtitle was converted to another format
+	MarkupStripped     bool             `json:"markup_stripped,omitempty"`      // Whether inline markup was removed from the subtitle
+	TextNormalized     bool             `json:"text_normalized,omitempty"`      // Whether line endings or the byte order mark were rewritten
+	Retimed            bool             `json:"retimed,omitempty"`              // Whether the subtitle timing was converted to the video's frame rate
+	SyncOffset         float64          `json:"sync_offset,omitempty"`          // Seconds the cues were shifted by to line up with the video's dialogue
+	MergedSubtitlePath string           `json:"merged_subtitle_path,omitempty"` // Bilingual subtitle merged from this and another subtitle, if any
+	JoinedSubtitlePath string           `json:"joined_subtitle_path,omitempty"` // Subtitle joined from this and the other parts, if any
+	SplitSubtitlePaths []string         `json:"split_subtitle_paths,omitempty"` // Per-language subtitles split from this bilingual subtitle, if any
+	OCRSubtitlePath    string           `json:"ocr_subtitle_path,omitempty"`    // Text subtitle read from this image-based subtitle by OCR, if any
+	TranslationPath    string           `json:"translation_path,omitempty"`     // Machine-translated copy of this subtitle, if any
+	Generated          bool             `json:"generated,omitempty"`            // Whether the subtitle is transcribed for a video without one rather than matched (Renamed once written)
+	Fetched            bool             `json:"fetched,omitempty"`              // Whether the result is a search of SubtitleProviders for a video without a subtitle rather than a match (Renamed once downloaded)
+	Remote             []RemoteSubtitle `json:"remote,omitempty"`               // Subtitles found online for the video, best first (only set when Fetched)
+	Explanation        *Explanation     `json:"explanation,omitempty"`          // Score breakdown (only set when Explain is enabled)
+	EmbeddedOverlap    float64          `json:"embedded_overlap,omitempty"`     // Share of the subtitle's text found in the video's embedded subtitles (only set when VerifyEmbedded compared them)
+	Rejected           bool             `json:"rejected,omitempty"`             // Whether VerifyEmbedded rejected the match, as the text differs from the video's embedded subtitles
+	Mapped             bool             `json:"mapped,omitempty"`               // Whether the subtitle was pinned by the mappings file
+	Pass               MatchPass        `json:"pass,omitempty"`                 // Matching pass that found the video (empty for mapped subtitles)
+	Archive            string           `json:"archive,omitempty"`              // Archive the subtitle is extracted from, if any
+	PairedPath         string           `json:"paired_path,omitempty"`          // VobSub .sub renamed along with this .idx, if any
+	Format             string           `json:"format,omitempty"`               // Extension detected from the content, when the file's own is wrong or missing
+	Quality            float64          `json:"quality,omitempty"`              // Quality score, when compared with duplicates
+	DuplicateOf        string           `json:"duplicate_of,omitempty"`         // Better subtitle that kept the name, when this one was skipped as a duplicate
+	CleanedUpPath      string           `json:"cleaned_up_path,omitempty"`      // Where the duplicate was moved by CleanupDuplicates, if it was
+	Held               bool             `json:"held,omitempty"`                 // Whether the match scored below SureThreshold, or competes for its name under MultiMatchPrompt, and awaits confirmation
+	Renamed            bool             `json:"renamed"`                        // Whether the file was actually renamed
+	Error              error            `json:"-"`                              // Any error that occurred during renaming
+	ErrorMessage       string           `json:"error,omitempty"`                // Text of Error, kept when results are stored and reloaded
+}
+
+// Matcher is implemented by types that match subtitles to videos.
+// It allows applications to mock matching in tests or wrap a matcher with
+// decorators such as caching or metrics.
+type Matcher interface {
+	MatchContext(ctx context.Context) ([]MatchResult, error)
+}
+
+// VideoSubtitleMatcher implements Matcher.
+var _ Matcher = (*VideoSubtitleMatcher)(nil)
+
+// Match performs the subtitle matching and renaming operation.
+// Returns a slice of MatchResult containing details about each processed subtitle file.
+//
+// This is the main entry point for the subtitle matching functionality.
+// It is equivalent to MatchContext with a background context.
+func (vsm *VideoSubtitleMatcher) Match() ([]MatchResult, error) {
+	return vsm.MatchContext(context.Background())
+}
+
+// MatchContext is like Match but stops when ctx is cancelled. Renames already
+// performed are not undone; their results are returned along with the
+// context's error.
+//
+// When renaming or processing some subtitles failed, all results are returned
+// along with a *MultiError listing the failures.
+//
+// Runs that rename files hold a lock file in the directory for their whole
+// duration; a second run started meanwhile fails with ErrLocked.
+func (vsm *VideoSubtitleMatcher) MatchContext(ctx context.Context) ([]MatchResult, error) {
+	return vsm.match(ctx, nil)
+}
+
+// match runs a match, passing each included result to emit (when not nil)
+// as soon as it has been executed.
+func (vsm *VideoSubtitleMatcher) match(ctx context.Context, emit func(MatchResult)) ([]MatchResult, error) {
+	started := time.Now()
+
+	// Concurrent runs must not share the caches filled by scanning
+	vsm = vsm.Clone()
+
+	ctx, cancel := vsm.runContext(ctx)
+	defer cancel()
+
+	if err := vsm.checkRoot(); err != nil {
+		return nil, err
+	}
+
+	lock, err := vsm.lockRun()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	videoFiles, subtitleFiles, err := vsm.scanFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan files: %w", err)
+	}
+
+	// Subtitles skipped as processed still give their video a subtitle
+	scannedSubtitles := subtitleFiles
+	if subtitleFiles, err = vsm.skipProcessedFiles(subtitleFiles); err != nil {
+		return nil, fmt.Errorf("failed to read processed subtitles: %w", err)
+	}
+
+	vsm.logFileCount(len(videoFiles), len(subtitleFiles))
+	vsm.metrics.observeScan(len(videoFiles), len(subtitleFiles))
+
+	if vsm.embedder != nil {
+		if err := vsm.embedTitles(ctx, videoFiles, subtitleFiles); err != nil {
+			return nil, fmt.Errorf("failed to embed titles: %w", err)
+		}
+	}
+
+	var mappings mappingTable
+	if vsm.mappingFile != "" {
+		if mappings, err = loadMappings(vsm.mappingFile); err != nil {
+			return nil, fmt.Errorf("failed to load mappings: %w", err)
+		}
+	}
+
+	planned := make([]MatchResult, 0, len(subtitleFiles))
+	for _, subtitlePath := range subtitleFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		matchStarted := time.Now()
+		planned = append(planned, vsm.processSubtitleFile(ctx, subtitlePath, videoFiles, mappings))
+		vsm.metrics.observeMatch(time.Since(matchStarted))
+	}
+
+	if vsm.sdhMode == SDHDeprioritize {
+		deprioritizeSDH(planned)
+	}
+
+	if len(vsm.languagePriority) > 0 {
+		vsm.preferLanguages(planned)
+	}
+
+	if vsm.preferQuality || vsm.multiMatch == MultiMatchKeepBest {
+		vsm.dropWorseDuplicates(planned)
+	}
+
+	if vsm.multiMatch == MultiMatchPrompt {
+		vsm.chooseDuplicates(planned)
+	}
+
+	if len(vsm.formatPreference) > 0 || vsm.multiMatch == MultiMatchKeepAll {
+		vsm.preferFormats(planned)
+	}
+
+	if vsm.strict && !vsm.dryRun {
+		if problems := findAmbiguities(vsm.fs, planned); len(problems) > 0 {
+			return nil, &AmbiguityError{Problems: problems}
+		}
+	}
+
+	vsm.holdUnsure(planned)
+
+	if vsm.debugOutput != nil {
+		if err := vsm.writeDebugTrace(started, videoFiles, subtitleFiles, planned); err != nil {
+			return nil, fmt.Errorf("failed to write debug trace: %w", err)
+		}
+	}
+
+	if err := vsm.checkPlannedPaths(planned); err != nil {
+		return nil, err
+	}
+
+	if err := vsm.checkOperationLimit(planned); err != nil {
+		return nil, err
+	}
+
+	if err := vsm.runPreflight(planned); err != nil {
+		return nil, err
+	}
+
+	// Merge and join before renaming so the sources are still intact
+	vsm.plannedNames = plannedSubtitleNames(planned)
+	if vsm.mergeTop != "" && vsm.mergeBottom != "" {
+		vsm.mergeBilingual(planned)
+	}
+	if vsm.joinParts {
+		vsm.joinSubtitleParts(ctx, planned)
+	}
+
+	matched := len(planned)
+	if len(vsm.providers) > 0 {
+		searched, err := vsm.plannedSearches(ctx, videoFiles, scannedSubtitles, planned)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, searched...)
+	}
+	if vsm.transcriber != nil && vsm.isLocal() {
+		planned = append(planned, vsm.plannedTranscriptions(videoFiles, scannedSubtitles, planned)...)
+	}
+	// Downloads and transcriptions are written next to their videos
+	if err := vsm.checkPlannedPaths(planned[matched:]); err != nil {
+		return nil, err
+	}
+
+	return vsm.execute(ctx, started, planned, emit)
+}
+
+// lockRun takes the run lock of a run that renames local files and resolves
+// renames an interrupted run left unfinished. The interrupted run usually
+// left its lock behind too, so the lock is taken first, taking over a stale
+// one, and the journal is only recovered once no other run can be renaming.
+// It returns a nil lock for runs that need none.
+func (vsm *VideoSubtitleMatcher) lockRun() (*runLock, error) {
+	if vsm.dryRun || !vsm.isLocal() {
+		return nil, nil
+	}
+
+	lock, err := acquireLock(vsm.directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock directory: %w", err)
+	}
+	if lock.stale != "" && vsm.verbose {
+		fmt.Printf("Taking over the lock of an interrupted run (%s)\n", lock.stale)
+	}
+	if err := vsm.recoverJournal(); err != nil {
+		lock.release()
+		return nil, fmt.Errorf("failed to recover interrupted run: %w", err)
+	}
+	return lock, nil
+}
 
-			return nil
-		})
-		return videoFiles, subtitleFiles, err
-	} else {
-		// Non-recursive scan - only current directory
-		entries, err := os.ReadDir(vsm.directory)
-		if err != nil {
-			return nil, nil, err
+// execute performs a plan, passing each included result to emit (when not
+// nil) as soon as it has been executed, then records and reports the run.
+func (vsm *VideoSubtitleMatcher) execute(ctx context.Context, started time.Time, planned []MatchResult, emit func(MatchResult)) ([]MatchResult, error) {
+	var journal *renameJournal
+	if vsm.journaled() {
+		var err error
+		if journal, err = vsm.openJournal(planned); err != nil {
+			return nil, fmt.Errorf("failed to write rename journal: %w", err)
 		}
+	}
 
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
+	var results []MatchResult
+	var stream *ndjsonStream
+	if vsm.ndjsonOutput != nil {
+		stream = newNDJSONStream(vsm.ndjsonOutput)
+	}
 
-			fullPath := filepath.Join(vsm.directory, entry.Name())
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
+	// Downloads and transcriptions are planned after merging and joining
+	vsm.plannedNames.addPlanned(planned)
+	if vsm.translator != nil {
+		vsm.plannedNames.addTranslated(planned, vsm.translateTo)
+	}
 
-			for _, videoExt := range vsm.videoExtensions {
-				if ext == videoExt {
-					videoFiles = append(videoFiles, fullPath)
-					break
-				}
+	for _, result := range planned {
+		// A cancelled run is not interrupted: the remaining renames are dropped
+		// rather than completed by the next run
+		if err := ctx.Err(); err != nil {
+			journal.close()
+			if recordErr := vsm.recordRun(started, results); recordErr != nil {
+				return results, errors.Join(err, fmt.Errorf("failed to record run: %w", recordErr))
 			}
-
-			for _, subtitleExt := range vsm.subtitleExtensions {
-				if ext == subtitleExt {
-					subtitleFiles = append(subtitleFiles, fullPath)
-					break
-				}
+			return results, err
+		}
+		if result.Generated {
+			result = vsm.generateSubtitle(ctx, result)
+		} else if result.Fetched {
+			result = vsm.fetchSubtitle(ctx, result)
+		} else {
+			result = vsm.executeResult(ctx, result)
+		}
+		if result.Error != nil {
+			result.ErrorMessage = result.Error.Error()
+		}
+		vsm.metrics.observeResult(result)
+		if err := journal.markDone(result); err != nil {
+			return results, fmt.Errorf("failed to update rename journal: %w", err)
+		}
+		if vsm.shouldIncludeResult(result) {
+			results = append(results, result)
+			stream.emit(result)
+			if emit != nil {
+				emit(result)
 			}
 		}
-		return videoFiles, subtitleFiles, nil
 	}
-}
-
-// normalizeTitle normalizes video/subtitle titles for comparison by removing
-// platform-specific patterns and standardizing the format.
-//
-// This function handles common patterns like:
-// - YouTube IDs in brackets: [ABC123]
-// - YouTube subtitle suffixes: -_YouTube-zh-CN-dual-double
-// - Underscores to spaces conversion
-// - Character normalization (e.g., ？ to ?)
-func (vsm *VideoSubtitleMatcher) normalizeTitle(title string) string {
-	// Remove YouTube ID pattern [xxxxx] from video files
-	re := regexp.MustCompile(`\[[A-Za-z0-9_-]+\]`)
-	title = re.ReplaceAllString(title, "")
 
-	// Remove YouTube subtitle patterns
-	title = strings.ReplaceAll(title, "-_YouTube-zh-CN-dual-double", "")
-	title = strings.ReplaceAll(title, "_-_YouTube", "")
+	if err := journal.close(); err != nil {
+		return results, fmt.Errorf("failed to remove rename journal: %w", err)
+	}
 
-	// Replace underscores with spaces and normalize
-	title = strings.ReplaceAll(title, "_", " ")
-	title = strings.ReplaceAll(title, "？", "?")
+	vsm.logSummary(results)
+	vsm.metrics.observeRun(time.Now())
 
-	// Remove extra spaces and convert to lowercase
-	title = strings.TrimSpace(title)
-	title = regexp.MustCompile(`\s+`).ReplaceAllString(title, " ")
+	if err := vsm.recordProcessed(results); err != nil {
+		return results, fmt.Errorf("failed to record processed subtitles: %w", err)
+	}
 
-	return strings.ToLower(title)
-}
+	// Compare before recording, while the previous run is still the last one
+	if err := vsm.writeChanges(results); err != nil {
+		return results, fmt.Errorf("failed to write changes: %w", err)
+	}
 
-// findBestMatch finds the best matching video file for a given subtitle file
-// using fuzzy string matching based on the longest common subsequence algorithm.
-//
-// Returns the path of the best matching video file and the similarity score (0.0-1.0).
-func (vsm *VideoSubtitleMatcher) findBestMatch(subtitlePath string, videoFiles []string) (string, float64) {
-	subtitleName := strings.TrimSuffix(filepath.Base(subtitlePath), filepath.Ext(subtitlePath))
-	normalizedSubtitle := vsm.normalizeTitle(subtitleName)
+	if err := vsm.recordRun(started, results); err != nil {
+		return results, fmt.Errorf("failed to record run: %w", err)
+	}
 
-	var bestMatch string
-	var bestScore float64
+	if err := vsm.notify(ctx, results); err != nil {
+		return results, fmt.Errorf("failed to send notification: %w", err)
+	}
 
-	for _, videoPath := range videoFiles {
-		videoName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
-		normalizedVideo := vsm.normalizeTitle(videoName)
+	if err := stream.err(); err != nil {
+		return results, fmt.Errorf("failed to write NDJSON output: %w", err)
+	}
 
-		score := vsm.calculateSimilarity(normalizedSubtitle, normalizedVideo)
-		if score > bestScore {
-			bestScore = score
-			bestMatch = videoPath
+	if vsm.writesDiff() {
+		if err := WriteDiff(vsm.diffOutput, results); err != nil {
+			return results, fmt.Errorf("failed to write diff: %w", err)
 		}
 	}
 
-	return bestMatch, bestScore
-}
-
-// calculateSimilarity calculates the similarity between two strings using the
-// longest common subsequence (LCS) algorithm.
-//
-// Returns a score between 0.0 (no similarity) and 1.0 (identical).
-func (vsm *VideoSubtitleMatcher) calculateSimilarity(s1, s2 string) float64 {
-	if s1 == s2 {
-		return 1.0
+	if vsm.writesScript() {
+		if err := WriteScript(vsm.scriptOutput, results, vsm.scriptShell); err != nil {
+			return results, fmt.Errorf("failed to write rename script: %w", err)
+		}
 	}
 
-	lcs := vsm.longestCommonSubsequence(s1, s2)
-	maxLen := len(s1)
-	if len(s2) > maxLen {
-		maxLen = len(s2)
+	if vsm.tableOutput != nil {
+		if err := writeTable(vsm.tableOutput, results, vsm.paint); err != nil {
+			return results, fmt.Errorf("failed to write table: %w", err)
+		}
 	}
 
-	if maxLen == 0 {
-		return 0.0
+	if vsm.groupOutput != nil {
+		if err := WriteVideoGroups(vsm.groupOutput, results); err != nil {
+			return results, fmt.Errorf("failed to write video groups: %w", err)
+		}
 	}
 
-	return float64(lcs) / float64(maxLen)
-}
-
-// longestCommonSubsequence calculates the length of the longest common subsequence
-// between two strings using dynamic programming.
-func (vsm *VideoSubtitleMatcher) longestCommonSubsequence(s1, s2 string) int {
-	m, n := len(s1), len(s2)
-	dp := make([][]int, m+1)
-	for i := range dp {
-		dp[i] = make([]int, n+1)
+	if vsm.writesPlanFile() {
+		if err := WritePlan(vsm.planOutput, NewPlan(vsm.directory, results)); err != nil {
+			return results, fmt.Errorf("failed to write plan: %w", err)
+		}
 	}
 
-	for i := 1; i <= m; i++ {
-		for j := 1; j <= n; j++ {
-			if s1[i-1] == s2[j-1] {
-				dp[i][j] = dp[i-1][j-1] + 1
-			} else {
-				if dp[i-1][j] > dp[i][j-1] {
-					dp[i][j] = dp[i-1][j]
-				} else {
-					dp[i][j] = dp[i][j-1]
-				}
-			}
+	if vsm.heldOutput != nil {
+		if err := WritePlan(vsm.heldOutput, heldPlan(vsm.directory, results)); err != nil {
+			return results, fmt.Errorf("failed to write held plan: %w", err)
 		}
 	}
 
-	return dp[m][n]
+	return results, collectErrors(results)
 }
 
-// MatchResult represents the result of a subtitle matching operation.
-type MatchResult struct {
-	SubtitlePath    string  // Original subtitle file path
-	VideoPath       string  // Matched video file path
-	NewSubtitlePath string  // New subtitle file path after renaming
-	Similarity      float64 // Similarity score (0.0-1.0)
-	Renamed         bool    // Whether the file was actually renamed
-	Error           error   // Any error that occurred during renaming
+// writesDiff reports whether the dry run plan is written as a diff
+func (vsm *VideoSubtitleMatcher) writesDiff() bool {
+	return vsm.dryRun && vsm.diffOutput != nil
 }
 
-// Match performs the subtitle matching and renaming operation.
-// Returns a slice of MatchResult containing details about each processed subtitle file.
-//
-// This is the main entry point for the subtitle matching functionality.
-func (vsm *VideoSubtitleMatcher) Match() ([]MatchResult, error) {
-	videoFiles, subtitleFiles, err := vsm.scanFiles()
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan files: %w", err)
-	}
-
-	vsm.logFileCount(len(videoFiles), len(subtitleFiles))
+// writesScript reports whether the dry run plan is written as a rename script
+func (vsm *VideoSubtitleMatcher) writesScript() bool {
+	return vsm.dryRun && vsm.scriptOutput != nil
+}
 
-	var results []MatchResult
-	for _, subtitlePath := range subtitleFiles {
-		result := vsm.processSubtitleFile(subtitlePath, videoFiles)
-		if vsm.shouldIncludeResult(result) {
-			results = append(results, result)
-		}
-	}
+// writesPlanFile reports whether the dry run plan is written for Apply
+func (vsm *VideoSubtitleMatcher) writesPlanFile() bool {
+	return vsm.dryRun && vsm.planOutput != nil
+}
 
-	vsm.logSummary(results)
-	return results, nil
+// writesPlan reports whether the dry run plan is written out instead of
+// logged match by match
+func (vsm *VideoSubtitleMatcher) writesPlan() bool {
+	return vsm.writesDiff() || vsm.writesScript() || vsm.writesPlanFile()
 }
 
 // logFileCount logs the number of video and subtitle files found
@@ -335,93 +1936,426 @@ func (vsm *VideoSubtitleMatcher) logFileCount(videoCount, subtitleCount int) {
 
 // shouldIncludeResult determines if a result should be included in the final results
 func (vsm *VideoSubtitleMatcher) shouldIncludeResult(result MatchResult) bool {
-	// Skip if already correctly named and ignoreExisting is true
-	if vsm.ignoreExisting && result.SubtitlePath == result.NewSubtitlePath {
+	// Skip if already correctly named and ignoreExisting is true. Archived
+	// subtitles named like their target still have to be extracted.
+	if vsm.ignoreExisting && result.SubtitlePath == result.NewSubtitlePath && result.Archive == "" && !result.Generated && !result.Fetched {
 		return false
 	}
 	return true
 }
 
-// processSubtitleFile processes a single subtitle file and returns the match result
-func (vsm *VideoSubtitleMatcher) processSubtitleFile(subtitlePath string, videoFiles []string) MatchResult {
-	bestMatch, score := vsm.findBestMatch(subtitlePath, videoFiles)
+// processSubtitleFile finds the best video for a single subtitle file and plans
+// its new name. Subtitles listed in the mappings file bypass automatic matching.
+// No file operations are performed here.
+func (vsm *VideoSubtitleMatcher) processSubtitleFile(ctx context.Context, subtitlePath string, videoFiles []string, mappings mappingTable) MatchResult {
+	var result MatchResult
+	if target, ok := mappings.lookup(vsm.directory, subtitlePath); ok {
+		result = vsm.mappedResult(subtitlePath, target, videoFiles)
+	} else {
+		result = vsm.scoredResult(subtitlePath, videoFiles)
+	}
+	result.Language = detectLanguage(subtitlePath)
+	result.Archive = vsm.archiveOf(subtitlePath)
+	result.PairedPath = vobSubData(vsm.fs, subtitlePath)
+	if vsm.sniffContent && !vsm.isSubtitleName(subtitlePath) {
+		result.Format = sniffFormat(vsm.fs, subtitlePath)
+	}
+
+	if vsm.sdhMode != SDHIgnore {
+		result.SDH = isSDH(vsm.fs, subtitlePath)
+	}
+
+	if vsm.validate {
+		result = vsm.validateSubtitle(result)
+	}
+
+	if result.NewSubtitlePath == "" && result.Similarity >= vsm.similarityThreshold {
+		result.NewSubtitlePath = vsm.planNewSubtitlePath(result)
+	}
+
+	if vsm.embeddedTracks != nil && result.NewSubtitlePath != "" && !result.Mapped && !result.Invalid {
+		result = vsm.verifyEmbedded(ctx, result)
+	}
+
+	return result
+}
+
+// scoredResult matches a subtitle against the videos by similarity
+func (vsm *VideoSubtitleMatcher) scoredResult(subtitlePath string, videoFiles []string) MatchResult {
+	localVideos := vsm.localVideos(subtitlePath, videoFiles)
+	videoFiles = vsm.indexedVideos(subtitlePath, localVideos)
+
+	// Fuzzy scoring is only needed when no video has the subtitle's name
+	bestMatch, pass := vsm.exactMatch(subtitlePath, videoFiles)
+	score, runnerUp := 1.0, 0.0
+	if bestMatch == "" {
+		bestMatch, score, runnerUp = vsm.findBestMatch(subtitlePath, videoFiles)
+		pass = PassFuzzy
+	}
+	// Translated titles share no letters, so the trigram index is no help
+	if vsm.embedder != nil && score < vsm.similarityThreshold {
+		if video, similarity, second := vsm.semanticMatch(subtitlePath, localVideos); video != "" {
+			bestMatch, score, runnerUp, pass = video, similarity, second, PassSemantic
+		}
+	}
 
 	result := MatchResult{
 		SubtitlePath: subtitlePath,
 		VideoPath:    bestMatch,
 		Similarity:   score,
 	}
+	result.RunnerUpSimilarity = runnerUp
+	if bestMatch != "" {
+		result.Pass = pass
+	}
 
-	if score >= vsm.similarityThreshold {
-		result = vsm.processMatchedSubtitle(result, bestMatch)
-	} else {
-		vsm.logNoMatch(subtitlePath, score)
+	if vsm.candidateCount > 0 {
+		result.Candidates = topCandidates(vsm.scoreCandidates(subtitlePath, videoFiles), vsm.candidateCount)
+	}
+
+	if bestMatch != "" {
+		agreement := signalAgreement(vsm.normalizeTitle(subtitleTitle(subtitlePath)), vsm.normalizedVideo(bestMatch))
+		result.Confidence = classifyConfidence(score, runnerUp, vsm.similarityThreshold, agreement)
+	}
+
+	if vsm.explain {
+		result.Explanation = vsm.explainMatch(subtitlePath, bestMatch, videoFiles)
 	}
 
 	return result
 }
 
-// processMatchedSubtitle handles a subtitle that has a good match
-func (vsm *VideoSubtitleMatcher) processMatchedSubtitle(result MatchResult, bestMatch string) MatchResult {
-	videoBaseName := strings.TrimSuffix(filepath.Base(bestMatch), filepath.Ext(bestMatch))
+// planNewSubtitlePath builds the new subtitle path for a matched subtitle
+func (vsm *VideoSubtitleMatcher) planNewSubtitlePath(result MatchResult) string {
+	videoBaseName := strings.TrimSuffix(filepath.Base(result.VideoPath), filepath.Ext(result.VideoPath))
 	subtitleExt := filepath.Ext(result.SubtitlePath)
-	newSubtitlePath := filepath.Join(filepath.Dir(result.SubtitlePath), videoBaseName+subtitleExt)
-	result.NewSubtitlePath = newSubtitlePath
+	if result.Format != "" {
+		subtitleExt = result.Format
+	}
+	if vsm.needsConversion(result) {
+		subtitleExt = ".srt"
+	}
+
+	// Keep multi-part subtitles apart when the video is a single file
+	subtitleName := strings.TrimSuffix(filepath.Base(result.SubtitlePath), filepath.Ext(result.SubtitlePath))
+	videoPart, _ := detectPart(vsm.normalizeTitle(videoBaseName))
+	subtitlePart, _ := pairedPart(vsm.normalizeTitle(subtitleName), videoPart)
+	videoBaseName = vsm.targetBaseName(result.VideoPath) + partSuffix(subtitlePart, videoPart)
+	// and the subtitles of single episodes when the video holds several
+	videoBaseName += vsm.multiEpisodeSuffix(result)
+
+	// Subtitles from a torrent Subs folder move next to their video, tagged
+	// with their language because the folder usually holds several tracks
+	dir := filepath.Dir(result.SubtitlePath)
+	if _, ok := subsFolder(result.SubtitlePath); ok {
+		dir = filepath.Dir(result.VideoPath)
+		if result.Language != "" {
+			videoBaseName += "." + result.Language
+		}
+	}
+
+	if result.SDH && vsm.sdhMode == SDHTag {
+		videoBaseName += sdhTag
+	}
+
+	return filepath.Join(dir, videoBaseName+subtitleExt)
+}
+
+// executeResult logs a planned result and performs its rename unless in dry run mode
+func (vsm *VideoSubtitleMatcher) executeResult(ctx context.Context, result MatchResult) MatchResult {
+	if result.DuplicateOf != "" {
+		vsm.logDuplicate(result)
+		return vsm.cleanupDuplicate(result)
+	}
+	if result.Rejected {
+		vsm.logRejected(result)
+		return result
+	}
+	if result.NewSubtitlePath == "" {
+		vsm.logNoMatch(result.SubtitlePath, result.Similarity)
+		return result
+	}
 
 	vsm.logMatch(result)
 
+	if result.Invalid {
+		vsm.logInvalid(result)
+		return result
+	}
+
+	if result.Held {
+		vsm.logHeld(result)
+		return result
+	}
+
 	if !vsm.dryRun {
-		result = vsm.performRename(result)
+		// Read before renaming, while the original subtitle still exists
+		modTime := vsm.sourceModTime(result)
+
+		result = vsm.performRename(ctx, result)
+		if vsm.repair && result.Renamed && result.Error == nil {
+			result = vsm.repairSubtitle(result)
+		}
+		if vsm.stripMarkup && result.Renamed && result.Error == nil {
+			result = vsm.stripSubtitleMarkup(result)
+		}
+		if vsm.subtitleFrameRate > 0 && result.Renamed && result.Error == nil {
+			result = vsm.retimeSubtitle(ctx, result)
+		}
+		if vsm.resyncWindow > 0 && result.Renamed && result.Error == nil {
+			result = vsm.resyncSubtitle(ctx, result)
+		}
+		if vsm.normalizesText() && result.Renamed && result.Error == nil {
+			result = vsm.normalizeSubtitleText(result)
+		}
+		result = vsm.applyModTime(result, modTime)
+	}
+
+	if vsm.splitBilingual && result.Error == nil {
+		result = vsm.splitSubtitle(result)
+	}
+	if vsm.ocrLanguage != "" && result.Error == nil {
+		result = vsm.ocrSubtitle(ctx, result)
+	}
+	if vsm.translator != nil && result.Error == nil {
+		result = vsm.translateSubtitle(ctx, result)
+	}
+
+	return result
+}
+
+// repairSubtitle runs the SRT repair pass on a renamed subtitle
+func (vsm *VideoSubtitleMatcher) repairSubtitle(result MatchResult) MatchResult {
+	if !strings.EqualFold(filepath.Ext(result.NewSubtitlePath), ".srt") {
+		return result
 	}
 
+	repaired, err := repairSRT(vsm.fs, result.NewSubtitlePath)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to repair subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error repairing: %v\n", err)
+		}
+		return result
+	}
+
+	result.Repaired = repaired
+	if repaired && vsm.verbose {
+		vsm.printf(colorGreen, "  ✓ Repaired cue numbering and timestamps\n")
+	}
 	return result
 }
 
 // logMatch logs information about a successful match
 func (vsm *VideoSubtitleMatcher) logMatch(result MatchResult) {
-	if !vsm.verbose {
+	if !vsm.verbose || vsm.writesPlan() {
 		return
 	}
 
-	fmt.Printf("\nMatch found (%.2f similarity):\n", result.Similarity)
+	// The header is colored like the rename that would happen
+	color := ""
+	if vsm.dryRun {
+		color = colorYellow
+	}
+	if result.Mapped {
+		vsm.printf(color, "\nManual mapping:\n")
+	} else if result.Pass == PassID {
+		vsm.printf(color, "\nMatch found (video ID, %s confidence):\n", result.Confidence)
+	} else if result.Pass == PassIndex {
+		vsm.printf(color, "\nMatch found (playlist index, %s confidence):\n", result.Confidence)
+	} else if result.Pass == PassExact || result.Pass == PassNormalized {
+		vsm.printf(color, "\nMatch found (%s name, %s confidence):\n", result.Pass, result.Confidence)
+	} else if result.Pass == PassSemantic {
+		vsm.printf(color, "\nMatch found (%.2f semantic similarity, %s confidence):\n", result.Similarity, result.Confidence)
+	} else {
+		vsm.printf(color, "\nMatch found (%.2f similarity, %s confidence):\n", result.Similarity, result.Confidence)
+	}
 	fmt.Printf("  Subtitle: %s\n", filepath.Base(result.SubtitlePath))
-	fmt.Printf("  Video:    %s\n", filepath.Base(result.VideoPath))
+	if result.Archive != "" {
+		fmt.Printf("  Archive:  %s\n", filepath.Base(result.Archive))
+	}
+	if result.VideoPath != "" {
+		fmt.Printf("  Video:    %s\n", filepath.Base(result.VideoPath))
+	}
 	fmt.Printf("  New name: %s\n", filepath.Base(result.NewSubtitlePath))
+	if result.PairedPath != "" {
+		fmt.Printf("  Paired:   %s -> %s\n", filepath.Base(result.PairedPath), filepath.Base(withExt(result.NewSubtitlePath, vobSubDataExt)))
+	}
+
+	if vsm.previewLines > 0 {
+		for _, line := range previewLines(vsm.fs, result.SubtitlePath, vsm.previewLines) {
+			fmt.Printf("    | %s\n", line)
+		}
+	}
+}
+
+// logInvalid logs the validation problems of a subtitle that will not be renamed
+func (vsm *VideoSubtitleMatcher) logInvalid(result MatchResult) {
+	if !vsm.verbose {
+		return
+	}
+
+	vsm.printf(colorRed, "  ✗ Invalid subtitle, not renaming:\n")
+	for _, issue := range result.Issues {
+		fmt.Printf("    - %s\n", issue)
+	}
+}
+
+// logHeld logs a match held for confirmation
+func (vsm *VideoSubtitleMatcher) logHeld(result MatchResult) {
+	if !vsm.verbose {
+		return
+	}
+
+	if vsm.sureThreshold > 0 && result.Similarity < vsm.sureThreshold && !result.Mapped {
+		vsm.printf(colorYellow, "  ? Held for confirmation (%.2f below sure threshold %.2f)\n", result.Similarity, vsm.sureThreshold)
+		return
+	}
+	vsm.printf(colorYellow, "  ? Held for confirmation (other subtitles match this video)\n")
 }
 
 // logNoMatch logs information about a subtitle with no good match
 func (vsm *VideoSubtitleMatcher) logNoMatch(subtitlePath string, score float64) {
-	if vsm.verbose {
-		fmt.Printf("\nNo good match found for: %s (best score: %.2f)\n", filepath.Base(subtitlePath), score)
+	if vsm.verbose && !vsm.writesPlan() {
+		vsm.printf(colorGray, "\nNo good match found for: %s (best score: %.2f)\n", filepath.Base(subtitlePath), score)
 	}
 }
 
 // performRename performs the actual file renaming operation
-func (vsm *VideoSubtitleMatcher) performRename(result MatchResult) MatchResult {
-	if result.SubtitlePath == result.NewSubtitlePath {
+func (vsm *VideoSubtitleMatcher) performRename(ctx context.Context, result MatchResult) MatchResult {
+	if result.SubtitlePath == result.NewSubtitlePath && result.Archive == "" {
 		result.Renamed = true
 		if vsm.verbose {
-			fmt.Printf("  ✓ Already correctly named\n")
+			vsm.printf(colorGray, "  ✓ Already correctly named\n")
 		}
 		return result
 	}
 
-	err := os.Rename(result.SubtitlePath, result.NewSubtitlePath)
+	var err error
+	if vsm.needsConversion(result) {
+		err = vsm.withRetry(ctx, func(ctx context.Context) error { return vsm.convertSubtitle(ctx, result) })
+		result.Converted = err == nil
+	} else if result.PairedPath != "" {
+		err = vsm.renameVobSub(ctx, result)
+	} else {
+		err = vsm.withRetry(ctx, func(ctx context.Context) error { return vsm.rename(ctx, result.SubtitlePath, result.NewSubtitlePath) })
+	}
 	if err != nil {
 		result.Error = err
 		if vsm.verbose {
-			fmt.Printf("  Error renaming: %v\n", err)
+			vsm.printf(colorRed, "  Error renaming: %v\n", err)
 		}
 	} else {
 		result.Renamed = true
+		if vsm.verbose && result.Converted {
+			vsm.printf(colorGreen, "  ✓ Converted to SRT and renamed successfully\n")
+		} else if vsm.verbose && result.Archive != "" {
+			vsm.printf(colorGreen, "  ✓ Extracted successfully\n")
+		} else if vsm.verbose {
+			vsm.printf(colorGreen, "  ✓ Renamed successfully\n")
+		}
+	}
+
+	return result
+}
+
+// stripSubtitleMarkup removes inline markup from a renamed subtitle
+func (vsm *VideoSubtitleMatcher) stripSubtitleMarkup(result MatchResult) MatchResult {
+	stripped, err := stripMarkup(vsm.fs, result.NewSubtitlePath)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to strip markup: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error stripping markup: %v\n", err)
+		}
+		return result
+	}
+
+	result.MarkupStripped = stripped
+	if stripped && vsm.verbose {
+		vsm.printf(colorGreen, "  ✓ Removed inline markup tags\n")
+	}
+	return result
+}
+
+// normalizeSubtitleText rewrites the line endings and byte order mark of a
+// renamed subtitle
+func (vsm *VideoSubtitleMatcher) normalizeSubtitleText(result MatchResult) MatchResult {
+	normalized, err := normalizeText(vsm.fs, result.NewSubtitlePath, vsm.lineEnding, vsm.bomMode)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to normalize line endings: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error normalizing line endings: %v\n", err)
+		}
+		return result
+	}
+
+	result.TextNormalized = normalized
+	if normalized && vsm.verbose {
+		vsm.printf(colorGreen, "  ✓ Normalized line endings and byte order mark\n")
+	}
+	return result
+}
+
+// retimeSubtitle converts a renamed SRT subtitle to the matched video's frame rate
+func (vsm *VideoSubtitleMatcher) retimeSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	if !strings.EqualFold(filepath.Ext(result.NewSubtitlePath), ".srt") {
+		return result
+	}
+
+	// MicroDVD conversions are already timed with the video's frame rate
+	if result.Converted && strings.EqualFold(filepath.Ext(result.SubtitlePath), ".sub") {
+		return result
+	}
+
+	videoFPS, err := vsm.videoFrameRate(ctx, result.VideoPath)
+	if err != nil {
+		if vsm.verbose {
+			vsm.printf(colorGray, "  Skipping retiming: %v\n", err)
+		}
+		return result
+	}
+
+	retimed, err := retimeSRT(vsm.fs, result.NewSubtitlePath, vsm.subtitleFrameRate, videoFPS)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to retime subtitle: %w", err)
 		if vsm.verbose {
-			fmt.Printf("  ✓ Renamed successfully\n")
+			vsm.printf(colorRed, "  Error retiming: %v\n", err)
 		}
+		return result
 	}
 
+	result.Retimed = retimed
+	if retimed && vsm.verbose {
+		vsm.printf(colorGreen, "  ✓ Retimed from %.3f to %.3f fps\n", vsm.subtitleFrameRate, videoFPS)
+	}
 	return result
 }
 
+// needsConversion reports whether a subtitle is converted to SRT instead of plainly renamed
+func (vsm *VideoSubtitleMatcher) needsConversion(result MatchResult) bool {
+	switch subtitleFormat(result) {
+	case ".vtt":
+		return vsm.convertVTT
+	case ".sub":
+		return vsm.convertMicroDVD && isMicroDVD(vsm.fs, result.SubtitlePath)
+	}
+	return false
+}
+
+// convertSubtitle converts a subtitle to SRT at its new path and removes the original
+func (vsm *VideoSubtitleMatcher) convertSubtitle(ctx context.Context, result MatchResult) error {
+	fsys := withContext(ctx, vsm.fs)
+	if subtitleFormat(result) == ".vtt" {
+		return convertVTTToSRT(fsys, result.SubtitlePath, result.NewSubtitlePath)
+	}
+
+	fps, err := vsm.videoFrameRate(ctx, result.VideoPath)
+	if err != nil {
+		fps = defaultFrameRate
+	}
+	return convertMicroDVDToSRT(fsys, result.SubtitlePath, result.NewSubtitlePath, fps)
+}
+
 // logSummary logs the final summary of the matching operation
 func (vsm *VideoSubtitleMatcher) logSummary(results []MatchResult) {
 	if !vsm.verbose {
@@ -436,13 +2370,17 @@ func (vsm *VideoSubtitleMatcher) logSummary(results []MatchResult) {
 	} else {
 		fmt.Printf("\nRenaming completed. %d subtitles processed.\n", matchCount)
 	}
+
+	if held := countHeld(results); held > 0 {
+		fmt.Printf("%d matches held for confirmation.\n", held)
+	}
 }
 
-// countMatches counts the number of subtitles that met the similarity threshold
+// countMatches counts the number of valid subtitles that met the similarity threshold
 func (vsm *VideoSubtitleMatcher) countMatches(results []MatchResult) int {
 	count := 0
 	for _, result := range results {
-		if result.Similarity >= vsm.similarityThreshold {
+		if result.Similarity >= vsm.similarityThreshold && !result.Invalid && !result.Rejected && !result.Held && result.DuplicateOf == "" {
 			count++
 		}
 	}
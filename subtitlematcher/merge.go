@@ -0,0 +1,134 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// mergeBilingual writes a bilingual SRT for every video that has matched .srt
+// subtitles in both the top and bottom merge languages. Each merged cue shows
+// the top language's lines above the bottom language's lines. A merged name
+// that exists or is planned for another subtitle is left alone.
+func (vsm *VideoSubtitleMatcher) mergeBilingual(results []MatchResult) {
+	type pair struct{ top, bottom int }
+	pairs := make(map[string]*pair)
+	var videos []string
+
+	for i, result := range results {
+		if result.NewSubtitlePath == "" || result.Invalid || result.Held || subtitleFormat(result) != ".srt" {
+			continue
+		}
+		p, ok := pairs[result.VideoPath]
+		if !ok {
+			p = &pair{top: -1, bottom: -1}
+			pairs[result.VideoPath] = p
+			videos = append(videos, result.VideoPath)
+		}
+		switch {
+		case result.Language == vsm.mergeTop && p.top < 0:
+			p.top = i
+		case result.Language == vsm.mergeBottom && p.bottom < 0:
+			p.bottom = i
+		}
+	}
+
+	for _, video := range videos {
+		p := pairs[video]
+		if p.top < 0 || p.bottom < 0 {
+			continue
+		}
+
+		mergedPath := filepath.Join(filepath.Dir(results[p.top].NewSubtitlePath),
+			fmt.Sprintf("%s.%s-%s.srt", vsm.targetBaseName(video), vsm.mergeTop, vsm.mergeBottom))
+		if err := vsm.outputTaken(mergedPath); err != nil {
+			vsm.logMergeSkipped(results[p.top], err)
+			continue
+		}
+		vsm.plannedNames[mergedPath] = true
+
+		err := vsm.writeMergedSubtitle(results[p.top].SubtitlePath, results[p.bottom].SubtitlePath, mergedPath)
+		for _, i := range []int{p.top, p.bottom} {
+			results[i].MergedSubtitlePath = mergedPath
+			if err != nil {
+				results[i].Error = fmt.Errorf("failed to merge subtitles: %w", err)
+			}
+		}
+		vsm.logMerge(results[p.top], results[p.bottom], err)
+	}
+}
+
+// writeMergedSubtitle merges two SRT files into a bilingual SRT file.
+// Nothing is written in dry run mode.
+func (vsm *VideoSubtitleMatcher) writeMergedSubtitle(topPath, bottomPath, mergedPath string) error {
+	if vsm.dryRun {
+		return nil
+	}
+
+	top, err := readSRT(vsm.fs, topPath)
+	if err != nil {
+		return err
+	}
+	bottom, err := readSRT(vsm.fs, bottomPath)
+	if err != nil {
+		return err
+	}
+
+	merged := formatSRT(mergeCues(top.Cues, bottom.Cues), "\n")
+	return vsm.fs.WriteFile(mergedPath, []byte(merged))
+}
+
+// mergeCues combines two cue lists. Bottom cues overlapping a top cue are
+// folded into it below the top text; the rest are kept as separate cues.
+func mergeCues(top, bottom []srtCue) []srtCue {
+	merged := make([]srtCue, 0, len(top)+len(bottom))
+	used := make([]bool, len(bottom))
+
+	for _, cue := range top {
+		combined := srtCue{Start: cue.Start, End: cue.End, Lines: append([]string(nil), cue.Lines...)}
+		for j, other := range bottom {
+			if !used[j] && other.Start < cue.End && other.End > cue.Start {
+				combined.Lines = append(combined.Lines, other.Lines...)
+				used[j] = true
+			}
+		}
+		merged = append(merged, combined)
+	}
+
+	for j, other := range bottom {
+		if !used[j] {
+			merged = append(merged, other)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Start < merged[j].Start
+	})
+	return merged
+}
+
+// logMerge logs the outcome of merging two subtitles
+func (vsm *VideoSubtitleMatcher) logMerge(top, bottom MatchResult, err error) {
+	if !vsm.verbose {
+		return
+	}
+
+	fmt.Printf("\nBilingual merge for %s:\n", filepath.Base(top.VideoPath))
+	fmt.Printf("  Top:    %s\n", filepath.Base(top.SubtitlePath))
+	fmt.Printf("  Bottom: %s\n", filepath.Base(bottom.SubtitlePath))
+	fmt.Printf("  Merged: %s\n", filepath.Base(top.MergedSubtitlePath))
+
+	switch {
+	case err != nil:
+		vsm.printf(colorRed, "  Error merging: %v\n", err)
+	case !vsm.dryRun:
+		vsm.printf(colorGreen, "  ✓ Merged successfully\n")
+	}
+}
+
+// logMergeSkipped logs a merge left out because its name is taken
+func (vsm *VideoSubtitleMatcher) logMergeSkipped(top MatchResult, err error) {
+	if vsm.verbose {
+		vsm.printf(colorGray, "\nSkipping bilingual merge for %s: %v\n", filepath.Base(top.VideoPath), err)
+	}
+}
@@ -0,0 +1,56 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeDoesNotOverwriteSubtitles(t *testing.T) {
+	const (
+		cues = "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+		dual = "1\n00:00:01,000 --> 00:00:02,000\n你好\nA bilingual subtitle of its own\n"
+	)
+	tests := []struct {
+		name      string
+		subtitles map[string]string
+		only      bool
+		mappings  string
+	}{
+		// Only the subtitles to merge are matched, so Movie.zh-en.srt stays where it is
+		{"existing", map[string]string{"movie.en.srt": cues, "movie.zh.srt": cues, "Movie.zh-en.srt": dual}, true, ""},
+		{"planned", map[string]string{"movie.en.srt": cues, "movie.zh.srt": cues, "dual.srt": dual}, false, "dual.srt -> Movie.zh-en.srt\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, "Movie.mkv")
+			writeSubtitles(t, dir, tt.subtitles)
+
+			options := []Option{Verbose(false), DryRun(false), MergeBilingual("zh", "en")}
+			if tt.only {
+				options = append(options, Files([]string{filepath.Join(dir, "movie.en.srt"), filepath.Join(dir, "movie.zh.srt")}))
+			}
+			if tt.mappings != "" {
+				mappings := filepath.Join(t.TempDir(), "mappings.txt")
+				if err := os.WriteFile(mappings, []byte(tt.mappings), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				options = append(options, MappingFile(mappings))
+			}
+
+			results, _ := New(dir, options...).Match()
+			for _, name := range []string{"movie.en.srt", "movie.zh.srt"} {
+				if result := matchOf(t, results, name); result.MergedSubtitlePath != "" {
+					t.Errorf("%s: MergedSubtitlePath = %q, want none", name, result.MergedSubtitlePath)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "Movie.zh-en.srt"))
+			if err != nil || !strings.Contains(string(data), "of its own") {
+				t.Errorf("Movie.zh-en.srt = %q, %v; want the bilingual subtitle of its own", data, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// probeTitle returns the title tag of a video container using ffprobe, or ""
+// when the container has none.
+func probeTitle(ctx context.Context, videoPath string) (string, error) {
+	out, err := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "format_tags=title",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		videoPath,
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// titleCache remembers titles derived from videos during a run, so each video
+// is inspected once however many subtitles are compared with it.
+type titleCache struct {
+	mu     sync.Mutex
+	titles map[string]string // Video path → title ("" when there is none)
+}
+
+// reset forgets the titles looked up by the previous run.
+func (c *titleCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.titles = nil
+}
+
+// lookup returns the cached title of a video, calling find on a miss.
+func (c *titleCache) lookup(videoPath string, find func() string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if title, ok := c.titles[videoPath]; ok {
+		return title
+	}
+	if c.titles == nil {
+		c.titles = make(map[string]string)
+	}
+	title := find()
+	c.titles[videoPath] = title
+	return title
+}
+
+// probeMetadataTitles reads the title tags of the scanned videos when
+// MetadataTitles is enabled, so that matching finds them cached. Videos that
+// cannot be probed, including all videos on remote file systems, have no
+// title.
+func (vsm *VideoSubtitleMatcher) probeMetadataTitles(ctx context.Context, videoFiles []string) error {
+	if vsm.metadataTitles == nil || !vsm.isLocal() {
+		return nil
+	}
+	for _, videoPath := range videoFiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		vsm.metadataTitles.lookup(videoPath, func() string {
+			title, err := probeTitle(ctx, videoPath)
+			if err != nil && vsm.verbose {
+				vsm.printf(colorRed, "  Error reading title of %s: %v\n", videoPath, err)
+			}
+			return title
+		})
+	}
+	return nil
+}
+
+// metadataTitle returns the title tag of a video found by
+// probeMetadataTitles, or "".
+func (vsm *VideoSubtitleMatcher) metadataTitle(videoPath string) string {
+	if vsm.metadataTitles == nil {
+		return ""
+	}
+	return vsm.metadataTitles.lookup(videoPath, func() string { return "" })
+}
+
+// videoAliases returns the titles a video is known by besides its file
+// name: its container title tag and its .nfo title, when enabled.
+func (vsm *VideoSubtitleMatcher) videoAliases(videoPath string) []string {
+	var aliases []string
+	for _, title := range []string{vsm.metadataTitle(videoPath), vsm.nfoTitle(videoPath)} {
+		if title != "" {
+			aliases = append(aliases, title)
+		}
+	}
+	return aliases
+}
@@ -0,0 +1,167 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// matchDurationBuckets are the upper bounds, in seconds, of the match
+// duration histogram.
+var matchDurationBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Metrics counts matcher activity across runs and serves it in the
+// Prometheus text exposition format. It is safe for concurrent use, so one
+// Metrics can be shared by several matchers and scraped while they run.
+// The zero value is ready to use. A nil *Metrics ignores all updates and
+// serves empty metrics.
+type Metrics struct {
+	mu               sync.Mutex
+	runs             int
+	videosScanned    int
+	subtitlesScanned int
+	matches          int
+	renames          int
+	failures         int
+	bucketCounts     []int // Per bucket of matchDurationBuckets, allocated on the first match
+	durationSum      float64
+	durationCount    int
+	lastRun          time.Time
+}
+
+// NewMetrics returns an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// observeScan counts scanned files.
+func (m *Metrics) observeScan(videos, subtitles int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.videosScanned += videos
+	m.subtitlesScanned += subtitles
+}
+
+// observeMatch records how long matching one subtitle took.
+func (m *Metrics) observeMatch(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bucketCounts == nil {
+		m.bucketCounts = make([]int, len(matchDurationBuckets))
+	}
+	seconds := duration.Seconds()
+	for i, bound := range matchDurationBuckets {
+		if seconds <= bound {
+			m.bucketCounts[i]++
+		}
+	}
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+// observeResult counts the outcome of one executed result.
+func (m *Metrics) observeResult(result MatchResult) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if result.NewSubtitlePath != "" {
+		m.matches++
+	}
+	if result.Renamed && result.SubtitlePath != result.NewSubtitlePath {
+		m.renames++
+	}
+	if result.Error != nil {
+		m.failures++
+	}
+}
+
+// observeRun counts a finished run.
+func (m *Metrics) observeRun(finished time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs++
+	m.lastRun = finished
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format, so a Metrics
+// can be mounted directly, e.g. http.Handle("/metrics", metrics).
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	if m == nil {
+		m = &Metrics{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	counter := func(name, help string, value int) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+
+	counter("subtitle_matcher_runs_total", "Completed matching runs.", m.runs)
+	fmt.Fprintf(cw, "# HELP subtitle_matcher_files_scanned_total Files found by scans.\n")
+	fmt.Fprintf(cw, "# TYPE subtitle_matcher_files_scanned_total counter\n")
+	fmt.Fprintf(cw, "subtitle_matcher_files_scanned_total{kind=\"video\"} %d\n", m.videosScanned)
+	fmt.Fprintf(cw, "subtitle_matcher_files_scanned_total{kind=\"subtitle\"} %d\n", m.subtitlesScanned)
+	counter("subtitle_matcher_matches_total", "Subtitles matched to a video.", m.matches)
+	counter("subtitle_matcher_renames_total", "Subtitles renamed.", m.renames)
+	counter("subtitle_matcher_failures_total", "Subtitles whose rename or processing failed.", m.failures)
+
+	name := "subtitle_matcher_match_duration_seconds"
+	fmt.Fprintf(cw, "# HELP %s Time spent matching one subtitle.\n# TYPE %s histogram\n", name, name)
+	for i, bound := range matchDurationBuckets {
+		count := 0
+		if m.bucketCounts != nil {
+			count = m.bucketCounts[i]
+		}
+		fmt.Fprintf(cw, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), count)
+	}
+	fmt.Fprintf(cw, "%s_bucket{le=\"+Inf\"} %d\n", name, m.durationCount)
+	fmt.Fprintf(cw, "%s_sum %g\n%s_count %d\n", name, m.durationSum, name, m.durationCount)
+
+	if !m.lastRun.IsZero() {
+		name = "subtitle_matcher_last_run_timestamp_seconds"
+		fmt.Fprintf(cw, "# HELP %s When the last run finished.\n# TYPE %s gauge\n%s %d\n",
+			name, name, name, m.lastRun.Unix())
+	}
+
+	return cw.n, cw.err
+}
+
+// countingWriter counts written bytes and keeps the first write error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,54 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// dropShortVideos removes the videos smaller than the minimum size or
+// shorter than the minimum duration from the match targets. Durations are
+// probed with ffprobe, so the duration check only applies on the local file
+// system; videos that cannot be measured are kept.
+func (vsm *VideoSubtitleMatcher) dropShortVideos(ctx context.Context, videoFiles []string) []string {
+	if vsm.minVideoSize <= 0 && vsm.minVideoDuration <= 0 {
+		return videoFiles
+	}
+
+	kept := videoFiles[:0:0]
+	for _, videoPath := range videoFiles {
+		if reason := vsm.shortReason(ctx, videoPath); reason != "" {
+			if vsm.verbose {
+				vsm.printf(colorGray, "Skipping short video %s: %s\n", filepath.Base(videoPath), reason)
+			}
+			continue
+		}
+		kept = append(kept, videoPath)
+	}
+	return kept
+}
+
+// shortReason explains why a video is below the minimum size or duration,
+// or returns "".
+func (vsm *VideoSubtitleMatcher) shortReason(ctx context.Context, videoPath string) string {
+	if vsm.minVideoSize > 0 {
+		if info, err := vsm.fs.Stat(videoPath); err == nil && info.Size() < vsm.minVideoSize {
+			return fmt.Sprintf("%d bytes, less than %d", info.Size(), vsm.minVideoSize)
+		}
+	}
+
+	if vsm.minVideoDuration > 0 && vsm.isLocal() {
+		duration, err := probeDuration(ctx, videoPath)
+		if err != nil {
+			if vsm.verbose {
+				vsm.printf(colorRed, "  Error reading duration of %s: %v\n", videoPath, err)
+			}
+			return ""
+		}
+		if duration < vsm.minVideoDuration {
+			return fmt.Sprintf("%s long, less than %s", duration.Round(time.Second), vsm.minVideoDuration)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MediaMode selects the heuristics used to tell videos apart: episode and
+// season numbers for TV shows, year and title for movies.
+type MediaMode int
+
+const (
+	// ModeAuto uses the TV heuristics for subtitles with an episode marker
+	// ("S01E02", "1x02", "Episode 2", "SP1"), an air date ("2024.03.15") or
+	// a specials marker, the movie heuristics for subtitles with a year, and
+	// neither for other subtitles.
+	ModeAuto MediaMode = iota
+	// ModeTV never matches a subtitle with a video of another episode or
+	// season, or of another air date for daily shows, and matches specials
+	// ("S00E01", "SP1", "OVA", Specials folders) only with specials. Seasons
+	// missing from file names are taken from season folders ("Season 2",
+	// "S02").
+	ModeTV
+	// ModeMovie never matches a subtitle with a video of another year, and
+	// compares the titles before the year ahead of the release details
+	// after it.
+	ModeMovie
+)
+
+// ParseMediaMode returns the media mode with the given name ("auto", "tv"
+// or "movie"), ignoring case.
+func ParseMediaMode(name string) (MediaMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "auto":
+		return ModeAuto, nil
+	case "tv":
+		return ModeTV, nil
+	case "movie":
+		return ModeMovie, nil
+	}
+	return ModeAuto, fmt.Errorf("unknown mode %q: expected auto, tv or movie", name)
+}
+
+// movieTitleWeight is the share of a movie's score given to the title before
+// the year in ModeMovie; the rest compares the whole names.
+const movieTitleWeight = 0.7
+
+// seasonFolderPattern matches a season folder name such as "Season 2",
+// "Series 02" or "S02".
+var seasonFolderPattern = regexp.MustCompile(`(?i)^(?:season|series|s)[\s._-]*(\d{1,2})$`)
+
+// mediaMode returns the heuristics that apply to a subtitle.
+func (vsm *VideoSubtitleMatcher) mediaMode(subtitlePath, normalizedSubtitle string) MediaMode {
+	if vsm.mode != ModeAuto {
+		return vsm.mode
+	}
+	if _, _, ok := vsm.findEpisodeMarker(normalizedSubtitle, subtitlePath); ok {
+		return ModeTV
+	}
+	if vsm.isSpecial(normalizedSubtitle, subtitlePath) {
+		return ModeTV
+	}
+	if _, _, ok := findAirDates(normalizedSubtitle); ok {
+		return ModeTV
+	}
+	if detectYear(normalizedSubtitle) != 0 {
+		return ModeMovie
+	}
+	return ModeAuto
+}
+
+// folderSeason returns the season number of a season folder holding path, or 0.
+func folderSeason(path string) int {
+	m := seasonFolderPattern.FindStringSubmatch(filepath.Base(filepath.Dir(path)))
+	if m == nil {
+		return 0
+	}
+	season, _ := strconv.Atoi(m[1])
+	return season
+}
+
+// sameEpisode reports whether two episode markers can name the same episode:
+// they share an episode number, counting every episode of multi-episode
+// markers, both or neither are specials, and their seasons are equal when
+// both are known.
+func sameEpisode(a, b episodeNumber) bool {
+	return a.Episode <= b.last() && b.Episode <= a.last() && a.Special == b.Special &&
+		(a.Season == 0 || b.Season == 0 || a.Season == b.Season)
+}
+
+// movieTitle returns the part of a normalized movie title before its year,
+// or the whole title when it has no year or starts with one.
+func movieTitle(normalizedTitle string) string {
+	loc := yearPattern.FindStringSubmatchIndex(normalizedTitle)
+	if loc == nil {
+		return normalizedTitle
+	}
+	if title := strings.TrimSpace(normalizedTitle[:loc[2]]); title != "" {
+		return title
+	}
+	return normalizedTitle
+}
+
+// modeCompatible reports whether a video can be the subtitle's under the
+// media mode: a special only for a special and of the same air date, or
+// episode and season, in ModeTV, and of the same year in ModeMovie, when
+// both carry them.
+func (vsm *VideoSubtitleMatcher) modeCompatible(mode MediaMode, subtitlePath, normalizedSubtitle, videoPath, normalizedVideo string) bool {
+	switch mode {
+	case ModeTV:
+		if vsm.isSpecial(normalizedSubtitle, subtitlePath) != vsm.isSpecial(normalizedVideo, videoPath) {
+			return false
+		}
+		subtitleDates, _, subtitleDated := findAirDates(normalizedSubtitle)
+		videoDates, _, videoDated := findAirDates(normalizedVideo)
+		if subtitleDated && videoDated {
+			return shareAirDate(subtitleDates, videoDates)
+		}
+		subtitleEpisode, _, subtitleOK := vsm.findEpisodeMarker(normalizedSubtitle, subtitlePath)
+		videoEpisode, _, videoOK := vsm.findEpisodeMarker(normalizedVideo, videoPath)
+		return !subtitleOK || !videoOK || sameEpisode(subtitleEpisode, videoEpisode)
+	case ModeMovie:
+		subtitleYear, videoYear := detectYear(normalizedSubtitle), detectYear(normalizedVideo)
+		return subtitleYear == 0 || videoYear == 0 || subtitleYear == videoYear
+	}
+	return true
+}
+
+// movieSimilarity scores a movie subtitle against a video title, weighing
+// the titles before their years ahead of the release details after them.
+func movieSimilarity(subtitleTitle, videoTitle string, similarity func(subtitleTitle, videoTitle string) float64) float64 {
+	whole := similarity(subtitleTitle, videoTitle)
+	if detectYear(subtitleTitle) == 0 || detectYear(videoTitle) == 0 {
+		return whole
+	}
+	title := similarity(movieTitle(subtitleTitle), movieTitle(videoTitle))
+	return movieTitleWeight*title + (1-movieTitleWeight)*whole
+}
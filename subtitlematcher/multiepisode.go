@@ -0,0 +1,89 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// multiEpisodeTailPattern matches the further episodes of a multi-episode
+// marker right after its first episode: the "e2" of "s1e1e2", the "-e2" of
+// "s1e1-e2", the "-2" of "s1e1-2" or the "-1x2" of "1x1-1x2".
+var multiEpisodeTailPattern = regexp.MustCompile(`^(?:-?e|-(?:\d{1,2}x)?)(\d{1,4})`)
+
+// maxMultiEpisodes is the most episodes a multi-episode marker may cover, so
+// that "s1e1-2020" is not read as two thousand episodes.
+const maxMultiEpisodes = 10
+
+// extendEpisode extends an episode marker ending at end over the further
+// episodes of a multi-episode marker, returning the new end. Ranges
+// ("s1e1-e3") and lists ("s1e1e2e3") both cover every episode from the
+// first to the last.
+func extendEpisode(normalizedTitle string, episode *episodeNumber, end int) int {
+	for {
+		m := multiEpisodeTailPattern.FindStringSubmatchIndex(normalizedTitle[end:])
+		if m == nil {
+			return end
+		}
+		next := end + m[1]
+		if next < len(normalizedTitle) && normalizedTitle[next] != 'e' && normalizedTitle[next] != '-' && isAlphanumeric(normalizedTitle[next]) {
+			// Not an episode, as in "s1e1-720p"
+			return end
+		}
+		last, _ := strconv.Atoi(normalizedTitle[end+m[2] : end+m[3]])
+		if last <= episode.last() || last-episode.Episode >= maxMultiEpisodes {
+			return end
+		}
+		episode.Last = last
+		end = next
+	}
+}
+
+// isAlphanumeric reports whether c is a lowercase letter or a digit.
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// last returns the last episode a marker covers.
+func (e episodeNumber) last() int {
+	return max(e.Episode, e.Last)
+}
+
+// multiEpisode reports whether a marker covers several episodes.
+func (e episodeNumber) multiEpisode() bool {
+	return e.Last > e.Episode
+}
+
+// covers reports whether a multi-episode marker includes a single episode.
+func (e episodeNumber) covers(episode episodeNumber) bool {
+	return sameEpisode(e, episode) && !episode.multiEpisode()
+}
+
+// episodeSuffix returns the suffix added to the new subtitle name when the
+// subtitle of one episode is matched to a multi-episode video, e.g. ".e02"
+// for the second episode of "Show S01E01E02.mkv", so that the subtitles of
+// each episode are kept apart.
+func episodeSuffix(subtitleEpisode, videoEpisode episodeNumber) string {
+	if !videoEpisode.multiEpisode() || !videoEpisode.covers(subtitleEpisode) {
+		return ""
+	}
+	return fmt.Sprintf(".e%02d", subtitleEpisode.Episode)
+}
+
+// resultEpisodes returns the episode markers of a matched subtitle and its
+// video, reporting false unless both have one.
+func (vsm *VideoSubtitleMatcher) resultEpisodes(result MatchResult) (episodeNumber, episodeNumber, bool) {
+	subtitleEpisode, _, subtitleOK := vsm.findEpisodeMarker(vsm.normalizeTitle(subtitleTitle(result.SubtitlePath)), result.SubtitlePath)
+	videoEpisode, _, videoOK := vsm.findEpisodeMarker(vsm.normalizedVideo(result.VideoPath), result.VideoPath)
+	return subtitleEpisode, videoEpisode, subtitleOK && videoOK
+}
+
+// multiEpisodeSuffix returns the episode suffix of a matched subtitle's new
+// name (see episodeSuffix), or "".
+func (vsm *VideoSubtitleMatcher) multiEpisodeSuffix(result MatchResult) string {
+	subtitleEpisode, videoEpisode, ok := vsm.resultEpisodes(result)
+	if !ok {
+		return ""
+	}
+	return episodeSuffix(subtitleEpisode, videoEpisode)
+}
@@ -0,0 +1,65 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileError is the failure of one subtitle's rename or processing.
+type FileError struct {
+	Path string // Path of the subtitle
+	Err  error  // What went wrong, as in the subtitle's MatchResult.Error
+}
+
+// Error returns the subtitle's path and its error.
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned by runs in which renaming or processing some
+// subtitles failed. The other subtitles were handled normally, and each
+// failure is also reported in the Error field of its result.
+type MultiError struct {
+	Errors []*FileError // One entry per failed subtitle, in execution order
+}
+
+// Error returns a report listing every failure.
+func (e *MultiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d subtitle(s) failed:", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the failures, so that errors.Is and errors.As look into
+// each of them.
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// collectErrors returns a *MultiError of the failed results, or nil when
+// none failed.
+func collectErrors(results []MatchResult) error {
+	var failures []*FileError
+	for _, result := range results {
+		if result.Error != nil {
+			failures = append(failures, &FileError{Path: result.SubtitlePath, Err: result.Error})
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &MultiError{Errors: failures}
+}
@@ -0,0 +1,114 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MultiMatch controls what happens when several subtitles match the same
+// video and are planned for the same name.
+type MultiMatch int
+
+const (
+	// MultiMatchReplace renames every subtitle to the video's name in scan
+	// order, each one replacing the previous, after the pre-flight check
+	// warns about the shared target. The last subtitle scanned wins.
+	MultiMatchReplace MultiMatch = iota
+	// MultiMatchKeepAll keeps every subtitle: the first by FormatPreference,
+	// then by path, gets the video's name and the others are tagged ".alt",
+	// ".alt2", ...
+	MultiMatchKeepAll
+	// MultiMatchKeepBest renames only the best subtitle, as PreferQuality.
+	MultiMatchKeepBest
+	// MultiMatchPrompt asks which subtitle to rename; the others are left
+	// alone. Without an answer, every candidate is held.
+	MultiMatchPrompt
+)
+
+// ParseMultiMatch returns the multi-match policy with the given name
+// ("replace", "keep-all", "keep-best" or "prompt"), ignoring case.
+func ParseMultiMatch(name string) (MultiMatch, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "replace":
+		return MultiMatchReplace, nil
+	case "keep-all":
+		return MultiMatchKeepAll, nil
+	case "keep-best":
+		return MultiMatchKeepBest, nil
+	case "prompt":
+		return MultiMatchPrompt, nil
+	}
+	return MultiMatchReplace, fmt.Errorf("unknown multi-match policy %q: expected replace, keep-all, keep-best or prompt", name)
+}
+
+// ChooseFunc picks which of several subtitles matching video is renamed,
+// returning its index in candidates, or -1 to hold them all. Candidates are
+// ordered best first and have Quality set.
+type ChooseFunc func(video string, candidates []MatchResult) int
+
+// groupByTarget groups the results planned for the same name, ignoring the
+// extension, and returns the indexes of each group in the order the names
+// first occur. Results not planned for a name, invalid ones and held ones
+// are left out.
+func groupByTarget(results []MatchResult) [][]int {
+	groups := make(map[string]int)
+	var indexes [][]int
+	for i, result := range results {
+		if result.NewSubtitlePath == "" || result.Invalid || result.Held {
+			continue
+		}
+		base := strings.TrimSuffix(result.NewSubtitlePath, filepath.Ext(result.NewSubtitlePath))
+		group, ok := groups[base]
+		if !ok {
+			group = len(indexes)
+			groups[base] = group
+			indexes = append(indexes, nil)
+		}
+		indexes[group] = append(indexes[group], i)
+	}
+	return indexes
+}
+
+// chooseDuplicates resolves subtitles planned for the same name by asking
+// the choose function. The chosen subtitle keeps its planned name and the
+// others record it in DuplicateOf. Dry runs hold every candidate without
+// asking, so that their report shows what would be asked.
+func (vsm *VideoSubtitleMatcher) chooseDuplicates(results []MatchResult) {
+	for _, indices := range groupByTarget(results) {
+		if len(indices) < 2 {
+			continue
+		}
+
+		for _, i := range indices {
+			results[i].Quality = subtitleQuality(vsm.fs, results[i])
+		}
+		sort.SliceStable(indices, func(a, b int) bool {
+			return results[indices[a]].Quality > results[indices[b]].Quality
+		})
+
+		chosen := -1
+		if !vsm.dryRun && vsm.chooseMatch != nil {
+			candidates := make([]MatchResult, len(indices))
+			for n, i := range indices {
+				candidates[n] = results[i]
+			}
+			chosen = vsm.chooseMatch(results[indices[0]].VideoPath, candidates)
+		}
+		if chosen < 0 || chosen >= len(indices) {
+			for _, i := range indices {
+				results[i].Held = true
+			}
+			continue
+		}
+
+		kept := results[indices[chosen]]
+		for n, i := range indices {
+			if n != chosen {
+				results[i].DuplicateOf = kept.SubtitlePath
+				results[i].NewSubtitlePath = ""
+			}
+		}
+	}
+}
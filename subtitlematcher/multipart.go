@@ -0,0 +1,66 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// partMarkerPattern matches multi-part markers in a normalized title: disc
+// markers such as "cd1" or "disc 2" and glued part markers such as "part1"
+// anywhere, and loose part markers such as "part 2" or "pt-2" only at its
+// end. Loose markers are captured apart, as they often belong to the title
+// ("deathly hallows part 2").
+var partMarkerPattern = regexp.MustCompile(`(?:^|[^a-z0-9])(?:(?:(?:cd|disc|disk)[ .-]?|part|pt)(\d{1,2})(?:[^0-9]|$)|(?:part|pt)[ .-](\d{1,2})$)`)
+
+// detectPart returns the part number found in a normalized title, or 0 if
+// the title carries no multi-part marker, and whether the marker is loose.
+func detectPart(normalizedTitle string) (int, bool) {
+	matches := partMarkerPattern.FindStringSubmatch(normalizedTitle)
+	if matches == nil {
+		return 0, false
+	}
+	number, loose := matches[1], false
+	if number == "" {
+		number, loose = matches[2], true
+	}
+	part, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, false
+	}
+	return part, loose
+}
+
+// pairedPart returns the part number of a subtitle compared with a video
+// carrying videoPart, and the subtitle title to compare with the video's. A
+// loose part marker only counts when the video carries a marker too, so
+// that "Movie Part II" is compared with "Movie II" as a whole title.
+func pairedPart(normalizedSubtitle string, videoPart int) (int, string) {
+	part, loose := detectPart(normalizedSubtitle)
+	if loose && videoPart == 0 {
+		return 0, normalizedSubtitle
+	}
+	return part, stripPart(normalizedSubtitle)
+}
+
+// stripPart removes the multi-part marker from a normalized title so that
+// part numbers, which are compared separately, do not dilute the similarity score.
+func stripPart(normalizedTitle string) string {
+	return strings.TrimSpace(partMarkerPattern.ReplaceAllString(normalizedTitle, " "))
+}
+
+// partsCompatible reports whether a subtitle and a video may belong together
+// based on their part numbers. Files without a marker are compatible with anything.
+func partsCompatible(subtitlePart, videoPart int) bool {
+	return subtitlePart == 0 || videoPart == 0 || subtitlePart == videoPart
+}
+
+// partSuffix returns the suffix added to the new subtitle name when a
+// multi-part subtitle is matched to a single-file video, e.g. ".cd1".
+func partSuffix(subtitlePart, videoPart int) string {
+	if subtitlePart == 0 || videoPart != 0 {
+		return ""
+	}
+	return fmt.Sprintf(".cd%d", subtitlePart)
+}
@@ -0,0 +1,62 @@
+package subtitlematcher
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// MarshalJSON encodes a MatchResult with its Error rendered as a string in
+// ErrorMessage.
+func (r MatchResult) MarshalJSON() ([]byte, error) {
+	type plain MatchResult
+
+	if r.Error != nil && r.ErrorMessage == "" {
+		r.ErrorMessage = r.Error.Error()
+	}
+	return json.Marshal(plain(r))
+}
+
+// UnmarshalJSON decodes a MatchResult written by MarshalJSON. The error text,
+// if any, is restored as a plain error.
+func (r *MatchResult) UnmarshalJSON(data []byte) error {
+	type plain MatchResult
+
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	if r.ErrorMessage != "" {
+		r.Error = errors.New(r.ErrorMessage)
+	}
+	return nil
+}
+
+// ndjsonStream writes results as newline-delimited JSON. The first write
+// error stops further output and is kept for the caller. A nil stream
+// discards everything.
+type ndjsonStream struct {
+	encoder  *json.Encoder
+	writeErr error
+}
+
+// newNDJSONStream creates a stream writing to w.
+func newNDJSONStream(w io.Writer) *ndjsonStream {
+	return &ndjsonStream{encoder: json.NewEncoder(w)}
+}
+
+// emit writes a single result as one line of JSON.
+func (s *ndjsonStream) emit(result MatchResult) {
+	if s == nil || s.writeErr != nil {
+		return
+	}
+	s.writeErr = s.encoder.Encode(result)
+}
+
+// err returns the first write error, if any.
+func (s *ndjsonStream) err() error {
+	if s == nil {
+		return nil
+	}
+	return s.writeErr
+}
@@ -0,0 +1,103 @@
+package subtitlematcher
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// movieNFOName is the .nfo Kodi reads for a movie stored in its own folder.
+const movieNFOName = "movie.nfo"
+
+// kodiNFO holds the fields of a Kodi movie or episode .nfo that identify it.
+type kodiNFO struct {
+	XMLName   xml.Name
+	Title     string `xml:"title"`
+	ShowTitle string `xml:"showtitle"`
+	Year      int    `xml:"year"`
+	Season    int    `xml:"season"`
+	Episode   int    `xml:"episode"`
+}
+
+// name returns the title an .nfo describes, in the form release file names
+// use: "Show S01E02 Episode Title" for episodes and "Title 2020" for movies.
+func (nfo kodiNFO) name() string {
+	if nfo.XMLName.Local == "episodedetails" {
+		return strings.TrimSpace(fmt.Sprintf("%s S%02dE%02d %s", nfo.ShowTitle, nfo.Season, nfo.Episode, nfo.Title))
+	}
+	if nfo.Year > 0 {
+		return fmt.Sprintf("%s %d", nfo.Title, nfo.Year)
+	}
+	return nfo.Title
+}
+
+// templateValues returns the fields of an .nfo by the names NFONaming
+// templates use. Numbers an .nfo does not give are left out.
+func (nfo kodiNFO) templateValues() map[string]any {
+	values := map[string]any{"title": nfo.Title, "showtitle": nfo.ShowTitle}
+	for field, number := range map[string]int{"year": nfo.Year, "season": nfo.Season, "episode": nfo.Episode} {
+		if number > 0 {
+			values[field] = number
+		}
+	}
+	return values
+}
+
+// readNFO parses the .nfo sidecar of a video: "<video name>.nfo", or for
+// movies "movie.nfo" in the same folder. Returns false when there is none or
+// it is not a Kodi XML .nfo (some only contain a scraper URL).
+func readNFO(fsys FileSystem, videoPath string) (kodiNFO, bool) {
+	for _, path := range []string{withExt(videoPath, ".nfo"), filepath.Join(filepath.Dir(videoPath), movieNFOName)} {
+		data, err := readFile(fsys, path)
+		if err != nil {
+			continue
+		}
+		var nfo kodiNFO
+		if err := xml.Unmarshal(data, &nfo); err != nil || nfo.Title == "" {
+			continue
+		}
+		switch nfo.XMLName.Local {
+		case "movie", "episodedetails":
+			return nfo, true
+		}
+	}
+	return kodiNFO{}, false
+}
+
+// nfoTitle returns the title of a video from its Kodi .nfo sidecar when
+// ReadNFO is enabled.
+func (vsm *VideoSubtitleMatcher) nfoTitle(videoPath string) string {
+	if vsm.nfoTitles == nil {
+		return ""
+	}
+	return vsm.nfoTitles.lookup(videoPath, func() string {
+		nfo, ok := readNFO(vsm.fs, videoPath)
+		if !ok {
+			return ""
+		}
+		return nfo.name()
+	})
+}
+
+// nfoFileNameReplacer replaces the path separators .nfo titles may contain.
+var nfoFileNameReplacer = strings.NewReplacer("/", "-", `\`, "-")
+
+// targetBaseName returns the name, without extension, that the subtitles of
+// a video are renamed after: the video's file name, or the NFONaming
+// template filled from its Kodi .nfo sidecar when it has one.
+func (vsm *VideoSubtitleMatcher) targetBaseName(videoPath string) string {
+	videoBaseName := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	if vsm.nfoNaming == nil {
+		return videoBaseName
+	}
+	nfo, ok := readNFO(vsm.fs, videoPath)
+	if !ok {
+		return videoBaseName
+	}
+	name := strings.TrimSpace(nfoFileNameReplacer.Replace(vsm.nfoNaming.expand(nfo.templateValues())))
+	if name == "" {
+		return videoBaseName
+	}
+	return name
+}
@@ -0,0 +1,51 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNFONaming(t *testing.T) {
+	const (
+		episode = `<episodedetails><title>Pilot</title><showtitle>The Show</showtitle><season>1</season><episode>2</episode></episodedetails>`
+		movie   = `<movie><title>Heat</title><year>1995</year></movie>`
+	)
+	tests := []struct {
+		name     string
+		template string
+		video    string
+		nfo      string
+		nfoName  string
+		subtitle string
+		want     string
+	}{
+		{"episode", "%(showtitle)s S%(season)02dE%(episode)02d %(title)s.%(ext)s", "show.s01e02.mkv", episode, "show.s01e02.nfo", "show.s01e02.srt", "The Show S01E02 Pilot.srt"},
+		{"movie", "%(title)s (%(year)d)", "heat.1995.mkv", movie, movieNFOName, "heat.1995.srt", "Heat (1995).srt"},
+		{"no year", "%(title)s (%(year)d)", "heat.mkv", `<movie><title>Heat</title></movie>`, movieNFOName, "heat.srt", "Heat ().srt"},
+		{"no nfo", "%(title)s (%(year)d)", "heat.1995.mkv", "", "", "heat.1995.srt", "heat.1995.srt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.video, tt.subtitle)
+			if tt.nfo != "" {
+				if err := os.WriteFile(filepath.Join(dir, tt.nfoName), []byte(tt.nfo), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			template, err := ParseOutputTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := New(dir, Verbose(false), NFONaming(template)).Match()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filepath.Base(matchOf(t, results, tt.subtitle).NewSubtitlePath); got != tt.want {
+				t.Errorf("new name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package subtitlematcher
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// numberWords maps Roman numerals and spelled-out numbers to their digit form.
+// Single-letter numerals (I, V, X) are deliberately left out because they are
+// far more often words or initials than numbers in titles.
+var numberWords = map[string]int{
+	"ii": 2, "iii": 3, "iv": 4, "vi": 6, "vii": 7, "viii": 8, "ix": 9,
+	"xi": 11, "xii": 12, "xiii": 13, "xiv": 14, "xv": 15, "xvi": 16,
+	"xvii": 17, "xviii": 18, "xix": 19, "xx": 20,
+
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15,
+	"sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	"twenty": 20,
+}
+
+// numberWordPattern matches whole words that may be a Roman numeral or a
+// spelled-out number. Candidates are checked against numberWords.
+var numberWordPattern = regexp.MustCompile(`\b[a-z]+\b`)
+
+// normalizeNumberWords replaces Roman numerals and spelled-out numbers in a
+// lowercased title with digits, so "Part Two" and "Part II" both become "part 2".
+func normalizeNumberWords(title string) string {
+	return numberWordPattern.ReplaceAllStringFunc(title, func(word string) string {
+		if n, ok := numberWords[word]; ok {
+			return strconv.Itoa(n)
+		}
+		return word
+	})
+}
+
+// cjkPunctuation maps CJK punctuation and brackets without a full-width ASCII
+// form to their ASCII counterparts.
+var cjkPunctuation = strings.NewReplacer(
+	"\u3000", " ", // Ideographic space
+	"、", ",", "。", ".", "·", " ", "・", " ",
+	"【", "[", "】", "]", "〔", "[", "〕", "]", "〖", "[", "〗", "]",
+	"「", "\"", "」", "\"", "『", "\"", "』", "\"",
+	"〈", "<", "〉", ">", "《", "<", "》", ">",
+	"“", "\"", "”", "\"", "‘", "'", "’", "'",
+	"〜", "~", "—", "-", "–", "-",
+)
+
+// normalizeWidth converts full-width forms (U+FF01 to U+FF5E, such as ？ and
+// （）) to half-width ASCII and CJK punctuation to its ASCII counterpart, so
+// titles that differ only in punctuation width compare equal.
+func normalizeWidth(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if r >= '！' && r <= '～' {
+			return r - '！' + '!'
+		}
+		return r
+	}, title)
+	return cjkPunctuation.Replace(title)
+}
+
+// stripSymbols replaces emoji and other symbols in a title with spaces,
+// along with the joiners, variation selectors and keycaps emoji are built
+// from, so "🔥 Best Moments 🔥" compares as "Best Moments". Letters, digits
+// and punctuation are kept.
+func stripSymbols(title string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.In(r, unicode.So, unicode.Cs, unicode.Co):
+			return ' '
+		case r >= '\U0001F3FB' && r <= '\U0001F3FF':
+			// Emoji skin tone modifiers; the other modifier symbols, such as
+			// ASCII ^ and `, are punctuation in titles
+			return -1
+		case r == '\u200d' || r == '\u20e3' || unicode.Is(unicode.Variation_Selector, r):
+			return -1
+		}
+		return r
+	}, title)
+}
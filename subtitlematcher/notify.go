@@ -0,0 +1,172 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// notifyTimeout bounds each notification request.
+const notifyTimeout = 10 * time.Second
+
+// Summary describes a finished run for notifications.
+type Summary struct {
+	Directory string        `json:"directory"` // Directory that was matched
+	DryRun    bool          `json:"dry_run"`   // Whether files were left untouched
+	Matched   int           `json:"matched"`   // Subtitles that met the threshold
+	Renamed   int           `json:"renamed"`   // Subtitles actually moved
+	Failed    int           `json:"failed"`    // Subtitles whose rename or processing failed
+	Results   []MatchResult `json:"results"`   // Results of the matched subtitles
+}
+
+// Notifier is told about finished runs, e.g. to alert a user running the
+// matcher headless that new episodes were matched.
+type Notifier interface {
+	Notify(ctx context.Context, summary Summary) error
+}
+
+// summarize builds the notification summary of a run.
+func (vsm *VideoSubtitleMatcher) summarize(results []MatchResult) Summary {
+	summary := Summary{Directory: vsm.directory, DryRun: vsm.dryRun}
+	for _, result := range results {
+		if result.NewSubtitlePath == "" {
+			continue
+		}
+		summary.Matched++
+		summary.Results = append(summary.Results, result)
+		if result.Error != nil {
+			summary.Failed++
+		} else if result.Renamed && result.SubtitlePath != result.NewSubtitlePath {
+			summary.Renamed++
+		}
+	}
+	return summary
+}
+
+// notify sends the run summary to every notifier when at least one subtitle
+// matched, so periodic runs that find nothing new stay quiet.
+func (vsm *VideoSubtitleMatcher) notify(ctx context.Context, results []MatchResult) error {
+	if len(vsm.notifiers) == 0 {
+		return nil
+	}
+
+	summary := vsm.summarize(results)
+	if summary.Matched == 0 {
+		return nil
+	}
+
+	for _, notifier := range vsm.notifiers {
+		if err := notifier.Notify(ctx, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Text renders the summary as a short human-readable message.
+func (s Summary) Text() string {
+	var b strings.Builder
+	if s.DryRun {
+		fmt.Fprintf(&b, "%d subtitles would be renamed in %s", s.Matched, s.Directory)
+	} else {
+		fmt.Fprintf(&b, "Renamed %d subtitles in %s", s.Renamed, s.Directory)
+		if s.Failed > 0 {
+			fmt.Fprintf(&b, " (%d failed)", s.Failed)
+		}
+	}
+	for _, result := range s.Results {
+		fmt.Fprintf(&b, "\n%s -> %s", filepath.Base(result.SubtitlePath), filepath.Base(result.NewSubtitlePath))
+	}
+	return b.String()
+}
+
+// WebhookNotifier POSTs the summary as JSON to a URL.
+type WebhookNotifier struct {
+	URL string
+}
+
+// NewWebhookNotifier returns a notifier that POSTs summaries to url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url}
+}
+
+// Notify sends the summary.
+func (n *WebhookNotifier) Notify(ctx context.Context, summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return post(ctx, n.URL, "application/json", bytes.NewReader(body), nil)
+}
+
+// NtfyNotifier publishes the summary text to an ntfy topic.
+type NtfyNotifier struct {
+	TopicURL string // e.g. "https://ntfy.sh/my-subtitles"
+}
+
+// NewNtfyNotifier returns a notifier that publishes to the ntfy topic URL.
+func NewNtfyNotifier(topicURL string) *NtfyNotifier {
+	return &NtfyNotifier{TopicURL: topicURL}
+}
+
+// Notify sends the summary.
+func (n *NtfyNotifier) Notify(ctx context.Context, summary Summary) error {
+	headers := map[string]string{"Title": "Subtitles matched"}
+	return post(ctx, n.TopicURL, "text/plain; charset=utf-8", strings.NewReader(summary.Text()), headers)
+}
+
+// TelegramNotifier sends the summary text through a Telegram bot.
+type TelegramNotifier struct {
+	Token  string // Bot token from @BotFather
+	ChatID string // Chat to send messages to
+}
+
+// NewTelegramNotifier returns a notifier that messages chatID as the bot with the given token.
+func NewTelegramNotifier(token, chatID string) *TelegramNotifier {
+	return &TelegramNotifier{Token: token, ChatID: chatID}
+}
+
+// Notify sends the summary.
+func (n *TelegramNotifier) Notify(ctx context.Context, summary Summary) error {
+	form := url.Values{"chat_id": {n.ChatID}, "text": {summary.Text()}}
+	endpoint := "https://api.telegram.org/bot" + n.Token + "/sendMessage"
+	return post(ctx, endpoint, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), nil)
+}
+
+// post sends a POST request and fails on any non-2xx response.
+func post(ctx context.Context, endpoint, contentType string, body io.Reader, headers map[string]string) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Avoid leaking credentials embedded in the URL, such as bot tokens
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification rejected: %s", resp.Status)
+	}
+	return nil
+}
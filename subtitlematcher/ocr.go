@@ -0,0 +1,253 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tesseractPath is the Tesseract executable used to read image-based subtitles.
+const tesseractPath = "tesseract"
+
+// ocrPadding is the white margin added around each bitmap, as Tesseract
+// misreads text touching the edge of an image.
+const ocrPadding = 10
+
+// tesseractLanguages maps ISO 639-1 codes to Tesseract language names.
+var tesseractLanguages = map[string]string{
+	"en": "eng", "zh": "chi_sim", "ja": "jpn", "ko": "kor", "fr": "fra",
+	"de": "deu", "es": "spa", "it": "ita", "pt": "por", "ru": "rus",
+	"ar": "ara", "nl": "nld", "sv": "swe", "pl": "pol", "tr": "tur",
+	"vi": "vie", "th": "tha",
+}
+
+// bitmapCue is a cue of an image-based subtitle, drawn as dark text on a
+// white background for OCR.
+type bitmapCue struct {
+	Start time.Duration
+	End   time.Duration
+	Image *image.Gray
+}
+
+// blankBitmap returns a white image of the given size.
+func blankBitmap(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+// textGray returns the gray level a subtitle pixel is drawn with: bright,
+// opaque text turns dark, while outlines, shadows and transparent pixels
+// turn light, which is what Tesseract reads best.
+func textGray(luma, alpha uint8) uint8 {
+	return 255 - uint8(int(luma)*int(alpha)/255)
+}
+
+// isOCRSource reports whether a subtitle is an image format OCR can read.
+func isOCRSource(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".sup" || ext == vobSubIndexExt
+}
+
+// decodeImageSubtitle decodes the cues of a PGS or VobSub subtitle, along
+// with the language a VobSub index declares.
+func decodeImageSubtitle(fsys FileSystem, path string) ([]bitmapCue, string, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isVobSubIndex(path) {
+		cues, err := decodePGS(data)
+		return cues, "", err
+	}
+
+	sub, err := readFile(fsys, withExt(path, vobSubDataExt))
+	if err != nil {
+		return nil, "", err
+	}
+	return decodeVobSub(data, sub)
+}
+
+// recognizeCues reads the text of each cue with a single Tesseract run over
+// a list of their bitmaps. Tesseract ends the text of every image with a
+// form feed.
+func recognizeCues(ctx context.Context, cues []bitmapCue, language string) ([][]string, error) {
+	dir, err := os.MkdirTemp("", "subtitle-ocr-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var list strings.Builder
+	for i, cue := range cues {
+		path := filepath.Join(dir, fmt.Sprintf("%05d.png", i))
+		if err := writePaddedPNG(path, cue.Image); err != nil {
+			return nil, err
+		}
+		list.WriteString(path + "\n")
+	}
+	listPath := filepath.Join(dir, "images.txt")
+	if err := os.WriteFile(listPath, []byte(list.String()), 0o644); err != nil {
+		return nil, err
+	}
+
+	out, err := exec.CommandContext(ctx, tesseractPath, listPath, "stdout", "-l", language, "--psm", "6").Output()
+	if err != nil {
+		return nil, fmt.Errorf("tesseract failed: %w", err)
+	}
+	pages := strings.Split(string(out), "\f")
+	if len(pages) < len(cues) {
+		return nil, fmt.Errorf("tesseract read %d of %d images", len(pages), len(cues))
+	}
+
+	texts := make([][]string, len(cues))
+	for i := range cues {
+		for _, line := range strings.Split(pages[i], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				texts[i] = append(texts[i], line)
+			}
+		}
+	}
+	return texts, nil
+}
+
+// writePaddedPNG writes img as a PNG file with a white margin around it.
+func writePaddedPNG(path string, img *image.Gray) error {
+	bounds := img.Bounds()
+	padded := blankBitmap(bounds.Dx()+2*ocrPadding, bounds.Dy()+2*ocrPadding)
+	draw.Draw(padded, bounds.Add(image.Pt(ocrPadding, ocrPadding)), img, bounds.Min, draw.Src)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, padded); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// tesseractLanguage picks the Tesseract language a subtitle is read in: the
+// language of its name, else the one its VobSub index declares, else the
+// configured one.
+func (vsm *VideoSubtitleMatcher) tesseractLanguage(result MatchResult, declared string) string {
+	if language, ok := tesseractLanguages[result.Language]; ok {
+		return language
+	}
+	if language, ok := tesseractLanguages[languageCodes[strings.ToLower(declared)]]; ok {
+		return language
+	}
+	return vsm.ocrLanguage
+}
+
+// ocrSubtitle reads a matched image-based subtitle into a text .srt named
+// like it, keeping the image subtitle. Names that exist or are planned for
+// other subtitles are never overwritten. In dry run mode the file that would
+// be written is reported but not created.
+func (vsm *VideoSubtitleMatcher) ocrSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	source := result.SubtitlePath
+	if result.Renamed {
+		source = result.NewSubtitlePath
+	}
+	if !isOCRSource(source) || result.NewSubtitlePath == "" || result.DuplicateOf != "" {
+		return result
+	}
+
+	target := withExt(result.NewSubtitlePath, ".srt")
+	if _, err := vsm.fs.Stat(target); err == nil || vsm.plannedNames[target] {
+		if vsm.verbose {
+			vsm.printf(colorGray, "  Skipping OCR: %s already exists\n", filepath.Base(target))
+		}
+		return result
+	}
+
+	if vsm.dryRun {
+		result.OCRSubtitlePath = target
+		vsm.logOCR(result, 0)
+		return result
+	}
+
+	cues, declared, err := decodeImageSubtitle(vsm.fs, source)
+	if err == nil && len(cues) == 0 {
+		err = fmt.Errorf("no cues found")
+	}
+	var texts [][]string
+	if err == nil {
+		texts, err = recognizeCues(ctx, cues, vsm.tesseractLanguage(result, declared))
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("failed to OCR subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error reading subtitle images: %v\n", err)
+		}
+		return result
+	}
+
+	var srtCues []srtCue
+	for i, cue := range cues {
+		if len(texts[i]) > 0 {
+			srtCues = append(srtCues, srtCue{Start: cue.Start, End: cue.End, Lines: texts[i]})
+		}
+	}
+	if err := vsm.fs.WriteFile(target, []byte(formatSRT(srtCues, "\n"))); err != nil {
+		result.Error = fmt.Errorf("failed to OCR subtitle: %w", err)
+		return result
+	}
+
+	result.OCRSubtitlePath = target
+	vsm.logOCR(result, len(srtCues))
+	return result
+}
+
+// logOCR logs the text subtitle read from an image-based subtitle
+func (vsm *VideoSubtitleMatcher) logOCR(result MatchResult, cues int) {
+	if !vsm.verbose {
+		return
+	}
+
+	if vsm.dryRun {
+		fmt.Printf("  Would read text into: %s\n", filepath.Base(result.OCRSubtitlePath))
+		return
+	}
+	vsm.printf(colorGreen, "  ✓ Read %d cues into: %s\n", cues, filepath.Base(result.OCRSubtitlePath))
+}
+
+// subtitleNames is a set of subtitle paths.
+type subtitleNames map[string]bool
+
+// plannedSubtitleNames returns the new names of the planned subtitles.
+func plannedSubtitleNames(planned []MatchResult) subtitleNames {
+	names := make(subtitleNames, len(planned))
+	names.addPlanned(planned)
+	return names
+}
+
+// addPlanned adds the new names of the planned subtitles.
+func (names subtitleNames) addPlanned(planned []MatchResult) {
+	for _, result := range planned {
+		if result.NewSubtitlePath != "" {
+			names[result.NewSubtitlePath] = true
+		}
+	}
+}
+
+// outputTaken returns an error when a subtitle the run writes besides its
+// renames, such as a merged or joined one, would replace an existing file or
+// a subtitle planned for that name.
+func (vsm *VideoSubtitleMatcher) outputTaken(path string) error {
+	if exists(vsm.fs, path) {
+		return fmt.Errorf("%s already exists", filepath.Base(path))
+	}
+	if vsm.plannedNames[path] {
+		return fmt.Errorf("%s is planned for another subtitle", filepath.Base(path))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package subtitlematcher
+
+// MatchPass names the matching pass that paired a subtitle with its video.
+// Cheap, unambiguous passes run first; fuzzy scoring only sees the subtitles
+// they leave over.
+type MatchPass string
+
+const (
+	// PassID matched a subtitle containing the same video ID as the video,
+	// e.g. "[dQw4w9WgXcQ]" or "BV1xx411c7mD", or the same id field of the
+	// yt-dlp output template.
+	PassID MatchPass = "id"
+	// PassExact matched a subtitle whose name equals the video's name.
+	PassExact MatchPass = "exact"
+	// PassNormalized matched a subtitle whose normalized title equals the
+	// video's, e.g. "my_show_s01e02" and "My Show S01E2".
+	PassNormalized MatchPass = "normalized"
+	// PassIndex matched a subtitle whose leading playlist index, e.g. "001 -"
+	// or "1.", equals that of the only numbered video in its directory.
+	PassIndex MatchPass = "index"
+	// PassFuzzy matched a subtitle by similarity score.
+	PassFuzzy MatchPass = "fuzzy"
+	// PassSemantic matched a subtitle whose title means the same as the
+	// video's, such as a translation of it, by comparing their embeddings.
+	// It only runs for subtitles fuzzy scoring leaves below the threshold.
+	PassSemantic MatchPass = "semantic"
+)
+
+// exactMatch runs the ID, exact, normalized and index passes: it returns the
+// only video whose ID, or else whose name, or else whose normalized title, or
+// else whose playlist index in the same directory, equals the subtitle's.
+// Returns "" when no video or several videos qualify, leaving the subtitle to
+// fuzzy scoring.
+func (vsm *VideoSubtitleMatcher) exactMatch(subtitlePath string, videoFiles []string) (string, MatchPass) {
+	title := subtitleTitle(subtitlePath)
+	if id := vsm.titleID(title); id != "" {
+		if video, ok := uniqueVideo(videoFiles, func(videoPath string) bool {
+			return vsm.videoID(videoPath) == id
+		}); ok {
+			return video, PassID
+		}
+	}
+
+	if video, ok := uniqueVideo(videoFiles, func(videoPath string) bool {
+		return titleOf(videoPath) == title
+	}); ok {
+		return video, PassExact
+	}
+
+	normalized := vsm.normalizeTitle(title)
+	if video, ok := uniqueVideo(videoFiles, func(videoPath string) bool {
+		return vsm.normalizedVideo(videoPath) == normalized
+	}); ok {
+		return video, PassNormalized
+	}
+
+	if video, ok := indexMatch(subtitlePath, title, videoFiles); ok {
+		return video, PassIndex
+	}
+	return "", ""
+}
+
+// uniqueVideo returns the video satisfying match when exactly one does.
+func uniqueVideo(videoFiles []string, match func(string) bool) (string, bool) {
+	found := ""
+	for _, videoPath := range videoFiles {
+		if !match(videoPath) {
+			continue
+		}
+		if found != "" {
+			return "", false
+		}
+		found = videoPath
+	}
+	return found, found != ""
+}
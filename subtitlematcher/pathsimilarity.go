@@ -0,0 +1,105 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// contextDirLevels is the number of parent directories (e.g. show and season)
+// included in path-aware comparisons.
+const contextDirLevels = 2
+
+// directoryContext returns the normalized names of up to contextDirLevels
+// parent directories of path below the scanned root, joined by spaces.
+func (vsm *VideoSubtitleMatcher) directoryContext(path string) string {
+	rel, err := filepath.Rel(vsm.directory, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	dirs := strings.Split(filepath.ToSlash(rel), "/")
+	if len(dirs) > contextDirLevels {
+		dirs = dirs[len(dirs)-contextDirLevels:]
+	}
+	for i, dir := range dirs {
+		dirs[i] = vsm.normalizeTitle(dir)
+	}
+	return strings.Join(dirs, " ")
+}
+
+// pathSimilarity compares two titles together with their directory context.
+// Characters from directory names weigh directoryWeight while characters from
+// file names weigh 1, so a bare "E01.mkv" inside "Show/Season 01" can still
+// match "Show S01E01.srt" without directories dominating the score.
+//
+// Returns a score between 0.0 (no similarity) and 1.0 (identical).
+func (vsm *VideoSubtitleMatcher) pathSimilarity(dirs1, name1, dirs2, name2 string) float64 {
+	s1, w1 := weightedTitle(dirs1, name1, vsm.directoryWeight)
+	s2, w2 := weightedTitle(dirs2, name2, vsm.directoryWeight)
+
+	total1, total2 := sumWeights(w1), sumWeights(w2)
+	maxTotal := total1
+	if total2 > maxTotal {
+		maxTotal = total2
+	}
+	if maxTotal == 0 {
+		return 0.0
+	}
+
+	return weightedLCS(s1, w1, s2, w2) / maxTotal
+}
+
+// weightedTitle joins directory context and file name into one comparison
+// string and returns it with a per-byte weight.
+func weightedTitle(dirs, name string, dirWeight float64) (string, []float64) {
+	if dirs == "" {
+		return name, uniformWeights(len(name), 1)
+	}
+	prefix := dirs + " "
+	return prefix + name, append(uniformWeights(len(prefix), dirWeight), uniformWeights(len(name), 1)...)
+}
+
+// uniformWeights returns n copies of weight.
+func uniformWeights(n int, weight float64) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = weight
+	}
+	return weights
+}
+
+// sumWeights adds up a list of weights.
+func sumWeights(weights []float64) float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	return total
+}
+
+// weightedLCS computes the maximum total weight of a common subsequence of
+// two strings, where each matched pair counts the average of its two weights.
+func weightedLCS(s1 string, w1 []float64, s2 string, w2 []float64) float64 {
+	m, n := len(s1), len(s2)
+	dp := make([][]float64, m+1)
+	for i := range dp {
+		dp[i] = make([]float64, n+1)
+	}
+
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			best := dp[i-1][j]
+			if dp[i][j-1] > best {
+				best = dp[i][j-1]
+			}
+			if s1[i-1] == s2[j-1] {
+				if matched := dp[i-1][j-1] + (w1[i-1]+w2[j-1])/2; matched > best {
+					best = matched
+				}
+			}
+			dp[i][j] = best
+		}
+	}
+
+	return dp[m][n]
+}
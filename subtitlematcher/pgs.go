@@ -0,0 +1,194 @@
+package subtitlematcher
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"time"
+)
+
+// PGS segment types. A display set is a composition segment, followed by the
+// window, palette and object segments it uses, and ends with an end segment.
+const (
+	pgsPalette     = 0x14
+	pgsObject      = 0x15
+	pgsComposition = 0x16
+	pgsWindow      = 0x17
+	pgsEnd         = 0x80
+)
+
+// pgsHeaderSize is the size of a segment header: "PG", PTS, DTS, type and size.
+const pgsHeaderSize = 13
+
+// pgsClock is the frequency of PGS and MPEG presentation timestamps.
+const pgsClock = 90000
+
+// errNotPGS is returned for data that does not start with a PGS segment.
+var errNotPGS = errors.New("not a PGS subtitle")
+
+// pgsPlacement is an object shown by a composition, at its position on screen.
+type pgsPlacement struct {
+	object int
+	x, y   int
+}
+
+// pgsObjectData is an object's run-length encoded bitmap, which may be split
+// over several object segments.
+type pgsObjectData struct {
+	width, height int
+	rle           []byte
+}
+
+// decodePGS decodes the cues of a PGS (.sup) subtitle as bitmaps of dark
+// text on a white background. A cue lasts from the composition showing it
+// until the next composition, which clears or replaces it.
+func decodePGS(data []byte) ([]bitmapCue, error) {
+	if len(data) < 2 || data[0] != 'P' || data[1] != 'G' {
+		return nil, errNotPGS
+	}
+
+	var (
+		cues       []bitmapCue
+		open       bool
+		palette    [256]uint8 // Gray level for each palette entry, text dark
+		objects    = map[int]*pgsObjectData{}
+		placements []pgsPlacement
+		start      time.Duration
+	)
+	for i := range palette {
+		palette[i] = 255 // Entries never defined are transparent
+	}
+	for len(data) >= pgsHeaderSize {
+		if data[0] != 'P' || data[1] != 'G' {
+			return cues, errors.New("corrupt PGS segment")
+		}
+		pts := time.Duration(binary.BigEndian.Uint32(data[2:])) * time.Second / pgsClock
+		kind := data[10]
+		size := int(binary.BigEndian.Uint16(data[11:]))
+		if len(data) < pgsHeaderSize+size {
+			return cues, errors.New("truncated PGS segment")
+		}
+		segment := data[pgsHeaderSize : pgsHeaderSize+size]
+		data = data[pgsHeaderSize+size:]
+
+		switch kind {
+		case pgsComposition:
+			if len(segment) < 11 {
+				continue
+			}
+			if open {
+				cues[len(cues)-1].End = pts
+				open = false
+			}
+			start = pts
+			placements = placements[:0]
+			count := int(segment[10])
+			for i, p := 0, segment[11:]; i < count && len(p) >= 8; i++ {
+				placements = append(placements, pgsPlacement{
+					object: int(binary.BigEndian.Uint16(p)),
+					x:      int(binary.BigEndian.Uint16(p[4:])),
+					y:      int(binary.BigEndian.Uint16(p[6:])),
+				})
+				if p[3]&0x40 != 0 && len(p) >= 16 {
+					p = p[16:] // Skip the cropping rectangle
+				} else {
+					p = p[8:]
+				}
+			}
+		case pgsPalette:
+			for p := segment[min(2, len(segment)):]; len(p) >= 5; p = p[5:] {
+				palette[p[0]] = textGray(p[1], p[4])
+			}
+		case pgsObject:
+			if len(segment) < 4 {
+				continue
+			}
+			id := int(binary.BigEndian.Uint16(segment))
+			if segment[3]&0x80 != 0 { // First in sequence
+				if len(segment) < 11 {
+					continue
+				}
+				objects[id] = &pgsObjectData{
+					width:  int(binary.BigEndian.Uint16(segment[7:])),
+					height: int(binary.BigEndian.Uint16(segment[9:])),
+					rle:    append([]byte(nil), segment[11:]...),
+				}
+			} else if object := objects[id]; object != nil {
+				object.rle = append(object.rle, segment[4:]...)
+			}
+		case pgsEnd:
+			if len(placements) == 0 {
+				continue
+			}
+			if img := composePGS(placements, objects, &palette); img != nil {
+				cues = append(cues, bitmapCue{Start: start, End: start, Image: img})
+				open = true
+			}
+			placements = placements[:0]
+		}
+	}
+	return cues, nil
+}
+
+// composePGS draws the objects of a composition into one image covering them
+// all, or returns nil when none of them is known.
+func composePGS(placements []pgsPlacement, objects map[int]*pgsObjectData, palette *[256]uint8) *image.Gray {
+	var bounds image.Rectangle
+	for _, placement := range placements {
+		if object := objects[placement.object]; object != nil {
+			bounds = bounds.Union(image.Rect(placement.x, placement.y,
+				placement.x+object.width, placement.y+object.height))
+		}
+	}
+	if bounds.Empty() {
+		return nil
+	}
+
+	img := blankBitmap(bounds.Dx(), bounds.Dy())
+	for _, placement := range placements {
+		object := objects[placement.object]
+		if object == nil {
+			continue
+		}
+		decodePGSRLE(object.rle, object.width, object.height, func(x, y int, entry uint8) {
+			img.SetGray(placement.x-bounds.Min.X+x, placement.y-bounds.Min.Y+y, color.Gray{Y: palette[entry]})
+		})
+	}
+	return img
+}
+
+// decodePGSRLE decodes a PGS run-length encoded bitmap, calling set for every
+// pixel with its palette entry. Lines end with a zero-length run.
+func decodePGSRLE(rle []byte, width, height int, set func(x, y int, entry uint8)) {
+	x, y := 0, 0
+	for i := 0; i < len(rle) && y < height; {
+		entry, run := rle[i], 1
+		i++
+		if entry == 0 {
+			if i >= len(rle) {
+				break
+			}
+			flags := rle[i]
+			i++
+			switch {
+			case flags == 0: // End of line
+				x, y = 0, y+1
+				continue
+			case flags&0x40 == 0:
+				run = int(flags & 0x3F)
+			case i < len(rle):
+				run = int(flags&0x3F)<<8 | int(rle[i])
+				i++
+			}
+			if flags&0x80 != 0 && i < len(rle) {
+				entry = rle[i]
+				i++
+			}
+		}
+		for ; run > 0 && x < width; run-- {
+			set(x, y, entry)
+			x++
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package subtitlematcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Plan is the renames of a dry run in a form that can be saved, reviewed or
+// edited by hand, and executed later with Apply.
+type Plan struct {
+	Directory string      `json:"directory"` // Directory the plan was made for
+	Created   time.Time   `json:"created"`   // When the plan was made
+	Renames   []PlanEntry `json:"renames"`   // Renames to perform, in order
+}
+
+// PlanEntry is one planned rename. Only Subtitle and Target are required;
+// the other fields are carried over from the match so that applying the plan
+// performs the rename the same way the run would have.
+type PlanEntry struct {
+	Subtitle   string     `json:"subtitle"`             // Subtitle to rename
+	Target     string     `json:"target"`               // New path of the subtitle
+	Video      string     `json:"video,omitempty"`      // Video the subtitle was matched to
+	Similarity float64    `json:"similarity,omitempty"` // Similarity score of the match
+	Confidence Confidence `json:"confidence"`           // How trustworthy the match is
+	Paired     string     `json:"paired,omitempty"`     // VobSub .sub file moved along with the subtitle
+	Archive    string     `json:"archive,omitempty"`    // Archive the subtitle is extracted from
+	Format     string     `json:"format,omitempty"`     // Subtitle format detected from content
+}
+
+// NewPlan builds the plan of the renames in results. Unmatched, invalid,
+// duplicate, held and already correctly named subtitles are omitted.
+func NewPlan(directory string, results []MatchResult) *Plan {
+	return newPlan(directory, results, willRename)
+}
+
+// newPlan builds the plan of the renames in results selected by include.
+func newPlan(directory string, results []MatchResult, include func(MatchResult) bool) *Plan {
+	plan := &Plan{Directory: directory, Created: time.Now().UTC(), Renames: []PlanEntry{}}
+	for _, result := range results {
+		if !include(result) {
+			continue
+		}
+		plan.Renames = append(plan.Renames, PlanEntry{
+			Subtitle:   result.SubtitlePath,
+			Target:     result.NewSubtitlePath,
+			Video:      result.VideoPath,
+			Similarity: result.Similarity,
+			Confidence: result.Confidence,
+			Paired:     result.PairedPath,
+			Archive:    result.Archive,
+			Format:     result.Format,
+		})
+	}
+	return plan
+}
+
+// WritePlan writes plan as indented JSON.
+func WritePlan(w io.Writer, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadPlan reads a plan written by WritePlan, possibly edited since. Unknown
+// fields are rejected so that misspelled edits are not silently ignored.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var plan Plan
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	for i, entry := range plan.Renames {
+		if entry.Subtitle == "" || entry.Target == "" {
+			return nil, fmt.Errorf("invalid plan: rename %d needs both a subtitle and a target", i+1)
+		}
+	}
+	return &plan, nil
+}
+
+// Apply performs exactly the renames in plan, without scanning or matching,
+// in the plan's directory (or the matcher's, if the plan names none). The
+// matcher's options still apply: dry runs only report what would be done,
+// and processing such as repairs, conversions and run history works as in
+// MatchContext. Failed renames are reported in their results and in a
+// returned *MultiError.
+func (vsm *VideoSubtitleMatcher) Apply(ctx context.Context, plan *Plan) ([]MatchResult, error) {
+	started := time.Now()
+
+	vsm = vsm.Clone()
+	if plan.Directory != "" {
+		vsm.directory = plan.Directory
+	}
+
+	ctx, cancel := vsm.runContext(ctx)
+	defer cancel()
+
+	if err := vsm.checkRoot(); err != nil {
+		return nil, err
+	}
+
+	lock, err := vsm.lockRun()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	planned, err := vsm.plannedResults(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vsm.checkPlannedPaths(planned); err != nil {
+		return nil, err
+	}
+
+	if err := vsm.checkOperationLimit(planned); err != nil {
+		return nil, err
+	}
+
+	if err := vsm.runPreflight(planned); err != nil {
+		return nil, err
+	}
+
+	vsm.plannedNames = plannedSubtitleNames(planned)
+	return vsm.execute(ctx, started, planned, nil)
+}
+
+// plannedResults turns the entries of a plan into results ready to execute,
+// registering the archives subtitles are extracted from.
+func (vsm *VideoSubtitleMatcher) plannedResults(ctx context.Context, plan *Plan) ([]MatchResult, error) {
+	registered := make(map[string]map[string]bool)
+	planned := make([]MatchResult, 0, len(plan.Renames))
+
+	for _, entry := range plan.Renames {
+		if entry.Archive != "" {
+			if _, ok := registered[entry.Archive]; !ok {
+				entries, err := vsm.registerArchive(ctx, entry.Archive)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read archive %s: %w", entry.Archive, err)
+				}
+				registered[entry.Archive] = entries
+			}
+			if !registered[entry.Archive][entry.Subtitle] {
+				return nil, fmt.Errorf("%s is not a subtitle in %s", entry.Subtitle, entry.Archive)
+			}
+		}
+
+		planned = append(planned, MatchResult{
+			SubtitlePath:    entry.Subtitle,
+			VideoPath:       entry.Video,
+			NewSubtitlePath: entry.Target,
+			Similarity:      entry.Similarity,
+			Confidence:      entry.Confidence,
+			PairedPath:      entry.Paired,
+			Archive:         entry.Archive,
+			Format:          entry.Format,
+		})
+	}
+	return planned, nil
+}
+
+// registerArchive makes the subtitles in an archive available for
+// extraction, even when the matcher does not extract archives itself.
+func (vsm *VideoSubtitleMatcher) registerArchive(ctx context.Context, archivePath string) (map[string]bool, error) {
+	archiveFS, ok := vsm.fs.(*archiveFileSystem)
+	if !ok {
+		archiveFS = newArchiveFileSystem(vsm.fs)
+		vsm.fs = archiveFS
+	}
+
+	paths, err := archiveFS.register(ctx, archivePath, vsm.isSubtitleName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		entries[path] = true
+	}
+	return entries, nil
+}
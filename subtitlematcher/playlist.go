@@ -0,0 +1,40 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// playlistIndexPattern matches the playlist index downloaders put before the
+// title of course and playlist videos, e.g. "001 - Title", "1. Title",
+// "01_Title" or "[01] Title". The separator is required so that titles
+// starting with a number, such as "2001 A Space Odyssey" or "12.5 Percent",
+// are not mistaken for an index.
+var playlistIndexPattern = regexp.MustCompile(`^\s*(?:\[(\d{1,4})\]|(\d{1,4})\s*(?:[-._)]|\s-))(?:\s*[^\s\d]|\s+\d)`)
+
+// playlistIndex returns the leading playlist index of a file title.
+func playlistIndex(title string) (int, bool) {
+	match := playlistIndexPattern.FindStringSubmatch(title)
+	if match == nil {
+		return 0, false
+	}
+	digits := match[1] + match[2]
+	index, err := strconv.Atoi(digits)
+	return index, err == nil
+}
+
+// indexMatch returns the only video in the subtitle's directory whose
+// playlist index equals the subtitle's. Titles of numbered downloads are
+// often truncated or translated, while their indices still line up.
+func indexMatch(subtitlePath, title string, videoFiles []string) (string, bool) {
+	index, ok := playlistIndex(title)
+	if !ok {
+		return "", false
+	}
+	dir := subtitleDir(subtitlePath)
+	return uniqueVideo(videoFiles, func(videoPath string) bool {
+		videoIndex, ok := playlistIndex(titleOf(videoPath))
+		return ok && videoIndex == index && filepath.Dir(videoPath) == dir
+	})
+}
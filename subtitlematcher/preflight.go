@@ -0,0 +1,193 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// PreflightMode controls what happens when checking a plan before execution
+// finds renames that would fail or destroy files.
+type PreflightMode int
+
+const (
+	// PreflightWarn reports every problem before renaming, then renames anyway.
+	PreflightWarn PreflightMode = iota
+	// PreflightAbort reports every problem and renames nothing.
+	PreflightAbort
+	// PreflightOff skips the checks.
+	PreflightOff
+)
+
+// preflightProbePattern names the empty files created to test whether a
+// directory is writable.
+const preflightProbePattern = ".subtitle-matcher-preflight-*"
+
+// PreflightError is returned by runs aborted by the pre-flight checks.
+// No files are renamed when it is returned.
+type PreflightError struct {
+	Problems []string // One human-readable description per problem
+}
+
+// Error returns a report listing every problem.
+func (e *PreflightError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pre-flight check: %d problem(s), nothing renamed:", len(e.Problems))
+	for _, problem := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(problem)
+	}
+	return b.String()
+}
+
+// move is a directory pair files are renamed between.
+type move struct {
+	from, to string
+}
+
+// preflightCheck validates a plan before anything is renamed: targets that
+// would replace existing files or are shared by several subtitles, and, on
+// local disk, directories that cannot be written to or moved between.
+func (vsm *VideoSubtitleMatcher) preflightCheck(results []MatchResult) []string {
+	var problems []string
+	sources := make(map[string]bool)
+	targets := make(map[string][]string)
+	moves := make(map[move]int)
+
+	for _, result := range results {
+		if willRename(result) {
+			sources[result.SubtitlePath] = true
+		}
+	}
+
+	for _, result := range results {
+		if !willRename(result) {
+			continue
+		}
+
+		targets[result.NewSubtitlePath] = append(targets[result.NewSubtitlePath], result.SubtitlePath)
+		if !sources[result.NewSubtitlePath] && exists(vsm.fs, result.NewSubtitlePath) {
+			problems = append(problems, fmt.Sprintf("%s: target %s already exists and would be replaced",
+				filepath.Base(result.SubtitlePath), result.NewSubtitlePath))
+		}
+
+		// Extracting from an archive only writes the target directory
+		from := filepath.Dir(result.SubtitlePath)
+		if result.Archive != "" {
+			from = filepath.Dir(result.NewSubtitlePath)
+		}
+		moves[move{from, filepath.Dir(result.NewSubtitlePath)}]++
+	}
+
+	problems = append(problems, describeShared(targets, "target %s is planned for %d subtitles: %s")...)
+
+	if vsm.isLocal() {
+		problems = append(problems, probeMoves(moves)...)
+	}
+	return problems
+}
+
+// runPreflight checks a plan that is about to be executed, returning a
+// *PreflightError when the checks abort the run.
+func (vsm *VideoSubtitleMatcher) runPreflight(planned []MatchResult) error {
+	if vsm.preflight == PreflightOff || vsm.dryRun {
+		return nil
+	}
+
+	problems := vsm.preflightCheck(planned)
+	if len(problems) == 0 {
+		return nil
+	}
+	if vsm.preflight == PreflightAbort {
+		return &PreflightError{Problems: problems}
+	}
+	vsm.logPreflight(problems)
+	return nil
+}
+
+// willRename reports whether executing a result moves or writes a file.
+func willRename(result MatchResult) bool {
+	if result.NewSubtitlePath == "" || result.Invalid || result.Held || result.DuplicateOf != "" {
+		return false
+	}
+	return result.SubtitlePath != result.NewSubtitlePath || result.Archive != ""
+}
+
+// probeMoves tries each move with an empty file, reporting the moves that
+// fail, sorted by directory.
+func probeMoves(moves map[move]int) []string {
+	keys := make([]move, 0, len(moves))
+	for m := range moves {
+		keys = append(keys, m)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].from != keys[j].from {
+			return keys[i].from < keys[j].from
+		}
+		return keys[i].to < keys[j].to
+	})
+
+	var problems []string
+	for _, m := range keys {
+		if err := probeMove(m); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%d subtitle(s))", err, moves[m]))
+		}
+	}
+	return problems
+}
+
+// probeMove creates an empty file in the source directory and renames it
+// into the target directory, which fails the same way the real renames would.
+func probeMove(m move) error {
+	probe, err := os.CreateTemp(m.from, preflightProbePattern)
+	if err != nil {
+		return describeWriteError(m.from, err)
+	}
+	probe.Close()
+	path := probe.Name()
+	defer func() { os.Remove(path) }()
+
+	if m.to == m.from {
+		return nil
+	}
+
+	moved := filepath.Join(m.to, filepath.Base(path))
+	if err := os.Rename(path, moved); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("%s and %s are on different devices and cannot be renamed between", m.from, m.to)
+		}
+		return describeWriteError(m.to, err)
+	}
+	path = moved
+	return nil
+}
+
+// describeWriteError explains why a directory could not be written to.
+func describeWriteError(dir string, err error) error {
+	switch {
+	case errors.Is(err, syscall.EROFS):
+		return fmt.Errorf("%s is on a read-only file system", dir)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("no permission to write to %s", dir)
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s does not exist", dir)
+	default:
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+}
+
+// logPreflight prints the problems found by the pre-flight checks.
+func (vsm *VideoSubtitleMatcher) logPreflight(problems []string) {
+	if vsm.verbose {
+		fmt.Printf("Pre-flight check found %d problem(s):\n", len(problems))
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,88 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TitlePreset names the platform-specific naming noise removed from
+// titles before they are compared. The remaining normalization (underscores,
+// case, zero padding, number words) applies with every preset.
+type TitlePreset string
+
+const (
+	// PresetYouTube removes bracketed video IDs of any length, e.g. "[ABC123]",
+	// and the suffixes YouTube subtitle downloaders append, e.g.
+	// "-_YouTube-zh-CN-dual-double".
+	PresetYouTube TitlePreset = "youtube"
+	// PresetBilibili removes BV and av video IDs, bracketed or not, and the
+	// "_哔哩哔哩_bilibili" suffix of saved page titles.
+	PresetBilibili TitlePreset = "bilibili"
+	// PresetYTDLP removes only the 11-character bracketed video ID of yt-dlp's
+	// default output template, e.g. "Title [dQw4w9WgXcQ]".
+	PresetYTDLP TitlePreset = "yt-dlp"
+	// PresetScene treats dots as spaces and removes release tags such as
+	// "1080p", "WEB-DL" and "x264-GROUP" from scene release names.
+	PresetScene TitlePreset = "scene"
+	// PresetPlain removes nothing platform-specific.
+	PresetPlain TitlePreset = "plain"
+)
+
+// normalizationRule replaces every match of pattern in a title.
+type normalizationRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// sceneTagPattern matches scene release tags, with the release group that
+// follows the last one. Dots have already been replaced with spaces.
+var sceneTagPattern = regexp.MustCompile(`(?i)\b(?:2160p|1080p|720p|576p|480p|4k|uhd|` +
+	`web-?dl|web-?rip|blu-?ray|bdrip|brrip|hdrip|hdtv|dvdrip|remux|` +
+	`[hx] ?26[45]|hevc|avc|xvid|aac(?: ?2 0)?|e?ac-?3|ddp?(?: ?[257] [01])?|dts(?:-hd)?|truehd|atmos|` +
+	`10bit|hdr(?:10)?|proper|repack|amzn|dsnp|hmax|atvp)\b(?:-[a-z0-9]+\b)?`)
+
+// presetRules holds the rules of each preset, applied in order.
+var presetRules = map[TitlePreset][]normalizationRule{
+	PresetYouTube: {
+		{youtubeIDPattern, ""},
+		{regexp.MustCompile(`-_YouTube-zh-CN-dual-double`), ""},
+		{regexp.MustCompile(`_-_YouTube`), ""},
+	},
+	PresetBilibili: {
+		{regexp.MustCompile(`[\[(]?BV1[0-9A-Za-z]{9}[\])]?`), ""},
+		{regexp.MustCompile(`(?i)(^|[^a-z0-9])av\d{5,}\b`), "${1}"},
+		{regexp.MustCompile(`(?i)[_ -]*哔哩哔哩[_ -]*bilibili`), ""},
+	},
+	PresetYTDLP: {
+		{regexp.MustCompile(`\s*\[[A-Za-z0-9_-]{11}\]`), ""},
+	},
+	PresetScene: {
+		{regexp.MustCompile(`[._]`), " "},
+		{sceneTagPattern, ""},
+	},
+	PresetPlain: nil,
+}
+
+// ParsePreset returns the preset with the given name, ignoring case.
+func ParsePreset(name string) (TitlePreset, error) {
+	preset := TitlePreset(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := presetRules[preset]; !ok {
+		names := make([]string, 0, len(presetRules))
+		for p := range presetRules {
+			names = append(names, string(p))
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("unknown preset %q: expected one of %s", name, strings.Join(names, ", "))
+	}
+	return preset, nil
+}
+
+// applyPreset removes the naming noise of the configured preset from a title.
+func (vsm *VideoSubtitleMatcher) applyPreset(title string) string {
+	for _, rule := range presetRules[vsm.preset] {
+		title = rule.pattern.ReplaceAllString(title, rule.replacement)
+	}
+	return title
+}
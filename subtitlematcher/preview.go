@@ -0,0 +1,89 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// timingLinePattern matches SRT and WebVTT cue timing lines.
+var timingLinePattern = regexp.MustCompile(`^\d{1,2}:\d{2}(?::\d{2})?[.,]\d{3}\s+-->\s+`)
+
+// sbvTimingPattern matches YouTube SubViewer (.sbv) cue timing lines such as
+// "0:00:01.000,0:00:03.500".
+var sbvTimingPattern = regexp.MustCompile(`^\d+:\d{2}:\d{2}\.\d{3},\d+:\d{2}:\d{2}\.\d{3}$`)
+
+// cueIndexPattern matches SRT cue sequence numbers.
+var cueIndexPattern = regexp.MustCompile(`^\d+$`)
+
+// previewLines returns up to n lines of cue text from the beginning of a
+// subtitle file. SRT, WebVTT and ASS/SSA files are understood; other formats
+// yield no preview.
+func previewLines(fsys FileSystem, subtitlePath string, n int) []string {
+	if isImageSubtitle(fsys, subtitlePath) {
+		return nil
+	}
+
+	file, err := fsys.Open(subtitlePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for len(lines) < n && scanner.Scan() {
+		text := cueText(scanner.Text())
+		if text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// cueText extracts displayable text from a single subtitle file line,
+// returning an empty string for structural lines such as indices and timings.
+func cueText(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, utf8BOM))
+
+	switch {
+	case line == "":
+		return ""
+	case strings.HasPrefix(line, "Dialogue:"):
+		// ASS/SSA: the text is everything after the ninth comma
+		fields := strings.SplitN(line, ",", 10)
+		if len(fields) < 10 {
+			return ""
+		}
+		line = strings.ReplaceAll(fields[9], `\N`, " ")
+	case microDVDLinePattern.MatchString(line):
+		// MicroDVD: {start}{end}text, where "|" breaks lines and {1}{1}25
+		// declares the frame rate
+		m := microDVDLinePattern.FindStringSubmatch(line)
+		if m[1] == "1" && m[2] == "1" {
+			return ""
+		}
+		line = strings.ReplaceAll(m[3], "|", " ")
+	case line == "WEBVTT", strings.HasPrefix(line, "WEBVTT "), strings.HasPrefix(line, "NOTE"):
+		return ""
+	case cueIndexPattern.MatchString(line), timingLinePattern.MatchString(line), sbvTimingPattern.MatchString(line):
+		return ""
+	case assSectionPattern.MatchString(line), isASSHeaderLine(line):
+		return ""
+	}
+
+	// SAMI (.smi) cues are HTML, with entities such as &nbsp; for blank cues
+	return strings.TrimSpace(html.UnescapeString(markupPattern.ReplaceAllString(line, "")))
+}
+
+// isASSHeaderLine reports whether a line is an ASS/SSA key-value header such
+// as "Style:" or "Format:" rather than dialogue.
+func isASSHeaderLine(line string) bool {
+	for _, prefix := range []string{"Title:", "ScriptType:", "Format:", "Style:", "PlayResX:", "PlayResY:", "WrapStyle:", "ScaledBorderAndShadow:", "YCbCr Matrix:", "Comment:", "Kind:", "Language:"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(line, ";")
+}
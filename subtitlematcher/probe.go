@@ -0,0 +1,77 @@
+package subtitlematcher
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ffprobePath is the ffprobe executable used to inspect video files.
+const ffprobePath = "ffprobe"
+
+// probeFrameRate returns the frame rate of the first video stream using ffprobe.
+func probeFrameRate(ctx context.Context, videoPath string) (float64, error) {
+	out, err := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=r_frame_rate",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		videoPath,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseFrameRate(strings.TrimSpace(string(out)))
+}
+
+// videoFrameRate probes a matched video's frame rate. Probing needs the video
+// on local disk, so it fails on other file systems.
+func (vsm *VideoSubtitleMatcher) videoFrameRate(ctx context.Context, videoPath string) (float64, error) {
+	if !vsm.isLocal() {
+		return 0, fmt.Errorf("cannot probe %s: not on the local file system", videoPath)
+	}
+	return probeFrameRate(ctx, videoPath)
+}
+
+// parseFrameRate parses a frame rate written as a fraction ("24000/1001")
+// or a decimal ("25"). Rates that are not positive, such as the "0/1" some
+// containers report, are invalid.
+func parseFrameRate(value string) (float64, error) {
+	numerator, denominator, isFraction := strings.Cut(value, "/")
+	num, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frame rate %q", value)
+	}
+	if isFraction {
+		den, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || den == 0 {
+			return 0, fmt.Errorf("invalid frame rate %q", value)
+		}
+		num /= den
+	}
+	if !validFrameRate(num) {
+		return 0, fmt.Errorf("invalid frame rate %q", value)
+	}
+	return num, nil
+}
+
+// probeDuration returns the duration of a video container using ffprobe.
+func probeDuration(ctx context.Context, videoPath string) (time.Duration, error) {
+	out, err := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		videoPath,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", strings.TrimSpace(string(out)))
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,153 @@
+package subtitlematcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// processedFileName is the marker file in the target directory recording
+// the subtitles earlier runs have finished with.
+const processedFileName = ".subtitle-matcher.processed"
+
+// ProcessedMode controls whether runs keep track of the subtitles they have
+// finished with, so that watch and cron setups don't evaluate the same files
+// every cycle.
+type ProcessedMode int
+
+const (
+	// ProcessedOff neither records nor skips processed subtitles.
+	ProcessedOff ProcessedMode = iota
+	// ProcessedSkip records the subtitles each run renamed or found correctly
+	// named, and skips them on later runs while they are unchanged.
+	ProcessedSkip
+	// ProcessedRecheck evaluates every subtitle again, e.g. when forced by
+	// the user, and records the outcome for later ProcessedSkip runs.
+	ProcessedRecheck
+)
+
+// processedMark identifies the version of a subtitle a run finished with, so
+// that a subtitle replaced or edited since is evaluated again.
+type processedMark struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// equal reports whether two marks identify the same version of a file.
+func (m processedMark) equal(other processedMark) bool {
+	return m.Size == other.Size && m.ModTime.Equal(other.ModTime)
+}
+
+// processedMarks maps subtitle paths to the version that was processed.
+type processedMarks map[string]processedMark
+
+// loadProcessed reads the marker file of the directory. A missing file holds
+// no marks.
+func (vsm *VideoSubtitleMatcher) loadProcessed() (processedMarks, error) {
+	data, err := os.ReadFile(filepath.Join(vsm.directory, processedFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return processedMarks{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var marks processedMarks
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return nil, err
+	}
+	if marks == nil {
+		marks = processedMarks{}
+	}
+	return marks, nil
+}
+
+// mark returns the current mark of a file, reporting whether it exists.
+func (vsm *VideoSubtitleMatcher) mark(path string) (processedMark, bool) {
+	info, err := vsm.fs.Stat(path)
+	if err != nil {
+		return processedMark{}, false
+	}
+	return processedMark{Size: info.Size(), ModTime: info.ModTime().UTC()}, true
+}
+
+// processed reports whether a subtitle is unchanged since a run processed it.
+func (vsm *VideoSubtitleMatcher) processed(marks processedMarks, path string) bool {
+	recorded, ok := marks[path]
+	if !ok {
+		return false
+	}
+	current, ok := vsm.mark(path)
+	return ok && current.equal(recorded)
+}
+
+// skipProcessedFiles drops the subtitles earlier runs have finished with and
+// that are unchanged since.
+func (vsm *VideoSubtitleMatcher) skipProcessedFiles(subtitleFiles []string) ([]string, error) {
+	if vsm.processedMode != ProcessedSkip || !vsm.isLocal() {
+		return subtitleFiles, nil
+	}
+
+	marks, err := vsm.loadProcessed()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := subtitleFiles[:0:0]
+	skipped := 0
+	for _, subtitlePath := range subtitleFiles {
+		if vsm.processed(marks, subtitlePath) {
+			skipped++
+			continue
+		}
+		kept = append(kept, subtitlePath)
+	}
+	if skipped > 0 && vsm.verbose {
+		fmt.Printf("Skipping %d subtitles processed by earlier runs\n", skipped)
+	}
+	return kept, nil
+}
+
+// recordProcessed adds the subtitles a run renamed or found correctly named
+// to the marker file, and drops the marks of files that are gone or changed.
+func (vsm *VideoSubtitleMatcher) recordProcessed(results []MatchResult) error {
+	if vsm.processedMode == ProcessedOff || vsm.dryRun || !vsm.isLocal() {
+		return nil
+	}
+
+	marks, err := vsm.loadProcessed()
+	if err != nil {
+		return err
+	}
+	for path, recorded := range marks {
+		if current, ok := vsm.mark(path); !ok || !current.equal(recorded) {
+			delete(marks, path)
+		}
+	}
+
+	for _, result := range results {
+		if result.Error != nil || result.NewSubtitlePath == "" || result.Held || result.Invalid ||
+			!result.Renamed && result.SubtitlePath != result.NewSubtitlePath {
+			// Evaluated again, and not finished with
+			delete(marks, result.SubtitlePath)
+			continue
+		}
+		if current, ok := vsm.mark(result.NewSubtitlePath); ok {
+			marks[result.NewSubtitlePath] = current
+		}
+	}
+
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(vsm.directory, processedFileName)
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
@@ -0,0 +1,374 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// remoteCandidateCount is the number of subtitles found online that are
+// kept for each video.
+const remoteCandidateCount = 5
+
+// openSubtitlesURL is the base URL of the OpenSubtitles REST API.
+const openSubtitlesURL = "https://api.opensubtitles.com/api/v1"
+
+// SubtitleQuery describes the video a SubtitleProvider is searched for,
+// as parsed from its name.
+type SubtitleQuery struct {
+	Title     string   // Title without episode marker, year and release details, e.g. "the expanse"
+	Year      int      // Release year (0 if unknown)
+	Season    int      // Season number (0 if unknown or not an episode)
+	Episode   int      // Episode number (0 if not an episode)
+	Languages []string // ISO 639-1 codes of the languages wanted, best first (empty for any)
+}
+
+// RemoteSubtitle is a subtitle found by a SubtitleProvider.
+type RemoteSubtitle struct {
+	Provider  string `json:"provider"`            // Name of the provider that found it
+	ID        string `json:"id"`                  // Provider's ID of the subtitle file, passed back to Download
+	Name      string `json:"name"`                // File or release name
+	Language  string `json:"language,omitempty"`  // ISO 639-1 language code, if known
+	Format    string `json:"format,omitempty"`    // Extension without the dot, e.g. "srt" ("srt" if empty)
+	Downloads int    `json:"downloads,omitempty"` // Download count, as a measure of popularity
+}
+
+// providerList is a list of subtitle providers, searched in order.
+type providerList []SubtitleProvider
+
+// SubtitleProvider searches an online subtitle database, such as
+// OpenSubtitles. See SearchSubtitles.
+type SubtitleProvider interface {
+	// Name returns the provider's name, e.g. "opensubtitles".
+	Name() string
+	// Search returns the subtitles matching the query, best first.
+	Search(ctx context.Context, query SubtitleQuery) ([]RemoteSubtitle, error)
+	// Download returns the content of a subtitle found by Search.
+	Download(ctx context.Context, subtitle RemoteSubtitle) ([]byte, error)
+}
+
+// OpenSubtitlesProvider searches opensubtitles.com through its REST API.
+// Anonymous downloads are limited per day; a user token raises the limit.
+type OpenSubtitlesProvider struct {
+	APIKey    string // Consumer API key
+	Token     string // User token from the /login endpoint ("" to download anonymously)
+	UserAgent string // User agent registered with the API key ("subtitle-matcher" if empty)
+	URL       string // API base URL (the public API if empty)
+}
+
+// NewOpenSubtitlesProvider returns a provider using the OpenSubtitles API
+// with apiKey.
+func NewOpenSubtitlesProvider(apiKey string) *OpenSubtitlesProvider {
+	return &OpenSubtitlesProvider{APIKey: apiKey}
+}
+
+// Name returns "opensubtitles".
+func (p *OpenSubtitlesProvider) Name() string {
+	return "opensubtitles"
+}
+
+// openSubtitlesLanguages maps ISO 639-1 codes to the OpenSubtitles codes of
+// their regional variants.
+var openSubtitlesLanguages = map[string][]string{
+	"zh": {"zh-cn", "zh-tw"},
+	"pt": {"pt-br", "pt-pt"},
+}
+
+// Search searches subtitles by title, and episode or year.
+func (p *OpenSubtitlesProvider) Search(ctx context.Context, query SubtitleQuery) ([]RemoteSubtitle, error) {
+	params := url.Values{"query": {query.Title}}
+	if query.Episode > 0 {
+		params.Set("episode_number", strconv.Itoa(query.Episode))
+		if query.Season > 0 {
+			params.Set("season_number", strconv.Itoa(query.Season))
+		}
+	} else if query.Year > 0 {
+		params.Set("year", strconv.Itoa(query.Year))
+	}
+	if len(query.Languages) > 0 {
+		var languages []string
+		for _, language := range query.Languages {
+			if variants, ok := openSubtitlesLanguages[language]; ok {
+				languages = append(languages, variants...)
+			} else {
+				languages = append(languages, language)
+			}
+		}
+		// The API wants them sorted
+		sort.Strings(languages)
+		params.Set("languages", strings.Join(languages, ","))
+	}
+
+	var response struct {
+		Data []struct {
+			Attributes struct {
+				Language      string `json:"language"`
+				DownloadCount int    `json:"download_count"`
+				Release       string `json:"release"`
+				Files         []struct {
+					FileID   int    `json:"file_id"`
+					FileName string `json:"file_name"`
+				} `json:"files"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := p.request(ctx, http.MethodGet, "/subtitles?"+params.Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+
+	var found []RemoteSubtitle
+	for _, item := range response.Data {
+		attributes := item.Attributes
+		if len(attributes.Files) == 0 {
+			continue // Split into several files, e.g. CD1 and CD2
+		}
+		file := attributes.Files[0]
+		name := file.FileName
+		if name == "" {
+			name = attributes.Release
+		}
+		language, _, _ := strings.Cut(strings.ToLower(attributes.Language), "-")
+		found = append(found, RemoteSubtitle{
+			Provider:  p.Name(),
+			ID:        strconv.Itoa(file.FileID),
+			Name:      name,
+			Language:  language,
+			Format:    strings.TrimPrefix(strings.ToLower(filepath.Ext(file.FileName)), "."),
+			Downloads: attributes.DownloadCount,
+		})
+	}
+	return found, nil
+}
+
+// Download downloads a subtitle: the API returns a temporary link to it.
+func (p *OpenSubtitlesProvider) Download(ctx context.Context, subtitle RemoteSubtitle) ([]byte, error) {
+	fileID, err := strconv.Atoi(subtitle.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenSubtitles file ID %q", subtitle.ID)
+	}
+	body, err := json.Marshal(map[string]int{"file_id": fileID})
+	if err != nil {
+		return nil, err
+	}
+	var link struct {
+		Link string `json:"link"`
+	}
+	if err := p.request(ctx, http.MethodPost, "/download", body, &link); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.Link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// request calls an OpenSubtitles API endpoint and decodes its JSON response.
+func (p *OpenSubtitlesProvider) request(ctx context.Context, method, path string, body []byte, response any) error {
+	base, userAgent := p.URL, p.UserAgent
+	if base == "" {
+		base = openSubtitlesURL
+	}
+	if userAgent == "" {
+		userAgent = "subtitle-matcher"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Api-Key", p.APIKey)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OpenSubtitles rejected the request: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("invalid OpenSubtitles response: %w", err)
+	}
+	return nil
+}
+
+// subtitleQuery parses the title, year and episode of a video's name.
+func (vsm *VideoSubtitleMatcher) subtitleQuery(videoPath string) SubtitleQuery {
+	normalized := vsm.normalizedVideo(videoPath)
+	query := SubtitleQuery{
+		Title:     semanticTitle(vsm.strippedVideo(videoPath)),
+		Year:      detectYear(normalized),
+		Languages: vsm.searchLanguages,
+	}
+	if episode, ok := detectEpisode(normalized); ok && !episode.Special {
+		query.Season, query.Episode = episode.Season, episode.Episode
+	}
+	return query
+}
+
+// plannedSearches searches the providers for every video that neither a
+// planned subtitle nor one of the scanned subtitles belongs to, planning
+// the download of the best subtitle found when DownloadSubtitles is
+// enabled. Subtitles in earlier languages of the search come first, in the
+// order the providers returned them.
+func (vsm *VideoSubtitleMatcher) plannedSearches(ctx context.Context, videoFiles, subtitleFiles []string, planned []MatchResult) ([]MatchResult, error) {
+	rank := make(map[string]int, len(vsm.searchLanguages))
+	for i, language := range vsm.searchLanguages {
+		rank[language] = i
+	}
+	languageRank := func(language string) int {
+		if i, ok := rank[language]; ok {
+			return i
+		}
+		return len(rank)
+	}
+
+	var searched []MatchResult
+	for _, videoPath := range vsm.uncoveredVideos(videoFiles, subtitleFiles, planned) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		query := vsm.subtitleQuery(videoPath)
+		var found []RemoteSubtitle
+		var errs []error
+		for _, provider := range vsm.providers {
+			subtitles, err := provider.Search(ctx, query)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+				continue
+			}
+			found = append(found, subtitles...)
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			return languageRank(found[i].Language) < languageRank(found[j].Language)
+		})
+
+		result := MatchResult{
+			SubtitlePath: withExt(videoPath, ".srt"),
+			VideoPath:    videoPath,
+			Fetched:      true,
+			Remote:       found[:min(len(found), remoteCandidateCount)],
+		}
+		if len(found) == 0 && len(errs) > 0 {
+			result.Error = fmt.Errorf("failed to search subtitles: %w", errors.Join(errs...))
+		}
+		if len(found) > 0 {
+			best := found[0]
+			format := best.Format
+			if format == "" {
+				format = "srt"
+			}
+			suffix := "." + format
+			if best.Language != "" {
+				suffix = "." + best.Language + suffix
+			}
+			result.SubtitlePath = withExt(videoPath, suffix)
+			result.Language = best.Language
+			if vsm.downloadSubtitles {
+				result.NewSubtitlePath = result.SubtitlePath
+			}
+		}
+		searched = append(searched, result)
+	}
+	return searched, nil
+}
+
+// fetchSubtitle downloads the best subtitle found for a video without one,
+// under the name planned for it. An existing file is never overwritten. In
+// dry run mode, and when downloading is disabled, the subtitles found are
+// only reported.
+func (vsm *VideoSubtitleMatcher) fetchSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	if vsm.dryRun || result.NewSubtitlePath == "" || result.Error != nil {
+		vsm.logFetched(result)
+		return result
+	}
+	if _, err := vsm.fs.Stat(result.NewSubtitlePath); err == nil {
+		result.Error = fmt.Errorf("failed to download subtitle: %s already exists", filepath.Base(result.NewSubtitlePath))
+		return result
+	}
+
+	best := result.Remote[0]
+	var data []byte
+	err := fmt.Errorf("unknown provider %q", best.Provider)
+	for _, provider := range vsm.providers {
+		if provider.Name() == best.Provider {
+			data, err = provider.Download(ctx, best)
+			break
+		}
+	}
+	if err == nil {
+		err = vsm.fs.WriteFile(result.NewSubtitlePath, data)
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("failed to download subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error downloading subtitle for %s: %v\n", filepath.Base(result.VideoPath), err)
+		}
+		return result
+	}
+
+	result.Renamed = true
+	vsm.logFetched(result)
+	return result
+}
+
+// logFetched logs the subtitles found online for a video without one
+func (vsm *VideoSubtitleMatcher) logFetched(result MatchResult) {
+	if !vsm.verbose || vsm.writesPlan() {
+		return
+	}
+
+	fmt.Printf("\nNo subtitle for: %s\n", filepath.Base(result.VideoPath))
+	if result.Error != nil {
+		vsm.printf(colorRed, "  Error searching subtitles: %v\n", result.Error)
+		return
+	}
+	if len(result.Remote) == 0 {
+		vsm.printf(colorGray, "  Nothing found online\n")
+		return
+	}
+	fmt.Printf("  Found online:\n")
+	for _, remote := range result.Remote {
+		language := remote.Language
+		if language == "" {
+			language = "unknown language"
+		}
+		fmt.Printf("    [%s] %s (%s, %d downloads)\n", remote.Provider, remote.Name, language, remote.Downloads)
+	}
+	switch {
+	case result.NewSubtitlePath == "":
+	case result.Renamed:
+		vsm.printf(colorGreen, "  ✓ Downloaded: %s\n", filepath.Base(result.NewSubtitlePath))
+	default:
+		fmt.Printf("  Would download: %s\n", filepath.Base(result.NewSubtitlePath))
+	}
+}
@@ -0,0 +1,121 @@
+package subtitlematcher
+
+import "sort"
+
+// byteHistogram counts how often each byte occurs in a normalized title.
+type byteHistogram [256]int
+
+// histogramOf counts the bytes of s.
+func histogramOf(s string) *byteHistogram {
+	var h byteHistogram
+	for i := 0; i < len(s); i++ {
+		h[s[i]]++
+	}
+	return &h
+}
+
+// similarityBound returns an upper bound of calculateSimilarity for a title
+// with the given histogram and length and another title, in linear time. A
+// common subsequence cannot use more of any byte than both titles contain,
+// so titles of very different lengths or with few characters in common are
+// bounded low without running LCS.
+func similarityBound(h *byteHistogram, length int, other string) float64 {
+	maxLen := max(length, len(other))
+	if maxLen == 0 {
+		return 1
+	}
+
+	remaining := *h
+	shared := 0
+	for i := 0; i < len(other); i++ {
+		if remaining[other[i]] > 0 {
+			remaining[other[i]]--
+			shared++
+		}
+	}
+	return float64(shared) / float64(maxLen)
+}
+
+// candidateBound returns a function bounding from above the score that
+// candidateScorer gives a video, comparing the same titles as the scorer.
+func (vsm *VideoSubtitleMatcher) candidateBound(subtitlePath string) func(videoPath string) float64 {
+	normalizedSubtitle := vsm.normalizeTitle(subtitleTitle(subtitlePath))
+	mode := vsm.mediaMode(subtitlePath, stripPart(normalizedSubtitle))
+
+	return func(videoPath string) float64 {
+		videoPart, _ := detectPart(vsm.normalizedVideo(videoPath))
+		_, comparedSubtitle := pairedPart(normalizedSubtitle, videoPart)
+		return vsm.titleBound(mode, subtitlePath, comparedSubtitle, videoPath)
+	}
+}
+
+// titleBound bounds the similarity of a subtitle title and a video under the
+// media mode: the titles before the years weigh in as well as the whole
+// titles in ModeMovie, and the subtitle's episode marker is aligned to the
+// video's in ModeTV.
+func (vsm *VideoSubtitleMatcher) titleBound(mode MediaMode, subtitlePath, normalizedSubtitle, videoPath string) float64 {
+	bound := func(subtitleTitle, videoTitle string) float64 {
+		return similarityBound(histogramOf(subtitleTitle), len(subtitleTitle), videoTitle)
+	}
+
+	videoTitle := vsm.strippedVideo(videoPath)
+	switch mode {
+	case ModeMovie:
+		if detectYear(normalizedSubtitle) != 0 && detectYear(videoTitle) != 0 {
+			title := bound(movieTitle(normalizedSubtitle), movieTitle(videoTitle))
+			return movieTitleWeight*title + (1-movieTitleWeight)*bound(normalizedSubtitle, videoTitle)
+		}
+	case ModeTV:
+		return bound(vsm.alignEpisodeMarker(normalizedSubtitle, subtitlePath, videoTitle, videoPath), videoTitle)
+	}
+	return bound(normalizedSubtitle, videoTitle)
+}
+
+// prunedCandidates scores the videos that could be the best or runner-up
+// match for a subtitle, in scan order. Videos are scored in order of their
+// similarity bound, and scoring stops once no remaining video can beat the
+// runner-up, so findBestMatch gets the same answer as from scoring them all.
+// Directory context and metadata aliases are not covered by the bound, so
+// with those enabled every video is scored.
+func (vsm *VideoSubtitleMatcher) prunedCandidates(subtitlePath string, videoFiles []string) []Candidate {
+	if vsm.directoryWeight > 0 || vsm.metadataTitles != nil || vsm.nfoTitles != nil {
+		return vsm.scoreCandidates(subtitlePath, videoFiles)
+	}
+
+	bound := vsm.candidateBound(subtitlePath)
+	bounds := make([]float64, len(videoFiles))
+	order := make([]int, len(videoFiles))
+	for i, videoPath := range videoFiles {
+		bounds[i] = bound(videoPath)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return bounds[order[a]] > bounds[order[b]] })
+
+	score := vsm.candidateScorer(subtitlePath)
+	var scored []int
+	similarities := make([]float64, len(videoFiles))
+	var best, runnerUp float64
+	for _, i := range order {
+		if bounds[i] < runnerUp {
+			break
+		}
+		similarity, ok := score(videoFiles[i])
+		if !ok {
+			continue
+		}
+		if similarity > best {
+			best, runnerUp = similarity, best
+		} else if similarity > runnerUp {
+			runnerUp = similarity
+		}
+		similarities[i] = similarity
+		scored = append(scored, i)
+	}
+
+	sort.Ints(scored)
+	candidates := make([]Candidate, 0, len(scored))
+	for _, i := range scored {
+		candidates = append(candidates, Candidate{VideoPath: videoFiles[i], Similarity: similarities[i]})
+	}
+	return candidates
+}
@@ -0,0 +1,114 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates empty files with the given names in a new directory.
+func writeFiles(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// matchOf returns the result of the subtitle with the given name.
+func matchOf(t *testing.T, results []MatchResult, subtitle string) MatchResult {
+	t.Helper()
+	for _, result := range results {
+		if filepath.Base(result.SubtitlePath) == subtitle {
+			return result
+		}
+	}
+	t.Fatalf("no result for %s", subtitle)
+	return MatchResult{}
+}
+
+func TestPruningKeepsMovieTitleMatch(t *testing.T) {
+	dir := writeFiles(t,
+		"Alien 1979.srt",
+		"Alien 1979 Directors Cut Special Extended Anniversary Edition.mkv",
+		"Olive 1979.mkv",
+		"Xylem 1979.mkv",
+	)
+
+	results, err := New(dir, Verbose(false)).Match()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := matchOf(t, results, "Alien 1979.srt")
+	if got := filepath.Base(result.VideoPath); got != "Alien 1979 Directors Cut Special Extended Anniversary Edition.mkv" {
+		t.Errorf("Alien 1979.srt matched %s (%.3f)", got, result.Similarity)
+	}
+}
+
+func TestPrunedCandidatesMatchUnpruned(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		files   []string
+	}{
+		{"auto", nil, []string{
+			"How to code.srt", "how-to-code-part-one.srt", "Cooking basics.srt",
+			"How_to_code_[ABC123].mkv", "How to cook [XYZ987].mkv", "Coding interviews.mkv",
+		}},
+		{"movie", []Option{Mode(ModeMovie)}, []string{
+			"Alien 1979.srt", "Aliens 1986.srt", "Olive 1979.srt",
+			"Alien 1979 Directors Cut Special Extended Anniversary Edition.mkv",
+			"Aliens.1986.Special.Edition.1080p.BluRay.mkv", "Olive 1979.mkv", "Xylem 1979.mkv",
+		}},
+		{"tv", []Option{Mode(ModeTV)}, []string{
+			"Show S01E01.srt", "Show 1x02.srt", "Other Show S01E01.srt",
+			"Show.S01E01.Pilot.1080p.WEB.mkv", "Show.S01E02.The.Long.Way.Home.1080p.WEB.mkv",
+			"Show.S01E01E02.mkv", "Other.Show.S01E01.mkv",
+		}},
+		{"multi-part", nil, []string{
+			"Movie.cd1.srt", "Movie.part2.srt", "Deathly Hallows Part 2.srt",
+			"Movie.cd1.avi", "Movie.cd2.avi", "Movie.mkv", "Harry Potter and the Deathly Hallows Part 2.mkv",
+		}},
+		{"scene preset", []Option{Preset(PresetScene)}, []string{
+			"The.Matrix.1999.srt", "Matrix Reloaded.srt",
+			"The.Matrix.1999.1080p.BluRay.x264-GRP.mkv", "The.Matrix.Reloaded.2003.720p.WEB-DL.mkv", "Matrices.mkv",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files...)
+			vsm := New(dir, append([]Option{Verbose(false)}, tt.options...)...)
+			var videos, subtitles []string
+			for _, name := range tt.files {
+				if filepath.Ext(name) == ".srt" {
+					subtitles = append(subtitles, filepath.Join(dir, name))
+				} else {
+					videos = append(videos, filepath.Join(dir, name))
+				}
+			}
+
+			for _, subtitle := range subtitles {
+				var want string
+				var wantScore, wantRunnerUp float64
+				for _, candidate := range vsm.scoreCandidates(subtitle, videos) {
+					if candidate.Similarity > wantScore {
+						want, wantScore, wantRunnerUp = candidate.VideoPath, candidate.Similarity, wantScore
+					} else if candidate.Similarity > wantRunnerUp {
+						wantRunnerUp = candidate.Similarity
+					}
+				}
+
+				got, score, runnerUp := vsm.findBestMatch(subtitle, videos)
+				if got != want || score != wantScore || runnerUp != wantRunnerUp {
+					t.Errorf("%s: pruned %s (%.3f, runner-up %.3f), unpruned %s (%.3f, runner-up %.3f)",
+						filepath.Base(subtitle), filepath.Base(got), score, runnerUp,
+						filepath.Base(want), wantScore, wantRunnerUp)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// adPattern matches cue text advertising a website or crediting whoever
+// ripped or synced the subtitle.
+var adPattern = regexp.MustCompile(`(?i)https?://|www\.|\.(?:com|net|org)\b|opensubtitles|subscene|addic7ed|\bsubtitles? by\b|\bsync(?:ed|hronized)? (?:and corrected )?by\b|\bdownloaded from\b`)
+
+// formatRichness rewards formats that carry more than plain text, such as
+// ASS styling and positioning.
+var formatRichness = map[string]float64{
+	".ass": 0.5,
+	".ssa": 0.5,
+	".srt": 0.3,
+	".vtt": 0.3,
+}
+
+// subtitleQuality scores a subtitle file: more cues, valid UTF-8, no ads and
+// a richer format all score higher. Image-based and unreadable subtitles
+// score by format only.
+func subtitleQuality(fsys FileSystem, result MatchResult) float64 {
+	score := formatRichness[subtitleFormat(result)]
+	if isImageSubtitle(fsys, result.SubtitlePath) {
+		return score
+	}
+	data, err := readFile(fsys, result.SubtitlePath)
+	if err != nil {
+		return score
+	}
+
+	if utf8.Valid(data) {
+		score++
+	}
+
+	cues, ads := 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), utf8BOM))
+		if isCueStart(line) {
+			cues++
+		}
+		if text := cueText(line); text != "" && adPattern.MatchString(text) {
+			ads++
+		}
+	}
+	// Cue count matters by magnitude: a complete subtitle has hundreds,
+	// a truncated one dozens
+	score += math.Log10(1 + float64(cues))
+	score -= math.Min(0.5*float64(ads), 2)
+	return score
+}
+
+// isCueStart reports whether a line starts a cue in any text format.
+func isCueStart(line string) bool {
+	return timingLinePattern.MatchString(line) ||
+		sbvTimingPattern.MatchString(line) ||
+		strings.HasPrefix(line, "Dialogue:") ||
+		microDVDLinePattern.MatchString(line) ||
+		strings.HasPrefix(strings.ToUpper(line), "<SYNC")
+}
+
+// dropWorseDuplicates resolves subtitles planned for the same canonical name by
+// quality. The best subtitle keeps its planned name; the others are not
+// renamed and record which subtitle they duplicate.
+func (vsm *VideoSubtitleMatcher) dropWorseDuplicates(results []MatchResult) {
+	for _, indices := range groupByTarget(results) {
+		if len(indices) < 2 {
+			continue
+		}
+
+		for _, i := range indices {
+			results[i].Quality = subtitleQuality(vsm.fs, results[i])
+		}
+		sort.SliceStable(indices, func(a, b int) bool {
+			return results[indices[a]].Quality > results[indices[b]].Quality
+		})
+
+		best := results[indices[0]]
+		for _, i := range indices[1:] {
+			results[i].DuplicateOf = best.SubtitlePath
+			results[i].NewSubtitlePath = ""
+		}
+	}
+}
+
+// logDuplicate logs a subtitle that lost its name to a better duplicate, or
+// to a subtitle in a preferred language
+func (vsm *VideoSubtitleMatcher) logDuplicate(result MatchResult) {
+	if !vsm.verbose || vsm.writesPlan() {
+		return
+	}
+	if result.Quality == 0 && result.Language == "" {
+		vsm.printf(colorGray, "\nDuplicate skipped: %s (no language, kept %s)\n",
+			filepath.Base(result.SubtitlePath), filepath.Base(result.DuplicateOf))
+		return
+	}
+	vsm.printf(colorGray, "\nDuplicate skipped: %s (quality %.2f, kept %s)\n",
+		filepath.Base(result.SubtitlePath), result.Quality, filepath.Base(result.DuplicateOf))
+}
@@ -0,0 +1,148 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// rclonePath is the rclone executable used for rclone remotes.
+const rclonePath = "rclone"
+
+// rclone exit codes that mean the path does not exist.
+const (
+	rcloneDirNotFound  = 3
+	rcloneFileNotFound = 4
+)
+
+// RcloneFileSystem is a FileSystem on an rclone remote, such as Google Drive,
+// OneDrive or SFTP, configured with "rclone config". It runs the rclone
+// command for every operation, so rclone must be installed.
+type RcloneFileSystem struct {
+	remote string
+	ctx    context.Context // Kills running rclone commands when done (nil for never)
+}
+
+// NewRcloneFileSystem returns a file system on the named rclone remote, e.g.
+// "gdrive:". Paths are appended to the remote name as-is.
+func NewRcloneFileSystem(remote string) *RcloneFileSystem {
+	if !strings.HasSuffix(remote, ":") {
+		remote += ":"
+	}
+	return &RcloneFileSystem{remote: remote}
+}
+
+// WithContext returns a copy of the file system whose rclone commands are
+// killed when ctx is done.
+func (r *RcloneFileSystem) WithContext(ctx context.Context) FileSystem {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
+
+// Walk implements FileSystem.
+func (r *RcloneFileSystem) Walk(dir string, recursive bool, fn func(path string) error) error {
+	args := []string{"lsf", "--files-only", "--format", "p"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+	out, err := r.run("list", dir, nil, append(args, r.remote+dir)...)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		if err := fn(path.Join(dir, line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open implements FileSystem. The whole file is read before Open returns.
+func (r *RcloneFileSystem) Open(name string) (io.ReadCloser, error) {
+	out, err := r.run("open", name, nil, "cat", r.remote+name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(out)), nil
+}
+
+// WriteFile implements FileSystem.
+func (r *RcloneFileSystem) WriteFile(name string, data []byte) error {
+	_, err := r.run("write", name, data, "rcat", r.remote+name)
+	return err
+}
+
+// Rename implements FileSystem.
+func (r *RcloneFileSystem) Rename(oldPath, newPath string) error {
+	_, err := r.run("rename", oldPath, nil, "moveto", r.remote+oldPath, r.remote+newPath)
+	return err
+}
+
+// Remove implements FileSystem.
+func (r *RcloneFileSystem) Remove(name string) error {
+	_, err := r.run("remove", name, nil, "deletefile", r.remote+name)
+	return err
+}
+
+// Stat implements FileSystem.
+func (r *RcloneFileSystem) Stat(name string) (fs.FileInfo, error) {
+	out, err := r.run("stat", name, nil, "lsjson", "--stat", r.remote+name)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry struct {
+		Size    int64
+		ModTime time.Time
+		IsDir   bool
+	}
+	if err := json.Unmarshal(out, &entry); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fmt.Errorf("invalid rclone output: %w", err)}
+	}
+	return remoteFileInfo{path: cleanRemotePath(name), size: entry.Size, modTime: entry.ModTime, isDir: entry.IsDir}, nil
+}
+
+// run executes rclone with args and returns its output. Missing paths are
+// reported as fs.ErrNotExist.
+func (r *RcloneFileSystem) run(op, name string, stdin []byte, args ...string) ([]byte, error) {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, rclonePath, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err == nil {
+		return out, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case rcloneDirNotFound, rcloneFileNotFound:
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("rclone failed: %s", msg)
+		}
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: err}
+}
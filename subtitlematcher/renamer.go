@@ -0,0 +1,98 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Renamer performs the operation that puts a matched subtitle in place, so
+// that callers can choose how renames are executed: recorded only, copied,
+// linked, sent to a remote service or collected into a transaction. The
+// default moves the file on the matcher's FileSystem.
+//
+// Format conversions and extractions from archives write the new file on the
+// FileSystem themselves and do not go through the Renamer. Processing after
+// the rename, such as Repair or StripMarkup, expects the subtitle at its new
+// path on the FileSystem.
+type Renamer interface {
+	// Rename puts the subtitle at oldPath at newPath, replacing any file
+	// already there.
+	Rename(oldPath, newPath string) error
+}
+
+// RenameOperation is one rename handed to a RecordingRenamer.
+type RenameOperation struct {
+	From string // Path of the subtitle
+	To   string // Path it was to be renamed to
+}
+
+// RecordingRenamer records renames without performing them.
+type RecordingRenamer struct {
+	mu      sync.Mutex
+	renames []RenameOperation
+}
+
+// Rename implements Renamer.
+func (r *RecordingRenamer) Rename(oldPath, newPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renames = append(r.renames, RenameOperation{From: oldPath, To: newPath})
+	return nil
+}
+
+// Renames returns the renames recorded so far, in order.
+func (r *RecordingRenamer) Renames() []RenameOperation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RenameOperation(nil), r.renames...)
+}
+
+// CopyRenamer copies subtitles to their new path and leaves the originals in
+// place, e.g. to keep a seeding torrent intact.
+type CopyRenamer struct {
+	FileSystem FileSystem // Where the subtitles are copied (nil for local disk)
+}
+
+// Rename implements Renamer.
+func (c CopyRenamer) Rename(oldPath, newPath string) error {
+	fsys := c.FileSystem
+	if fsys == nil {
+		fsys = LocalFileSystem{}
+	}
+	data, err := readFile(fsys, oldPath)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(newPath, data)
+}
+
+// LinkRenamer hard-links subtitles to their new path on local disk and leaves
+// the originals in place, using no extra space.
+type LinkRenamer struct{}
+
+// Rename implements Renamer.
+func (LinkRenamer) Rename(oldPath, newPath string) error {
+	if err := os.Remove(newPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Link(oldPath, newPath)
+}
+
+// rename puts a subtitle at its new path with the configured Renamer, or
+// moves it on the file system when there is none. Extractions from archives
+// always go through the file system, which knows the archive's entries.
+// Commands the file system runs are killed when ctx is done.
+func (vsm *VideoSubtitleMatcher) rename(ctx context.Context, oldPath, newPath string) error {
+	if vsm.renamer == nil {
+		return withContext(ctx, vsm.fs).Rename(oldPath, newPath)
+	}
+	if archiveFS, ok := vsm.fs.(*archiveFileSystem); ok {
+		if _, archived := archiveFS.entry(oldPath); archived {
+			return archiveFS.WithContext(ctx).Rename(oldPath, newPath)
+		}
+	}
+	return vsm.renamer.Rename(oldPath, newPath)
+}
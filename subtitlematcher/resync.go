@@ -0,0 +1,199 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ffmpegPath is the ffmpeg executable used to decode video soundtracks.
+const ffmpegPath = "ffmpeg"
+
+const (
+	// resyncFrame is the resolution at which speech and cues are compared
+	// and the granularity of the detected offset.
+	resyncFrame = 100 * time.Millisecond
+	// resyncSampleRate is the rate the soundtrack is decoded at. Speech
+	// detection only needs the energy envelope, so it can be low.
+	resyncSampleRate = 8000
+)
+
+// extractSpeech decodes the first audio track of a video and reports, for
+// every resyncFrame, whether it is louder than the median frame. Dialogue is
+// what subtitles follow, and it is what sets loud frames apart from quiet ones.
+func extractSpeech(ctx context.Context, videoPath string) ([]bool, error) {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-v", "error",
+		"-i", videoPath,
+		"-map", "0:a:0",
+		"-ac", "1",
+		"-ar", fmt.Sprint(resyncSampleRate),
+		"-f", "s16le",
+		"-",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	samplesPerFrame := int(resyncSampleRate * resyncFrame / time.Second)
+	reader := bufio.NewReader(stdout)
+	frame := make([]int16, samplesPerFrame)
+	var energies []float64
+	for {
+		err := binary.Read(reader, binary.LittleEndian, frame)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return nil, err
+		}
+		var energy float64
+		for _, sample := range frame {
+			energy += float64(sample) * float64(sample)
+		}
+		energies = append(energies, energy)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	if len(energies) == 0 {
+		return nil, fmt.Errorf("no audio in %s", filepath.Base(videoPath))
+	}
+
+	sorted := append([]float64(nil), energies...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	speech := make([]bool, len(energies))
+	for i, energy := range energies {
+		speech[i] = energy > median
+	}
+	return speech, nil
+}
+
+// cueFrames returns the frames during which a cue is shown.
+func cueFrames(cues []srtCue) []int {
+	var frames []int
+	for _, cue := range cues {
+		for frame := int(cue.Start / resyncFrame); frame < int(cue.End/resyncFrame); frame++ {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// estimateOffset finds the shift within ±maxOffset that best lines up the
+// frames cues are shown with the frames speech is heard. Each shown frame
+// scores one point over speech and loses one over silence. The offset is
+// zero unless some shift does strictly better than none.
+func estimateOffset(speech []bool, frames []int, maxOffset time.Duration) time.Duration {
+	score := func(shift int) int {
+		total := 0
+		for _, frame := range frames {
+			if i := frame + shift; i >= 0 && i < len(speech) {
+				if speech[i] {
+					total++
+				} else {
+					total--
+				}
+			}
+		}
+		return total
+	}
+
+	maxShift := int(maxOffset / resyncFrame)
+	best, bestScore := 0, score(0)
+	for shift := -maxShift; shift <= maxShift; shift++ {
+		if s := score(shift); s > bestScore {
+			best, bestScore = shift, s
+		}
+	}
+	return time.Duration(best) * resyncFrame
+}
+
+// shiftSRT moves every cue of an SRT file by offset. Cues shifted before the
+// start of the video are clamped to it. The file keeps its byte order mark
+// and line endings. Reports whether the file was rewritten.
+func shiftSRT(fsys FileSystem, path string, offset time.Duration) (bool, error) {
+	if offset == 0 {
+		return false, nil
+	}
+
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	content := string(data)
+	doc := parseSRT(content)
+	if doc.Invalid {
+		return false, nil
+	}
+	bom, newline := textStyle(content)
+
+	for i := range doc.Cues {
+		doc.Cues[i].Start = max(doc.Cues[i].Start+offset, 0)
+		doc.Cues[i].End = max(doc.Cues[i].End+offset, 0)
+	}
+
+	if err := fsys.WriteFile(path, []byte(bom+formatSRT(doc.Cues, newline))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// resyncSubtitle shifts a renamed SRT subtitle by the offset detected between
+// its cues and the matched video's dialogue
+func (vsm *VideoSubtitleMatcher) resyncSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	if !strings.EqualFold(filepath.Ext(result.NewSubtitlePath), ".srt") {
+		return result
+	}
+	if !vsm.isLocal() {
+		if vsm.verbose {
+			vsm.printf(colorGray, "  Skipping resync: %s is not on the local file system\n", filepath.Base(result.VideoPath))
+		}
+		return result
+	}
+
+	doc, err := readSRT(vsm.fs, result.NewSubtitlePath)
+	if err != nil || doc.Invalid {
+		return result
+	}
+	speech, err := extractSpeech(ctx, result.VideoPath)
+	if err != nil {
+		if vsm.verbose {
+			vsm.printf(colorGray, "  Skipping resync: %v\n", err)
+		}
+		return result
+	}
+
+	offset := estimateOffset(speech, cueFrames(doc.Cues), vsm.resyncWindow)
+	shifted, err := shiftSRT(vsm.fs, result.NewSubtitlePath, offset)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to resync subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error resyncing: %v\n", err)
+		}
+		return result
+	}
+
+	if shifted {
+		result.SyncOffset = offset.Seconds()
+		if vsm.verbose {
+			vsm.printf(colorGreen, "  ✓ Resynced by %+.1fs\n", offset.Seconds())
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// transientErrors are errors worth retrying: busy files, interrupted calls
+// and the hiccups of network filesystems such as SMB or NFS.
+var transientErrors = []error{
+	syscall.EBUSY,
+	syscall.EAGAIN,
+	syscall.EINTR,
+	syscall.EIO,
+	syscall.ESTALE,
+	syscall.ETIMEDOUT,
+}
+
+// isTransient reports whether err may go away when the operation is retried.
+func isTransient(err error) bool {
+	for _, transient := range transientErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs op, retrying it after transient errors with exponential
+// backoff until the configured number of attempts is used up or ctx ends.
+// Each attempt is bounded by the OperationTimeout; timed out attempts are not
+// retried.
+func (vsm *VideoSubtitleMatcher) withRetry(ctx context.Context, op func(context.Context) error) error {
+	delay := vsm.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := vsm.withTimeout(ctx, op)
+		if err == nil || !isTransient(err) || attempt >= vsm.retryAttempts {
+			return err
+		}
+
+		if vsm.verbose && !vsm.writesPlan() {
+			vsm.printf(colorYellow, "  Retrying in %v after error: %v\n", delay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
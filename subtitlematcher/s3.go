@@ -0,0 +1,331 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config describes a bucket on Amazon S3 or an S3-compatible service such
+// as MinIO.
+type S3Config struct {
+	Endpoint  string // e.g. "http://minio.local:9000"; empty for Amazon S3
+	Region    string // e.g. "us-east-1"
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as endpoint/bucket instead of
+	// bucket.endpoint, as most self-hosted services require.
+	PathStyle bool
+}
+
+// S3FileSystem is a FileSystem on an S3 bucket. Paths are object keys with a
+// leading slash, and directories are key prefixes. S3 has no rename, so
+// Rename copies the object and deletes the original.
+type S3FileSystem struct {
+	config S3Config
+	base   *url.URL
+	client *http.Client
+}
+
+// NewS3FileSystem returns a file system for the bucket described by config.
+func NewS3FileSystem(config S3Config) (*S3FileSystem, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("invalid S3 configuration: bucket is required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	base.Path = ""
+	if config.PathStyle {
+		base.Path = "/" + config.Bucket
+	} else {
+		base.Host = config.Bucket + "." + base.Host
+	}
+
+	return &S3FileSystem{config: config, base: base, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// s3ListResult is the body of a ListObjectsV2 response.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// Walk implements FileSystem. Without recursion only objects directly below
+// dir are visited.
+func (s *S3FileSystem) Walk(dir string, recursive bool, fn func(path string) error) error {
+	query := url.Values{"list-type": {"2"}, "prefix": {dirPrefix(dir)}}
+	if !recursive {
+		query.Set("delimiter", "/")
+	}
+
+	for {
+		list, err := s.list(dir, query)
+		if err != nil {
+			return err
+		}
+		for _, object := range list.Contents {
+			if strings.HasSuffix(object.Key, "/") {
+				continue // directory marker
+			}
+			if err := fn("/" + object.Key); err != nil {
+				return err
+			}
+		}
+		if !list.IsTruncated {
+			return nil
+		}
+		query.Set("continuation-token", list.NextContinuationToken)
+	}
+}
+
+// Open implements FileSystem.
+func (s *S3FileSystem) Open(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, objectKey(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHTTPStatus(resp, "open", name); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// WriteFile implements FileSystem.
+func (s *S3FileSystem) WriteFile(name string, data []byte) error {
+	return s.call(http.MethodPut, "write", name, data, nil)
+}
+
+// Rename implements FileSystem by copying the object and deleting the original.
+func (s *S3FileSystem) Rename(oldPath, newPath string) error {
+	source := "/" + s.config.Bucket + "/" + s3Escape(objectKey(oldPath), false)
+	headers := map[string]string{"X-Amz-Copy-Source": source}
+	if err := s.call(http.MethodPut, "rename", newPath, nil, headers); err != nil {
+		return err
+	}
+	return s.Remove(oldPath)
+}
+
+// Remove implements FileSystem.
+func (s *S3FileSystem) Remove(name string) error {
+	// S3 reports success for missing keys, so check first to match os.Remove
+	if _, err := s.Stat(name); err != nil {
+		return err
+	}
+	return s.call(http.MethodDelete, "remove", name, nil, nil)
+}
+
+// Stat implements FileSystem. A path that is a prefix of other keys is
+// reported as a directory.
+func (s *S3FileSystem) Stat(name string) (fs.FileInfo, error) {
+	resp, err := s.do(http.MethodHead, objectKey(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		list, err := s.list(name, url.Values{"list-type": {"2"}, "prefix": {dirPrefix(name)}, "max-keys": {"1"}})
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Contents) == 0 {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+		return remoteFileInfo{path: cleanRemotePath(name), isDir: true}, nil
+	}
+	if err := checkHTTPStatus(resp, "stat", name); err != nil {
+		return nil, err
+	}
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return remoteFileInfo{path: cleanRemotePath(name), size: size, modTime: modTime}, nil
+}
+
+// list runs one ListObjectsV2 request.
+func (s *S3FileSystem) list(dir string, query url.Values) (*s3ListResult, error) {
+	resp, err := s.do(http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkHTTPStatus(resp, "list", dir); err != nil {
+		return nil, err
+	}
+
+	var list s3ListResult
+	if err := xml.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("list %s: invalid response: %w", dir, err)
+	}
+	return &list, nil
+}
+
+// call sends a request whose response body is not needed.
+func (s *S3FileSystem) call(method, op, name string, body []byte, headers map[string]string) error {
+	resp, err := s.do(method, objectKey(name), nil, body, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkHTTPStatus(resp, op, name)
+}
+
+// do sends a signed request for the object key, or for the bucket when key is empty.
+func (s *S3FileSystem) do(method, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	escapedPath := s.base.Path + "/" + s3Escape(key, false)
+	rawURL := s.base.Scheme + "://" + s.base.Host + escapedPath
+	if len(query) > 0 {
+		rawURL += "?" + canonicalS3Query(query)
+	}
+
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	signS3Request(req, body, s.config, time.Now())
+	return s.client.Do(req)
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req, signing the host
+// and every header already set on it.
+func signS3Request(req *http.Request, body []byte, config S3Config, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes an HMAC-SHA256 of data.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalS3Query encodes query parameters sorted by name, as signing requires.
+func canonicalS3Query(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and slashes
+// unless encodeSlash is set, as AWS signing requires.
+func s3Escape(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// objectKey converts a file system path to an object key.
+func objectKey(name string) string {
+	return strings.TrimPrefix(cleanRemotePath(name), "/")
+}
+
+// dirPrefix returns the key prefix of the objects in a directory.
+func dirPrefix(dir string) string {
+	key := objectKey(dir)
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
@@ -0,0 +1,89 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// samplePattern matches "sample" as a separate word of a video's name, as in
+// "sample.mkv" or "Movie.2020.1080p-GROUP-sample.mkv".
+var samplePattern = regexp.MustCompile(`(?i)(^|[\s._-])sample([\s._-]|$)`)
+
+// defaultExtrasFolders are the folders that hold samples and bonus material
+// rather than features, following the Plex and Jellyfin conventions.
+var defaultExtrasFolders = []string{
+	"sample", "samples", "extras", "featurettes", "behind the scenes",
+	"deleted scenes", "interviews", "scenes", "shorts", "trailers", "other",
+}
+
+// defaultSampleSize is the size under which a video next to a much larger one
+// is taken for a sample clip of it.
+const defaultSampleSize = 100 << 20
+
+// sampleSizeRatio is how many times larger than a small video another video
+// in its folder must be for the small one to count as its sample.
+const sampleSizeRatio = 20
+
+// excludeSampleVideos drops sample clips and extras from the candidate
+// videos, so that subtitles match the feature instead.
+func (vsm *VideoSubtitleMatcher) excludeSampleVideos(videoFiles []string) []string {
+	if !vsm.excludeSamples {
+		return videoFiles
+	}
+
+	var sizes map[string]int64
+	largest := make(map[string]int64)
+	if vsm.sampleSize > 0 {
+		sizes = make(map[string]int64, len(videoFiles))
+		for _, videoPath := range videoFiles {
+			info, err := vsm.fs.Stat(videoPath)
+			if err != nil {
+				continue
+			}
+			sizes[videoPath] = info.Size()
+			dir := filepath.Dir(videoPath)
+			largest[dir] = max(largest[dir], info.Size())
+		}
+	}
+
+	features := videoFiles[:0:0]
+	for _, videoPath := range videoFiles {
+		reason := vsm.sampleReason(videoPath)
+		if size, ok := sizes[videoPath]; ok && reason == "" && size < vsm.sampleSize &&
+			largest[filepath.Dir(videoPath)] > size*sampleSizeRatio {
+			reason = "much smaller than the video next to it"
+		}
+		if reason != "" {
+			if vsm.verbose {
+				vsm.printf(colorGray, "Skipping sample or extra %s: %s\n", filepath.Base(videoPath), reason)
+			}
+			continue
+		}
+		features = append(features, videoPath)
+	}
+	return features
+}
+
+// sampleReason explains why a video's name or folder marks it as a sample
+// or extra, or returns "".
+func (vsm *VideoSubtitleMatcher) sampleReason(videoPath string) string {
+	if samplePattern.MatchString(strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))) {
+		return "named as a sample"
+	}
+
+	// Only folders below the scanned directory count, so that matching
+	// inside an "Extras" folder itself still works
+	rel, err := filepath.Rel(vsm.directory, filepath.Dir(videoPath))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	for _, folder := range strings.Split(filepath.ToSlash(rel), "/") {
+		for _, extras := range vsm.extrasFolders {
+			if strings.EqualFold(folder, extras) {
+				return "in " + folder + " folder"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ScriptShell selects the shell a rename script is written for.
+type ScriptShell int
+
+const (
+	// ScriptBash writes a bash script of mv commands.
+	ScriptBash ScriptShell = iota
+	// ScriptPowerShell writes a PowerShell script of Rename-Item and Move-Item commands.
+	ScriptPowerShell
+)
+
+// WriteScript writes the renames in results as an executable script for
+// shell, grouped by directory, so the plan can be reviewed and run later:
+//
+//	#!/usr/bin/env bash
+//	set -euo pipefail
+//
+//	# /videos/Season 1
+//	mv -n -- '/videos/Season 1/Episode_1.en.srt' '/videos/Season 1/Show.S01E01.en.srt'
+//
+// The commands never replace a file that exists at the new name by the time
+// the script runs: mv skips such renames and PowerShell stops with an error. VobSub .sub files are moved along with their .idx.
+// Renames a script cannot perform, such as format conversions and
+// extractions from archives, are listed as comments. Unmatched, invalid,
+// duplicate and already correctly named subtitles are omitted.
+func WriteScript(w io.Writer, results []MatchResult, shell ScriptShell) error {
+	var renames []MatchResult
+	for _, result := range results {
+		if willRename(result) {
+			renames = append(renames, result)
+		}
+	}
+
+	sort.SliceStable(renames, func(i, j int) bool {
+		di, dj := filepath.Dir(renames[i].SubtitlePath), filepath.Dir(renames[j].SubtitlePath)
+		if di != dj {
+			return di < dj
+		}
+		return renames[i].SubtitlePath < renames[j].SubtitlePath
+	})
+
+	header := "#!/usr/bin/env bash\nset -euo pipefail\n"
+	if shell == ScriptPowerShell {
+		header = "$ErrorActionPreference = 'Stop'\n"
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	currentDir := ""
+	for i, result := range renames {
+		dir := filepath.Dir(result.SubtitlePath)
+		if i == 0 || dir != currentDir {
+			if _, err := fmt.Fprintf(w, "\n# %s\n", commentText(dir)); err != nil {
+				return err
+			}
+			currentDir = dir
+		}
+
+		if _, err := io.WriteString(w, scriptCommands(result, shell)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptCommands returns the commands performing one rename, or a comment
+// when a script cannot perform it.
+func scriptCommands(result MatchResult, shell ScriptShell) string {
+	switch {
+	case result.Archive != "":
+		return fmt.Sprintf("# skipped: %s must be extracted from %s\n",
+			commentText(filepath.Base(result.SubtitlePath)), commentText(result.Archive))
+	case !strings.EqualFold(filepath.Ext(result.SubtitlePath), filepath.Ext(result.NewSubtitlePath)):
+		return fmt.Sprintf("# skipped: %s must be converted to %s\n",
+			commentText(filepath.Base(result.SubtitlePath)), commentText(filepath.Base(result.NewSubtitlePath)))
+	}
+
+	var b strings.Builder
+	if result.PairedPath != "" {
+		b.WriteString(moveCommand(result.PairedPath, withExt(result.NewSubtitlePath, vobSubDataExt), shell))
+	}
+	b.WriteString(moveCommand(result.SubtitlePath, result.NewSubtitlePath, shell))
+	return b.String()
+}
+
+// moveCommand returns the command moving oldPath to newPath in shell,
+// leaving any file already at newPath alone.
+func moveCommand(oldPath, newPath string, shell ScriptShell) string {
+	if shell == ScriptPowerShell {
+		if filepath.Dir(oldPath) == filepath.Dir(newPath) {
+			return fmt.Sprintf("Rename-Item -LiteralPath %s -NewName %s\n",
+				powerShellQuote(oldPath), powerShellQuote(filepath.Base(newPath)))
+		}
+		return fmt.Sprintf("Move-Item -LiteralPath %s -Destination %s\n",
+			powerShellQuote(oldPath), powerShellQuote(newPath))
+	}
+	return fmt.Sprintf("mv -n -- %s %s\n", shellQuote(oldPath), shellQuote(newPath))
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellQuote quotes s as a verbatim PowerShell string. PowerShell also
+// accepts the typographic single quotes as quote characters, so they are
+// doubled like the ASCII one.
+func powerShellQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '\u2018', '\u2019', '\u201A', '\u201B':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// commentText returns path for use in a script comment. A path holding
+// control or other unprintable characters, such as a newline that would end
+// the comment and run the rest of the name as a command, is written as an
+// escaped Go string instead.
+func commentText(path string) string {
+	if strings.IndexFunc(path, func(r rune) bool { return !unicode.IsPrint(r) }) < 0 {
+		return path
+	}
+	return strconv.Quote(path)
+}
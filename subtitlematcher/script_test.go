@@ -0,0 +1,30 @@
+package subtitlematcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteScriptEscapesNamesInComments(t *testing.T) {
+	results := []MatchResult{
+		{SubtitlePath: "/videos/a\nrm -rf x/b.vtt", NewSubtitlePath: "/videos/a\nrm -rf x/B.srt"},
+		{SubtitlePath: "/videos/c.srt", NewSubtitlePath: "/videos/C.srt", Archive: "/videos/subs\nrm -rf y.zip"},
+	}
+
+	var b strings.Builder
+	if err := WriteScript(&b, results, ScriptBash); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, "rm ") {
+			t.Errorf("name escaped its comment:\n%s", b.String())
+		}
+	}
+}
+
+func TestPowerShellQuoteDoublesTypographicQuotes(t *testing.T) {
+	got := powerShellQuote("Don’t Look Up.srt")
+	if want := "'Don’’t Look Up.srt'"; got != want {
+		t.Errorf("powerShellQuote = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SDHMode controls how subtitles for the deaf and hard of hearing (SDH) are treated.
+type SDHMode int
+
+const (
+	// SDHIgnore treats SDH subtitles like any other subtitle.
+	SDHIgnore SDHMode = iota
+	// SDHTag adds ".sdh" to the new name of every SDH subtitle, e.g. "Movie.sdh.srt".
+	SDHTag
+	// SDHDeprioritize lets a regular subtitle take the canonical name when it
+	// competes with an SDH subtitle for the same video. The SDH subtitle is
+	// tagged with ".sdh" instead of overwriting it.
+	SDHDeprioritize
+)
+
+// sdhTag is inserted before the extension of tagged SDH subtitles.
+const sdhTag = ".sdh"
+
+// sdhContentSampleLines is the number of lines read when sniffing subtitle content.
+const sdhContentSampleLines = 400
+
+// sdhContentThreshold is the number of sound description lines needed to
+// consider a subtitle SDH based on its content.
+const sdhContentThreshold = 3
+
+// sdhNamePattern matches SDH markers in a filename such as "SDH" or
+// "hearing impaired". The short "CC" marker only counts as a tag (".CC.",
+// "[CC]") so that titles containing the letters are not flagged. "HI" is also
+// the ISO 639-1 code for Hindi, so here it only counts in brackets ("[HI]");
+// a dotted ".hi." tag is checked by hasHITag.
+var sdhNamePattern = regexp.MustCompile(`(?i)(?:^|[^a-z])sdh(?:[^a-z]|$)|hearing[ ._-]?impaired|[.\[(_-]cc(?:[.\])_-]|$)|[\[(]hi[\])]`)
+
+// soundDescriptionPattern matches a cue line consisting only of a bracketed
+// sound description such as "[door slams]" or "(laughing)". Lines between
+// music notes are not counted: regular subtitles use them for song lyrics.
+var soundDescriptionPattern = regexp.MustCompile(`^\s*(?:-\s*)?(?:\[[^\]]+\]|\([^)]+\))\s*$`)
+
+// assSectionPattern matches ASS/SSA section headers, which look like sound descriptions.
+var assSectionPattern = regexp.MustCompile(`^\[(?:Script Info|V4\+? Styles|Events|Fonts|Graphics)\]$`)
+
+// isSDH reports whether a subtitle file is intended for the deaf and hard of
+// hearing, based on markers in its filename or sound descriptions in its content.
+func isSDH(fsys FileSystem, subtitlePath string) bool {
+	name := strings.TrimSuffix(filepath.Base(subtitlePath), filepath.Ext(subtitlePath))
+	if sdhNamePattern.MatchString(name) || hasHITag(name) {
+		return true
+	}
+	return hasSoundDescriptions(fsys, subtitlePath)
+}
+
+// hasHITag reports whether a filename without extension has a dot-separated
+// "HI" tag following a language tag, as in "Movie.en.hi". A "hi" tag on its
+// own ("Movie.hi") is the language code of Hindi.
+func hasHITag(name string) bool {
+	segments := strings.Split(name, ".")
+	for i := 2; i < len(segments); i++ {
+		if strings.EqualFold(segments[i], "hi") && suffixLanguage(segments[i-1]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSoundDescriptions scans the beginning of a subtitle file for bracketed
+// sound descriptions. Unreadable and image-based files are reported as not SDH.
+func hasSoundDescriptions(fsys FileSystem, subtitlePath string) bool {
+	if isImageSubtitle(fsys, subtitlePath) {
+		return false
+	}
+
+	file, err := fsys.Open(subtitlePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for lines := 0; lines < sdhContentSampleLines && scanner.Scan(); lines++ {
+		line := strings.TrimSpace(scanner.Text())
+		if assSectionPattern.MatchString(line) {
+			continue
+		}
+		if soundDescriptionPattern.MatchString(line) {
+			count++
+			if count >= sdhContentThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deprioritizeSDH re-targets SDH subtitles that compete with a regular
+// subtitle for the same new name, so the regular subtitle keeps the canonical name.
+func deprioritizeSDH(results []MatchResult) {
+	hasRegular := make(map[string]bool)
+	for _, result := range results {
+		if result.NewSubtitlePath != "" && !result.SDH {
+			hasRegular[result.NewSubtitlePath] = true
+		}
+	}
+
+	for i := range results {
+		if results[i].SDH && hasRegular[results[i].NewSubtitlePath] {
+			results[i].NewSubtitlePath = insertTag(results[i].NewSubtitlePath, sdhTag)
+		}
+	}
+}
+
+// insertTag inserts a tag such as ".sdh" between a path's base name and its extension.
+func insertTag(path, tag string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + tag + ext
+}
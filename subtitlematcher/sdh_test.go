@@ -0,0 +1,41 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsSDHFromName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Movie.sdh.srt", true},
+		{"Movie.en.hi.srt", true},
+		{"Movie [HI].srt", true},
+		{"Movie (hi).srt", true},
+		{"Movie.CC.srt", true},
+		{"Movie.hi.srt", false}, // Hindi
+		{"Hi Score Girl.srt", false},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		if got := isSDH(LocalFileSystem{}, filepath.Join(dir, tt.name)); got != tt.want {
+			t.Errorf("isSDH(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsSDHIgnoresSongLyrics(t *testing.T) {
+	dir := t.TempDir()
+	lyrics := "1\n00:00:01,000 --> 00:00:02,000\n♪ Never gonna give you up ♪\n\n" +
+		"2\n00:00:02,000 --> 00:00:03,000\n♪ Never gonna let you down ♪\n\n" +
+		"3\n00:00:03,000 --> 00:00:04,000\n♪ Never gonna run around ♪\n\n" +
+		"4\n00:00:04,000 --> 00:00:05,000\n♪ And desert you ♪\n"
+	writeSubtitles(t, dir, map[string]string{"Movie.srt": lyrics})
+
+	if isSDH(LocalFileSystem{}, filepath.Join(dir, "Movie.srt")) {
+		t.Error("subtitle with song lyrics treated as SDH")
+	}
+}
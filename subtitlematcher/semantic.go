@@ -0,0 +1,198 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// semanticDefaultThreshold is the cosine similarity a video's title needs
+// in the semantic pass unless SemanticMatching sets another.
+const semanticDefaultThreshold = 0.8
+
+// embedBatch is the number of titles sent to an Embedder at once.
+const embedBatch = 100
+
+// Embedder turns titles into embedding vectors whose cosine similarity
+// measures how close their meanings are, e.g. with a multilingual embedding
+// model. See SemanticMatching.
+type Embedder interface {
+	// Embed returns a vector for each text, in the same order.
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// OpenAIEmbedder embeds titles with an OpenAI-compatible embeddings API:
+// OpenAI's, or a local server offering the same endpoint, such as Ollama
+// or the llama.cpp server.
+type OpenAIEmbedder struct {
+	URL    string // Embeddings endpoint, e.g. "http://localhost:11434/v1/embeddings"
+	APIKey string // Bearer token ("" for servers that need none)
+	Model  string // Model name, e.g. "bge-m3"; multilingual models bridge translated titles
+}
+
+// NewOpenAIEmbedder returns an embedder using model at the embeddings
+// endpoint at url, authenticated with apiKey.
+func NewOpenAIEmbedder(url, apiKey, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{URL: url, APIKey: apiKey, Model: model}
+}
+
+// Embed embeds the texts.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	body, err := json.Marshal(map[string]any{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embedding rejected: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid embedding response: %w", err)
+	}
+	vectors := make([][]float64, len(texts))
+	for _, item := range response.Data {
+		if item.Index >= 0 && item.Index < len(vectors) {
+			vectors[item.Index] = item.Embedding
+		}
+	}
+	return vectors, nil
+}
+
+// titleVectors maps titles to their embeddings.
+type titleVectors map[string][]float64
+
+// semanticTitle returns the part of a normalized title that carries its
+// meaning: the words before its episode marker or year, without release
+// details and separators that would only blur the embedding.
+func semanticTitle(normalizedTitle string) string {
+	title := movieTitle(normalizedTitle)
+	if _, span, ok := findEpisode(normalizedTitle); ok && strings.TrimSpace(normalizedTitle[:span[0]]) != "" {
+		title = normalizedTitle[:span[0]]
+	}
+	return strings.Join(strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// subtitleSemanticTitle returns the semantic title of a subtitle.
+func (vsm *VideoSubtitleMatcher) subtitleSemanticTitle(subtitlePath string) string {
+	return semanticTitle(stripPart(vsm.normalizeTitle(subtitleTitle(subtitlePath))))
+}
+
+// embedTitles embeds the semantic titles of the videos and subtitles of a
+// run in batches, so that the semantic pass can compare them without
+// calling the Embedder again.
+func (vsm *VideoSubtitleMatcher) embedTitles(ctx context.Context, videoFiles, subtitleFiles []string) error {
+	seen := make(map[string]bool)
+	var titles []string
+	add := func(title string) {
+		if title != "" && !seen[title] {
+			seen[title] = true
+			titles = append(titles, title)
+		}
+	}
+	for _, videoPath := range videoFiles {
+		add(semanticTitle(vsm.strippedVideo(videoPath)))
+	}
+	for _, subtitlePath := range subtitleFiles {
+		add(vsm.subtitleSemanticTitle(subtitlePath))
+	}
+
+	vsm.titleVectors = make(titleVectors, len(titles))
+	for start := 0; start < len(titles); start += embedBatch {
+		batch := titles[start:min(start+embedBatch, len(titles))]
+		vectors, err := vsm.embedder.Embed(ctx, batch)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedded %d of %d titles", len(vectors), len(batch))
+		}
+		for i, title := range batch {
+			vsm.titleVectors[title] = vectors[i]
+		}
+	}
+	return nil
+}
+
+// semanticMatch runs the semantic pass: it returns the video whose title
+// is closest in meaning to the subtitle's, its cosine similarity and that of
+// the runner-up. Videos ruled out by their multi-part marker, media mode,
+// episode or year are skipped. Returns "" unless the closest video reaches
+// the semantic threshold and clearly leads the runner-up, as titles of one
+// show or franchise embed alike.
+func (vsm *VideoSubtitleMatcher) semanticMatch(subtitlePath string, videoFiles []string) (string, float64, float64) {
+	vector := vsm.titleVectors[vsm.subtitleSemanticTitle(subtitlePath)]
+	if vector == nil {
+		return "", 0, 0
+	}
+
+	normalizedSubtitle := vsm.normalizeTitle(subtitleTitle(subtitlePath))
+	compatible := vsm.candidateScorer(subtitlePath)
+	var bestMatch string
+	var bestScore, runnerUpScore float64
+	for _, videoPath := range videoFiles {
+		if _, ok := compatible(videoPath); !ok || signalAgreement(normalizedSubtitle, vsm.normalizedVideo(videoPath)) < 0 {
+			continue
+		}
+		score := cosineSimilarity(vector, vsm.titleVectors[semanticTitle(vsm.strippedVideo(videoPath))])
+		if score > bestScore {
+			runnerUpScore = bestScore
+			bestScore = score
+			bestMatch = videoPath
+		} else if score > runnerUpScore {
+			runnerUpScore = score
+		}
+	}
+
+	if bestScore < vsm.semanticThreshold || bestScore-runnerUpScore < mediumConfidenceMargin {
+		return "", 0, 0
+	}
+	return bestMatch, bestScore, runnerUpScore
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, clamped to
+// 0.0-1.0, or 0 when they differ in length or either is zero.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return max(0, min(1, dot/math.Sqrt(normA*normB)))
+}
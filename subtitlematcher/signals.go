@@ -0,0 +1,93 @@
+package subtitlematcher
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// episodePatterns match episode markers in a normalized title, capturing the
+// season (optional) and episode numbers: "s1e2", "1x2", "episode 2", "ep 2",
+// and numbered specials (see specialPatternIndex).
+var episodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[^a-z0-9])s(\d{1,2}) ?e(\d{1,4})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^a-z0-9])(\d{1,2})x(\d{1,3})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^a-z0-9])()(?:episode|ep|e) ?(\d{1,4})(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^a-z0-9])()(?:sp|special|ova|oad|ona) ?(\d{1,3})(?:[^0-9]|$)`),
+}
+
+// yearPattern matches a plausible release year in a normalized title.
+var yearPattern = regexp.MustCompile(`(?:^|[^0-9])((?:19|20)\d{2})(?:[^0-9]|$)`)
+
+// episodeNumber identifies an episode. Season is 0 when the title carries
+// only an episode number, or for specials. Last is the last episode of a
+// multi-episode file ("S01E01E02"), and 0 otherwise.
+type episodeNumber struct {
+	Season  int
+	Episode int
+	Last    int
+	Special bool
+}
+
+// detectEpisode returns the episode marker found in a normalized title.
+func detectEpisode(normalizedTitle string) (episodeNumber, bool) {
+	episode, _, ok := findEpisode(normalizedTitle)
+	return episode, ok
+}
+
+// findEpisode returns the episode marker found in a normalized title and
+// its span, from the first letter or digit of the marker to the end of the
+// last episode number.
+func findEpisode(normalizedTitle string) (episodeNumber, [2]int, bool) {
+	for i, pattern := range episodePatterns {
+		if m := pattern.FindStringSubmatchIndex(normalizedTitle); m != nil {
+			season, seasonErr := strconv.Atoi(normalizedTitle[m[2]:m[3]])
+			episode, _ := strconv.Atoi(normalizedTitle[m[4]:m[5]])
+			start := m[0]
+			if !isAlphanumeric(normalizedTitle[start]) {
+				start++
+			}
+			found := episodeNumber{Season: season, Episode: episode}
+			found.Special = i == specialPatternIndex || (seasonErr == nil && season == 0)
+			end := extendEpisode(normalizedTitle, &found, m[5])
+			return found, [2]int{start, end}, true
+		}
+	}
+	return episodeNumber{}, [2]int{}, false
+}
+
+// detectYear returns the release year found in a normalized title, or 0.
+func detectYear(normalizedTitle string) int {
+	m := yearPattern.FindStringSubmatch(normalizedTitle)
+	if m == nil {
+		return 0
+	}
+	year, _ := strconv.Atoi(m[1])
+	return year
+}
+
+// signalAgreement compares the episode and year signals of two normalized
+// titles. It returns -1 if any signal present in both titles disagrees, 1 if
+// at least one agrees and none disagree, and 0 if no signal can be compared.
+func signalAgreement(subtitleTitle, videoTitle string) int {
+	agreement := 0
+
+	subtitleEpisode, subtitleHasEpisode := detectEpisode(subtitleTitle)
+	videoEpisode, videoHasEpisode := detectEpisode(videoTitle)
+	if subtitleHasEpisode && videoHasEpisode {
+		if subtitleEpisode.Episode != videoEpisode.Episode ||
+			(subtitleEpisode.Season != 0 && videoEpisode.Season != 0 && subtitleEpisode.Season != videoEpisode.Season) {
+			return -1
+		}
+		agreement = 1
+	}
+
+	subtitleYear, videoYear := detectYear(subtitleTitle), detectYear(videoTitle)
+	if subtitleYear != 0 && videoYear != 0 {
+		if subtitleYear != videoYear {
+			return -1
+		}
+		agreement = 1
+	}
+
+	return agreement
+}
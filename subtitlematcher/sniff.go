@@ -0,0 +1,78 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sniffBytes is how much of a file is read to recognize its format.
+const sniffBytes = 4096
+
+// srtTimingPattern matches an SRT cue timing line, which uses a comma before
+// the milliseconds where WebVTT uses a dot.
+var srtTimingPattern = regexp.MustCompile(`^\d{1,2}:\d{2}:\d{2},\d{3}\s+-->\s+\d{1,2}:\d{2}:\d{2},\d{3}`)
+
+// sniffFormat recognizes a subtitle by its content and returns the extension
+// it should have: ".srt", ".vtt", ".ass", ".ssa", ".smi", ".sbv" or ".sub"
+// for MicroDVD. Returns "" for anything else, including unreadable files.
+func sniffFormat(fsys FileSystem, path string) string {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(io.LimitReader(file, sniffBytes))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), utf8BOM))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	first := lines[0]
+	switch {
+	case first == "WEBVTT" || strings.HasPrefix(first, "WEBVTT "):
+		return ".vtt"
+	case strings.EqualFold(first, "[Script Info]"):
+		for _, line := range lines {
+			if strings.EqualFold(line, "ScriptType: v4.00") {
+				return ".ssa"
+			}
+		}
+		return ".ass"
+	case strings.HasPrefix(strings.ToUpper(first), "<SAMI"):
+		return ".smi"
+	case sbvTimingPattern.MatchString(first):
+		return ".sbv"
+	case microDVDLinePattern.MatchString(first):
+		return ".sub"
+	case cueIndexPattern.MatchString(first) && len(lines) > 1 && srtTimingPattern.MatchString(lines[1]):
+		return ".srt"
+	}
+	return ""
+}
+
+// subtitleFormat returns the lowercase extension describing a subtitle's
+// format: the one detected from its content, or else its own.
+func subtitleFormat(result MatchResult) string {
+	if result.Format != "" {
+		return result.Format
+	}
+	return strings.ToLower(filepath.Ext(result.SubtitlePath))
+}
+
+// isSniffCandidate reports whether a scanned file that is neither a video nor
+// a known subtitle should have its content checked. Matcher state files are
+// never subtitles.
+func isSniffCandidate(path string) bool {
+	name := filepath.Base(path)
+	return name != lockFileName && name != journalFileName && name != processedFileName && !isArchive(path)
+}
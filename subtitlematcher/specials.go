@@ -0,0 +1,38 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// specialPatternIndex is the index in episodePatterns of the pattern
+// matching numbered specials: "sp1", "special 2", "ova 3", "oad 1".
+const specialPatternIndex = 3
+
+// specialWordPattern matches the unnumbered markers of specials in a
+// normalized title. A bare "special" is left out, as in "Christmas Special"
+// it is usually part of an episode's title.
+var specialWordPattern = regexp.MustCompile(`(?:^|[^a-z0-9])(?:ova|oad|ona)(?:[^a-z0-9]|$)`)
+
+// specialsFolder reports whether path is in a folder of specials:
+// "Specials", "Season 0" or "S00".
+func specialsFolder(path string) bool {
+	folder := filepath.Base(filepath.Dir(path))
+	if strings.EqualFold(folder, "specials") {
+		return true
+	}
+	m := seasonFolderPattern.FindStringSubmatch(folder)
+	return m != nil && strings.Trim(m[1], "0") == ""
+}
+
+// isSpecial reports whether a normalized title, or the folder of its file,
+// marks a special: an episode of season 0 ("S00E01", "0x01"), a numbered
+// special ("SP1", "Special 2", "OVA 3"), an OVA, or anything in a Specials
+// or Season 0 folder.
+func (vsm *VideoSubtitleMatcher) isSpecial(normalizedTitle, path string) bool {
+	if episode, _, ok := vsm.findEpisodeMarker(normalizedTitle, path); ok && episode.Special {
+		return true
+	}
+	return specialWordPattern.MatchString(normalizedTitle) || specialsFolder(path)
+}
@@ -0,0 +1,162 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// bilingualCueRatio is the share of cues that must contain both a CJK and a
+// Latin line for a subtitle to be considered bilingual.
+const bilingualCueRatio = 0.5
+
+// lineScript classifies a line of subtitle text as "zh", "ja", "ko" for CJK
+// scripts, "en" for Latin script, or "" when it has no letters.
+func lineScript(line string) string {
+	script := ""
+	for _, r := range line {
+		switch {
+		case unicode.Is(unicode.Hangul, r):
+			return "ko"
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return "ja"
+		case unicode.Is(unicode.Han, r):
+			script = "zh"
+		case script == "" && unicode.IsLetter(r):
+			script = "en"
+		}
+	}
+	return script
+}
+
+// splitByScript separates cue lines into a CJK and a Latin cue list and
+// reports whether the cues are bilingual. The CJK language code is returned too.
+func splitByScript(cues []srtCue) (cjk, latin []srtCue, cjkLanguage string, bilingual bool) {
+	withText, mixed := 0, 0
+
+	for _, cue := range cues {
+		var cjkLines, latinLines []string
+		for _, line := range cue.Lines {
+			switch script := lineScript(markupPattern.ReplaceAllString(line, "")); script {
+			case "":
+			case "en":
+				latinLines = append(latinLines, line)
+			default:
+				cjkLanguage = script
+				cjkLines = append(cjkLines, line)
+			}
+		}
+
+		if len(cjkLines) > 0 || len(latinLines) > 0 {
+			withText++
+		}
+		if len(cjkLines) > 0 && len(latinLines) > 0 {
+			mixed++
+		}
+		if len(cjkLines) > 0 {
+			cjk = append(cjk, srtCue{Start: cue.Start, End: cue.End, Lines: cjkLines})
+		}
+		if len(latinLines) > 0 {
+			latin = append(latin, srtCue{Start: cue.Start, End: cue.End, Lines: latinLines})
+		}
+	}
+
+	bilingual = withText > 0 && float64(mixed)/float64(withText) >= bilingualCueRatio
+	return cjk, latin, cjkLanguage, bilingual
+}
+
+// splitSubtitle splits a renamed bilingual .srt subtitle into one file per
+// language next to it, e.g. "Movie.zh.srt" and "Movie.en.srt" for "Movie.srt"
+// or "Movie.zh.srt". Files that exist or are planned for other subtitles are
+// never overwritten, unless they already hold the same split. In dry run mode
+// the files that would be written are reported but not created.
+func (vsm *VideoSubtitleMatcher) splitSubtitle(result MatchResult) MatchResult {
+	source := result.SubtitlePath
+	if result.Renamed {
+		source = result.NewSubtitlePath
+	}
+	if !strings.EqualFold(filepath.Ext(source), ".srt") {
+		return result
+	}
+
+	doc, err := readSRT(vsm.fs, source)
+	if err != nil || doc.Invalid {
+		return result
+	}
+
+	cjk, latin, cjkLanguage, bilingual := splitByScript(doc.Cues)
+	if !bilingual {
+		return result
+	}
+
+	base := withoutLanguageTag(strings.TrimSuffix(result.NewSubtitlePath, filepath.Ext(result.NewSubtitlePath)))
+	outputs := []struct {
+		path string
+		cues []srtCue
+	}{
+		{base + "." + cjkLanguage + ".srt", cjk},
+		{base + ".en.srt", latin},
+	}
+
+	var splitErr error
+	for _, output := range outputs {
+		if output.path == result.NewSubtitlePath {
+			// Already tagged with this language; the bilingual original stays
+			continue
+		}
+		data := []byte(formatSRT(output.cues, "\n"))
+		if existing, err := readFile(vsm.fs, output.path); err == nil {
+			if !bytes.Equal(existing, data) {
+				splitErr = fmt.Errorf("%s already exists", filepath.Base(output.path))
+				break
+			}
+			// Split by an earlier run
+			result.SplitSubtitlePaths = append(result.SplitSubtitlePaths, output.path)
+			continue
+		}
+		if vsm.plannedNames[output.path] {
+			splitErr = fmt.Errorf("%s is planned for another subtitle", filepath.Base(output.path))
+			break
+		}
+
+		vsm.plannedNames[output.path] = true
+		result.SplitSubtitlePaths = append(result.SplitSubtitlePaths, output.path)
+		if vsm.dryRun {
+			continue
+		}
+		if splitErr = vsm.fs.WriteFile(output.path, data); splitErr != nil {
+			break
+		}
+	}
+	if splitErr != nil {
+		result.Error = fmt.Errorf("failed to split subtitle: %w", splitErr)
+	}
+
+	vsm.logSplit(result, splitErr)
+	return result
+}
+
+// logSplit logs the per-language files produced from a bilingual subtitle
+func (vsm *VideoSubtitleMatcher) logSplit(result MatchResult, err error) {
+	if !vsm.verbose {
+		return
+	}
+	if err != nil {
+		vsm.printf(colorRed, "  Error splitting: %v\n", err)
+	}
+	if len(result.SplitSubtitlePaths) == 0 {
+		return
+	}
+
+	verb := "Split into"
+	if vsm.dryRun {
+		verb = "Would split into"
+	}
+	names := make([]string, len(result.SplitSubtitlePaths))
+	for i, path := range result.SplitSubtitlePaths {
+		names[i] = filepath.Base(path)
+	}
+	fmt.Printf("  %s: %s\n", verb, strings.Join(names, ", "))
+}
@@ -0,0 +1,99 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// bilingualSRT has Chinese and English on alternating lines of every cue.
+	bilingualSRT = "1\n00:00:01,000 --> 00:00:02,000\n你好\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\n再见\nGoodbye\n"
+	englishSRT   = "1\n00:00:01,000 --> 00:00:02,000\nA real English subtitle\n"
+)
+
+// writeSubtitles writes subtitles with the given contents to dir.
+func writeSubtitles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSplitTaggedSubtitle(t *testing.T) {
+	dir := writeFiles(t, "Movie.mkv")
+	writeSubtitles(t, dir, map[string]string{"movie.zh.srt": bilingualSRT, "movie.en.srt": englishSRT})
+
+	// The English subtitle claims Movie.srt, so the bilingual one is renamed to Movie.zh.srt
+	results, err := New(dir, Verbose(false), DryRun(false), SplitBilingual(true), LanguagePriority([]string{"en"})).Match()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := matchOf(t, results, "movie.zh.srt")
+	if got, want := filepath.Base(result.NewSubtitlePath), "Movie.zh.srt"; got != want {
+		t.Fatalf("renamed to %s, want %s", got, want)
+	}
+	if len(result.SplitSubtitlePaths) != 1 || filepath.Base(result.SplitSubtitlePaths[0]) != "Movie.en.srt" {
+		t.Errorf("SplitSubtitlePaths = %q, want only Movie.en.srt", result.SplitSubtitlePaths)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Movie.zh.srt"))
+	if err != nil || string(data) != bilingualSRT {
+		t.Errorf("Movie.zh.srt = %q, %v; want the bilingual original", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "Movie.en.srt"))
+	if err != nil || strings.Contains(string(data), "你好") || !strings.Contains(string(data), "Hello") {
+		t.Errorf("Movie.en.srt = %q, %v; want the English lines", data, err)
+	}
+	for _, name := range []string{"Movie.zh.zh.srt", "Movie.zh.en.srt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s written", name)
+		}
+	}
+}
+
+func TestSplitDoesNotOverwriteSubtitles(t *testing.T) {
+	tests := []struct {
+		name      string
+		bilingual string
+		english   string
+		only      bool
+		options   []Option
+	}{
+		// Only the bilingual subtitle is matched, so Movie.en.srt stays where it is
+		{"existing", "movie.srt", "Movie.en.srt", true, []Option{DryRun(false)}},
+		// The bilingual subtitle claims Movie.srt, so the English one is planned
+		// for Movie.en.srt; the dry run leaves it unrenamed when splitting
+		{"planned", "movie.zh.srt", "movie.en.srt", false, []Option{DryRun(true), LanguagePriority([]string{"zh", "en"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, "Movie.mkv")
+			writeSubtitles(t, dir, map[string]string{tt.bilingual: bilingualSRT, tt.english: englishSRT})
+
+			options := append([]Option{Verbose(false), SplitBilingual(true)}, tt.options...)
+			if tt.only {
+				options = append(options, Files([]string{filepath.Join(dir, tt.bilingual)}))
+			}
+			results, _ := New(dir, options...).Match()
+			result := matchOf(t, results, tt.bilingual)
+			if result.Error == nil || !strings.Contains(result.Error.Error(), "Movie.en.srt") {
+				t.Errorf("Error = %v, want Movie.en.srt reported", result.Error)
+			}
+			for _, path := range result.SplitSubtitlePaths {
+				if filepath.Base(path) == "Movie.en.srt" {
+					t.Errorf("SplitSubtitlePaths = %q, includes Movie.en.srt", result.SplitSubtitlePaths)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, tt.english))
+			if err != nil || string(data) != englishSRT {
+				t.Errorf("%s = %q, %v; want the English subtitle", tt.english, data, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package subtitlematcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vobSubTimestampLayout is the layout of .idx timestamps, e.g. "00:01:02:345".
+const vobSubTimestampLayout = "%d:%d:%d:%d"
+
+// spuDefaultDuration is how long a subtitle without a stop command is shown
+// when no other subtitle follows it.
+const spuDefaultDuration = 3 * time.Second
+
+// spuDelayUnit is the unit of the delays in SPU control sequences.
+const spuDelayUnit = 1024 * time.Second / pgsClock
+
+// SPU control commands.
+const (
+	spuStart   = 0x01
+	spuStop    = 0x02
+	spuPalette = 0x03
+	spuAlpha   = 0x04
+	spuCoords  = 0x05
+	spuOffsets = 0x06
+	spuForced  = 0x00
+	spuEnd     = 0xFF
+)
+
+// vobSubIndex is what matters of a VobSub .idx file for decoding its first
+// track: the 16-color palette, the track's language and where its subtitles
+// are stored in the .sub file.
+type vobSubIndex struct {
+	palette   [16]uint8 // Luminance of each palette color
+	language  string    // Language of the first track, e.g. "en"
+	positions []vobSubPosition
+}
+
+// vobSubPosition is a subtitle of the .idx: when it is shown and where its
+// SPU starts in the .sub file.
+type vobSubPosition struct {
+	time time.Duration
+	pos  int64
+}
+
+// parseVobSubIndex reads the palette and the first track of a VobSub .idx.
+func parseVobSubIndex(data []byte) (*vobSubIndex, error) {
+	index := &vobSubIndex{}
+	tracks := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "palette":
+			for i, hex := range strings.Split(value, ",") {
+				rgb, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 32)
+				if err != nil || i >= len(index.palette) {
+					break
+				}
+				r, g, b := uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)
+				index.palette[i] = color.GrayModel.Convert(color.RGBA{R: r, G: g, B: b, A: 255}).(color.Gray).Y
+			}
+		case "id":
+			tracks++
+			language, _, _ := strings.Cut(value, ",")
+			if tracks == 1 {
+				index.language = strings.TrimSpace(language)
+			}
+		case "timestamp":
+			if tracks > 1 {
+				continue
+			}
+			stamp, filepos, _ := strings.Cut(value, ", filepos:")
+			var h, m, s, ms int
+			if _, err := fmt.Sscanf(stamp, vobSubTimestampLayout, &h, &m, &s, &ms); err != nil {
+				continue
+			}
+			pos, err := strconv.ParseInt(strings.TrimSpace(filepos), 16, 64)
+			if err != nil {
+				continue
+			}
+			index.positions = append(index.positions, vobSubPosition{
+				time: time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+					time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond,
+				pos: pos,
+			})
+		}
+	}
+	if len(index.positions) == 0 {
+		return nil, errors.New("no subtitles in VobSub index")
+	}
+	return index, scanner.Err()
+}
+
+// decodeVobSub decodes the cues of the first track of a VobSub subtitle as
+// bitmaps of dark text on a white background, along with the track's
+// language as written in the .idx.
+func decodeVobSub(idx, sub []byte) ([]bitmapCue, string, error) {
+	index, err := parseVobSubIndex(idx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var cues []bitmapCue
+	for i, position := range index.positions {
+		packet, err := readSPU(sub, position.pos)
+		if err != nil {
+			return cues, index.language, fmt.Errorf("subtitle at %s: %w", formatSRTTimestamp(position.time), err)
+		}
+		cue, ok := decodeSPU(packet, &index.palette)
+		if !ok {
+			continue
+		}
+		cue.Start += position.time
+		cue.End += position.time
+		if cue.End <= cue.Start {
+			if i+1 < len(index.positions) {
+				cue.End = index.positions[i+1].time // Shown until the next one
+			} else {
+				cue.End = cue.Start + spuDefaultDuration
+			}
+		}
+		cues = append(cues, cue)
+	}
+	return cues, index.language, nil
+}
+
+// readSPU gathers the SPU starting at pos of a .sub file from the payloads
+// of the MPEG packets it is split into.
+func readSPU(sub []byte, pos int64) ([]byte, error) {
+	if pos < 0 || pos >= int64(len(sub)) {
+		return nil, errors.New("position beyond the end of the .sub file")
+	}
+
+	var spu []byte
+	size := -1
+	for p := sub[pos:]; size < 0 || len(spu) < size; {
+		payload, rest, err := mpegPayload(p)
+		if err != nil {
+			return nil, err
+		}
+		p = rest
+		if payload == nil {
+			continue // Padding or another stream
+		}
+		spu = append(spu, payload...)
+		if size < 0 && len(spu) >= 2 {
+			size = int(binary.BigEndian.Uint16(spu))
+		}
+	}
+	return spu[:size], nil
+}
+
+// mpegPayload returns the subtitle payload of the MPEG pack or packet that
+// data starts with, or nil when it holds none, and the data following it.
+func mpegPayload(data []byte) (payload, rest []byte, err error) {
+	if len(data) < 6 || !bytes.Equal(data[:3], mpegPackHeader[:3]) {
+		return nil, nil, errors.New("corrupt MPEG stream")
+	}
+
+	switch data[3] {
+	case 0xBA: // Pack header, MPEG-2 or MPEG-1
+		if data[4]&0xC0 == 0x40 {
+			if len(data) < 14 {
+				return nil, nil, errors.New("truncated MPEG pack header")
+			}
+			return nil, data[14+int(data[13]&0x07):], nil
+		}
+		return nil, data[min(12, len(data)):], nil
+	case 0xB9: // End of stream
+		return nil, nil, errors.New("SPU cut off by the end of the stream")
+	}
+
+	length := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 6+length {
+		return nil, nil, errors.New("truncated MPEG packet")
+	}
+	packet, rest := data[6:6+length], data[6+length:]
+	if data[3] != 0xBD || len(packet) < 3 {
+		return nil, rest, nil // Not private stream 1, which holds subtitles
+	}
+
+	headerEnd := 3 + int(packet[2])
+	if len(packet) <= headerEnd {
+		return nil, rest, nil
+	}
+	// Skip the PES header and the substream ID
+	return packet[headerEnd+1:], rest, nil
+}
+
+// decodeSPU decodes a subpicture unit into a cue timed relative to its
+// position in the .idx. It reports false for units with nothing to show.
+func decodeSPU(spu []byte, palette *[16]uint8) (bitmapCue, bool) {
+	if len(spu) < 4 {
+		return bitmapCue{}, false
+	}
+
+	var (
+		cue                   bitmapCue
+		colors, alphas        [4]uint8
+		x1, x2, y1, y2        int
+		topField, bottomField int
+		stopped               bool
+	)
+	for offset := int(binary.BigEndian.Uint16(spu[2:])); offset+4 <= len(spu); {
+		delay := time.Duration(binary.BigEndian.Uint16(spu[offset:])) * spuDelayUnit
+		next := int(binary.BigEndian.Uint16(spu[offset+2:]))
+
+	commands:
+		for i := offset + 4; i < len(spu); {
+			command := spu[i]
+			i++
+			switch command {
+			case spuForced, spuStart:
+				cue.Start = delay
+			case spuStop:
+				cue.End, stopped = delay, true
+			case spuPalette, spuAlpha:
+				if i+2 > len(spu) {
+					break commands
+				}
+				values := [4]uint8{spu[i+1] & 0x0F, spu[i+1] >> 4, spu[i] & 0x0F, spu[i] >> 4}
+				if command == spuPalette {
+					colors = values
+				} else {
+					alphas = values
+				}
+				i += 2
+			case spuCoords:
+				if i+6 > len(spu) {
+					break commands
+				}
+				c := spu[i : i+6]
+				x1, x2 = int(c[0])<<4|int(c[1])>>4, int(c[1]&0x0F)<<8|int(c[2])
+				y1, y2 = int(c[3])<<4|int(c[4])>>4, int(c[4]&0x0F)<<8|int(c[5])
+				i += 6
+			case spuOffsets:
+				if i+4 > len(spu) {
+					break commands
+				}
+				topField = int(binary.BigEndian.Uint16(spu[i:]))
+				bottomField = int(binary.BigEndian.Uint16(spu[i+2:]))
+				i += 4
+			default: // spuEnd, or a command we cannot skip
+				break commands
+			}
+		}
+
+		if next <= offset {
+			break
+		}
+		offset = next
+	}
+
+	width, height := x2-x1+1, y2-y1+1
+	if width <= 0 || height <= 0 || topField == 0 {
+		return bitmapCue{}, false
+	}
+	if !stopped {
+		cue.End = cue.Start
+	}
+
+	var grays [4]uint8
+	for i := range grays {
+		// Alpha is 0-15; scale it to 0-255
+		grays[i] = textGray(palette[colors[i]], alphas[i]*17)
+	}
+	img := blankBitmap(width, height)
+	for y := 0; y < height; y++ {
+		field := topField
+		if y%2 == 1 {
+			field = bottomField
+		}
+		decodeSPULine(spu, &field, width, func(x int, pixel uint8) {
+			img.SetGray(x, y, color.Gray{Y: grays[pixel]})
+		})
+		if y%2 == 1 {
+			bottomField = field
+		} else {
+			topField = field
+		}
+	}
+	cue.Image = img
+	return cue, true
+}
+
+// decodeSPULine decodes one line of an SPU's interlaced, nibble-based
+// run-length encoding, starting at the byte *offset and leaving *offset at
+// the next line. set is called for each pixel with its value (0-3).
+func decodeSPULine(spu []byte, offset *int, width int, set func(x int, pixel uint8)) {
+	nibble := *offset * 2
+	read := func() int {
+		if nibble/2 >= len(spu) {
+			nibble++
+			return 0
+		}
+		b := spu[nibble/2]
+		nibble++
+		if nibble%2 == 1 {
+			return int(b >> 4)
+		}
+		return int(b & 0x0F)
+	}
+
+	for x := 0; x < width; {
+		v := read()
+		for threshold := 0x4; v < threshold && threshold <= 0x40; threshold <<= 2 {
+			v = v<<4 | read()
+		}
+		run, pixel := v>>2, uint8(v&0x03)
+		if run == 0 {
+			run = width - x // Until the end of the line
+		}
+		for ; run > 0 && x < width; run-- {
+			set(x, pixel)
+			x++
+		}
+	}
+	*offset = (nibble + 1) / 2 // Lines are byte aligned
+}
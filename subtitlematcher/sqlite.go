@@ -0,0 +1,173 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqlitePath is the sqlite3 executable used for SQLite histories.
+const sqlitePath = "sqlite3"
+
+// sqliteBusyTimeout is how long, in milliseconds, a history write waits for
+// another process holding the database.
+const sqliteBusyTimeout = 5000
+
+// sqliteSchema creates the history tables. Each run is kept whole as JSON in
+// runs.data, and its results are spread over the results table so that past
+// operations can be queried with SQL.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS runs (
+	id TEXT PRIMARY KEY,
+	started TEXT NOT NULL,
+	directory TEXT NOT NULL,
+	dry_run INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS results (
+	run_id TEXT NOT NULL REFERENCES runs(id),
+	subtitle_path TEXT NOT NULL,
+	video_path TEXT,
+	new_subtitle_path TEXT,
+	similarity REAL NOT NULL,
+	renamed INTEGER NOT NULL,
+	error TEXT
+);
+CREATE INDEX IF NOT EXISTS results_subtitle_path ON results(subtitle_path);
+CREATE INDEX IF NOT EXISTS results_new_subtitle_path ON results(new_subtitle_path);
+`
+
+// SQLiteHistory is a HistoryStore in an SQLite database. Besides the runs,
+// it keeps one row per result in a results table, so the history can be
+// queried with SQL, e.g. for every run that renamed a file:
+//
+//	sqlite3 history.db "SELECT run_id, new_subtitle_path FROM results WHERE subtitle_path = '/videos/a.srt'"
+//
+// It runs the sqlite3 command for every operation, so sqlite3 3.33 or later
+// must be installed.
+type SQLiteHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewSQLiteHistory returns a history stored in the SQLite database at path.
+// The database is created on the first recorded run.
+func NewSQLiteHistory(path string) *SQLiteHistory {
+	return &SQLiteHistory{path: path}
+}
+
+// Record inserts run and its results into the database in one transaction.
+func (h *SQLiteHistory) Record(run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".timeout %d\n", sqliteBusyTimeout)
+	b.WriteString(sqliteSchema)
+	b.WriteString("BEGIN;\n")
+	fmt.Fprintf(&b, "INSERT INTO runs (id, started, directory, dry_run, data) VALUES (%s, %s, %s, %d, %s);\n",
+		sqlQuote(run.ID), sqlQuote(run.Started.Format(time.RFC3339Nano)), sqlQuote(run.Directory),
+		sqlBool(run.Settings.DryRun), sqlQuote(string(data)))
+	for _, result := range run.Results {
+		message := result.ErrorMessage
+		if result.Error != nil {
+			message = result.Error.Error()
+		}
+		fmt.Fprintf(&b, "INSERT INTO results (run_id, subtitle_path, video_path, new_subtitle_path, similarity, renamed, error) VALUES (%s, %s, %s, %s, %g, %d, %s);\n",
+			sqlQuote(run.ID), sqlQuote(result.SubtitlePath), sqlNullable(result.VideoPath), sqlNullable(result.NewSubtitlePath),
+			result.Similarity, sqlBool(result.Renamed), sqlNullable(message))
+	}
+	b.WriteString("COMMIT;\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err = h.run(b.String())
+	return err
+}
+
+// Runs reads every run from the database, oldest first. A missing database
+// is an empty history.
+func (h *SQLiteHistory) Runs() ([]Run, error) {
+	if _, err := os.Stat(h.path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	out, err := h.run("SELECT data FROM runs ORDER BY rowid;\n", "-readonly", "-json")
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// sqlite3 prints nothing at all for an empty table
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("%s: invalid sqlite3 output: %w", h.path, err)
+	}
+	runs := make([]Run, 0, len(rows))
+	for _, row := range rows {
+		var run Run
+		if err := json.Unmarshal([]byte(row.Data), &run); err != nil {
+			return nil, fmt.Errorf("%s: %w", h.path, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// run executes sqlite3 on the database with the SQL script on its standard
+// input and returns its output.
+func (h *SQLiteHistory) run(script string, args ...string) ([]byte, error) {
+	path := h.path
+	// Keep sqlite3 from reading the database name as an option
+	if strings.HasPrefix(path, "-") {
+		path = "./" + path
+	}
+	cmd := exec.Command(sqlitePath, append(append([]string{"-bail"}, args...), path)...)
+	cmd.Stdin = strings.NewReader(script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("sqlite3 failed: %s", msg)
+		}
+		return nil, fmt.Errorf("%s: %w", h.path, err)
+	}
+	return out, nil
+}
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlNullable returns s as an SQL string literal, or NULL when it is empty.
+func sqlNullable(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return sqlQuote(s)
+}
+
+// sqlBool returns the SQLite integer for b.
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package subtitlematcher
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteHistoryRecordsRuns(t *testing.T) {
+	if _, err := exec.LookPath(sqlitePath); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := writeFiles(t, "The Great Escape 1963.mkv", "great.escape.1963.srt")
+	history := NewSQLiteHistory(filepath.Join(t.TempDir(), "history.db"))
+
+	if runs, err := history.Runs(); err != nil || len(runs) != 0 {
+		t.Fatalf("runs before the first record = %d, %v", len(runs), err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := New(dir, Verbose(false), DryRun(i == 0), History(history)).Match(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := history.Runs()
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("recorded runs = %d, %v", len(runs), err)
+	}
+	if !runs[0].Settings.DryRun || len(runs[1].Renames()) != 1 {
+		t.Errorf("runs = %+v, want a dry run followed by one rename", runs)
+	}
+
+	query := "SELECT run_id FROM results WHERE renamed = 1 AND subtitle_path = " + sqlQuote(filepath.Join(dir, "great.escape.1963.srt")) + ";\n"
+	out, err := history.run(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != runs[1].ID {
+		t.Errorf("queried run = %q, want %q", got, runs[1].ID)
+	}
+}
@@ -0,0 +1,238 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// srtCue is a single cue parsed from an SRT file.
+type srtCue struct {
+	Index int           // Sequence number as written in the file (0 if missing)
+	Start time.Duration // Cue start time
+	End   time.Duration // Cue end time
+	Lines []string      // Cue text lines
+}
+
+// srtDocument is the result of leniently parsing an SRT file.
+type srtDocument struct {
+	Cues    []srtCue // Cues that could be parsed
+	Issues  []string // Human-readable descriptions of every problem found
+	Invalid bool     // Whether any problem is severe enough to consider the file broken
+}
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files.
+const utf8BOM = "\ufeff"
+
+// strictTimingPattern matches a well-formed SRT timing line.
+var strictTimingPattern = regexp.MustCompile(`^\d{2}:\d{2}:\d{2},\d{3} --> \d{2}:\d{2}:\d{2},\d{3}(?:\s.*)?$`)
+
+// lenientTimingPattern matches timing lines with common formatting mistakes
+// such as missing zero padding or a dot instead of a comma.
+var lenientTimingPattern = regexp.MustCompile(`^(\d{1,2}):(\d{1,2}):(\d{1,2})[,.:](\d{1,3})\s*-+>\s*(\d{1,2}):(\d{1,2}):(\d{1,2})[,.:](\d{1,3})`)
+
+// readSRT reads and parses an SRT file.
+func readSRT(fsys FileSystem, path string) (srtDocument, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return srtDocument{}, err
+	}
+	return parseSRT(string(data)), nil
+}
+
+// parseSRT parses SRT content, recording problems instead of failing so that
+// callers can both validate and repair files.
+func parseSRT(content string) srtDocument {
+	var doc srtDocument
+
+	content = strings.TrimPrefix(content, utf8BOM)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	line := 1
+	for _, block := range strings.Split(content, "\n\n") {
+		blockLine := line
+		line += strings.Count(block, "\n") + 2
+
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if len(lines) == 1 && strings.TrimSpace(lines[0]) == "" {
+			continue
+		}
+
+		cue := srtCue{}
+		if index, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil {
+			cue.Index = index
+			lines = lines[1:]
+		} else if !lenientTimingPattern.MatchString(strings.TrimSpace(lines[0])) {
+			doc.addIssue(true, "line %d: expected sequence number, got %q", blockLine, lines[0])
+			continue
+		} else {
+			doc.addIssue(false, "line %d: cue is missing its sequence number", blockLine)
+		}
+
+		if len(lines) == 0 {
+			doc.addIssue(true, "line %d: cue %d has no timing line", blockLine, cue.Index)
+			continue
+		}
+
+		timing := strings.TrimSpace(lines[0])
+		start, end, ok := parseSRTTiming(timing)
+		if !ok {
+			doc.addIssue(true, "line %d: unparseable timing %q", blockLine, timing)
+			continue
+		}
+		if !strictTimingPattern.MatchString(timing) {
+			doc.addIssue(false, "line %d: malformed timestamp %q", blockLine, timing)
+		}
+
+		cue.Start, cue.End, cue.Lines = start, end, lines[1:]
+		doc.Cues = append(doc.Cues, cue)
+	}
+
+	if len(doc.Cues) == 0 {
+		doc.addIssue(true, "no cues found")
+	}
+	doc.checkOrdering()
+
+	return doc
+}
+
+// checkOrdering records sequence gaps, inverted or zero-length cues, and overlaps.
+func (doc *srtDocument) checkOrdering() {
+	for i, cue := range doc.Cues {
+		if cue.Index != 0 && cue.Index != i+1 {
+			doc.addIssue(false, "cue %d: expected sequence number %d", cue.Index, i+1)
+		}
+		if cue.End < cue.Start {
+			doc.addIssue(true, "cue %d: ends before it starts", i+1)
+		} else if cue.End == cue.Start {
+			doc.addIssue(false, "cue %d: has zero length", i+1)
+		}
+		if i > 0 && cue.Start < doc.Cues[i-1].End {
+			doc.addIssue(false, "cue %d: overlaps the previous cue", i+1)
+		}
+	}
+}
+
+// addIssue records a problem and marks the document invalid if it is fatal.
+func (doc *srtDocument) addIssue(fatal bool, format string, args ...interface{}) {
+	doc.Issues = append(doc.Issues, fmt.Sprintf(format, args...))
+	if fatal {
+		doc.Invalid = true
+	}
+}
+
+// parseSRTTiming parses a timing line leniently and returns the start and end times.
+func parseSRTTiming(timing string) (time.Duration, time.Duration, bool) {
+	m := lenientTimingPattern.FindStringSubmatch(timing)
+	if m == nil {
+		return 0, 0, false
+	}
+	return srtTimestamp(m[1], m[2], m[3], m[4]), srtTimestamp(m[5], m[6], m[7], m[8]), true
+}
+
+// srtTimestamp builds a duration from timestamp components. The fractional
+// part is read as a decimal fraction, so "5" means 500 milliseconds.
+func srtTimestamp(hours, minutes, seconds, fraction string) time.Duration {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	ms, _ := strconv.Atoi((fraction + "00")[:3])
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond
+}
+
+// validateSubtitle validates the structure of an SRT subtitle and records the
+// outcome in the result. Other formats are left untouched.
+func (vsm *VideoSubtitleMatcher) validateSubtitle(result MatchResult) MatchResult {
+	if subtitleFormat(result) != ".srt" {
+		return result
+	}
+
+	doc, err := readSRT(vsm.fs, result.SubtitlePath)
+	if err != nil {
+		result.Invalid = true
+		result.Issues = []string{err.Error()}
+		return result
+	}
+
+	result.Invalid = doc.Invalid
+	result.Issues = doc.Issues
+	return result
+}
+
+// repairSRT renumbers cues, rewrites malformed timestamps in the standard
+// format and removes zero-length cues. Files without problems, and files too
+// broken to parse safely, are left untouched. Reports whether the file was rewritten.
+func repairSRT(fsys FileSystem, path string) (bool, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+
+	content := string(data)
+	doc := parseSRT(content)
+	if doc.Invalid || len(doc.Issues) == 0 {
+		return false, nil
+	}
+
+	bom, newline := textStyle(content)
+
+	var cues []srtCue
+	for _, cue := range doc.Cues {
+		if cue.End > cue.Start {
+			cues = append(cues, cue)
+		}
+	}
+
+	repaired := bom + formatSRT(cues, newline)
+	if err := fsys.WriteFile(path, []byte(repaired)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// textStyle returns the byte order mark ("" for none) and the line ending of
+// SRT content, so that rewriting the file keeps them.
+func textStyle(content string) (bom, newline string) {
+	newline = "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	if strings.HasPrefix(content, utf8BOM) {
+		bom = utf8BOM
+	}
+	return bom, newline
+}
+
+// formatSRT renders cues as SRT content, numbering them sequentially from 1.
+func formatSRT(cues []srtCue, newline string) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		if i > 0 {
+			b.WriteString(newline)
+		}
+		fmt.Fprintf(&b, "%d%s%s --> %s%s", i+1, newline,
+			formatSRTTimestamp(cue.Start), formatSRTTimestamp(cue.End), newline)
+		for _, line := range cue.Lines {
+			b.WriteString(line)
+			b.WriteString(newline)
+		}
+	}
+	return b.String()
+}
+
+// formatSRTTimestamp formats a duration as an SRT timestamp (HH:MM:SS,mmm).
+func formatSRTTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, d/time.Millisecond)
+}
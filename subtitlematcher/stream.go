@@ -0,0 +1,30 @@
+package subtitlematcher
+
+import "context"
+
+// MatchStream runs a match like MatchContext but delivers each result on the
+// returned channel as soon as its subtitle has been processed, instead of all
+// at the end. The results channel is closed when the run ends; the error
+// channel then receives the run's error, or nil, and is closed too.
+//
+// Results must be received until the channel is closed, or ctx cancelled, or
+// the run blocks. After cancelling, results still in flight are dropped.
+func (vsm *VideoSubtitleMatcher) MatchStream(ctx context.Context) (<-chan MatchResult, <-chan error) {
+	results := make(chan MatchResult)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(results)
+
+		_, err := vsm.match(ctx, func(result MatchResult) {
+			select {
+			case results <- result:
+			case <-ctx.Done():
+			}
+		})
+		errc <- err
+	}()
+
+	return results, errc
+}
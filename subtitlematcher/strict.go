@@ -0,0 +1,96 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AmbiguityError is returned by strict runs that found ambiguous matches.
+// No files are renamed when it is returned.
+type AmbiguityError struct {
+	Problems []string // One human-readable description per ambiguity
+}
+
+// Error returns a report listing every ambiguity.
+func (e *AmbiguityError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "strict mode: %d ambiguous match(es), nothing renamed:", len(e.Problems))
+	for _, problem := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(problem)
+	}
+	return b.String()
+}
+
+// findAmbiguities checks a plan for near-tied matches, conflicting target
+// names and videos whose subtitles resolve to the same name. Subtitles of one
+// video kept under different names, such as several languages or .alt
+// copies, are not ambiguous. Manual mappings are never considered near-ties,
+// and held and duplicate subtitles are left out since they are not renamed.
+func findAmbiguities(fsys FileSystem, results []MatchResult) []string {
+	var problems []string
+	targets := make(map[string][]string)
+	videoTargets := make(map[[2]string][]string)
+
+	for _, result := range results {
+		if result.NewSubtitlePath == "" || result.Invalid || result.Held || result.DuplicateOf != "" {
+			continue
+		}
+
+		if !result.Mapped && result.Similarity-result.RunnerUpSimilarity < mediumConfidenceMargin {
+			problems = append(problems, fmt.Sprintf("%s: best match %s (%.2f) is nearly tied with another video (%.2f)",
+				filepath.Base(result.SubtitlePath), filepath.Base(result.VideoPath), result.Similarity, result.RunnerUpSimilarity))
+		}
+
+		targets[result.NewSubtitlePath] = append(targets[result.NewSubtitlePath], result.SubtitlePath)
+		// Mappings to a target name have no video to share
+		if result.VideoPath != "" {
+			key := [2]string{result.VideoPath, result.NewSubtitlePath}
+			videoTargets[key] = append(videoTargets[key], result.SubtitlePath)
+		}
+
+		if result.NewSubtitlePath != result.SubtitlePath {
+			if exists(fsys, result.NewSubtitlePath) {
+				problems = append(problems, fmt.Sprintf("%s: target %s already exists",
+					filepath.Base(result.SubtitlePath), result.NewSubtitlePath))
+			}
+		}
+	}
+
+	videos := make(map[string][]string)
+	for key, subtitles := range videoTargets {
+		if len(subtitles) > 1 {
+			videos[key[0]] = append(videos[key[0]], subtitles...)
+		}
+	}
+	for _, subtitles := range videos {
+		sort.Strings(subtitles)
+	}
+
+	problems = append(problems, describeShared(targets, "target %s is planned for %d subtitles: %s")...)
+	problems = append(problems, describeShared(videos, "video %s is matched by %d subtitles under the same name: %s")...)
+	return problems
+}
+
+// describeShared reports keys claimed by more than one subtitle, sorted by key.
+func describeShared(claims map[string][]string, format string) []string {
+	keys := make([]string, 0, len(claims))
+	for key, subtitles := range claims {
+		if len(subtitles) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	problems := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names := make([]string, len(claims[key]))
+		for i, subtitle := range claims[key] {
+			names[i] = filepath.Base(subtitle)
+		}
+		problems = append(problems, fmt.Sprintf(format, key, len(names), strings.Join(names, ", ")))
+	}
+	return problems
+}
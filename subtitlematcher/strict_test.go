@@ -0,0 +1,37 @@
+package subtitlematcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStrictAcceptsLanguagesOfOneVideo(t *testing.T) {
+	dir := writeFiles(t, "Movie.mkv", "movie.en.srt", "movie.zh.srt")
+
+	if _, err := New(dir, Verbose(false), DryRun(false), Strict(true), LanguagePriority([]string{"en"})).Match(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Movie.srt", "Movie.zh.srt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not renamed: %v", name, err)
+		}
+	}
+}
+
+func TestFindAmbiguitiesReportsSubtitlesOfOneVideoUnderOneName(t *testing.T) {
+	dir := t.TempDir()
+	video, target := filepath.Join(dir, "Movie.mkv"), filepath.Join(dir, "Movie.srt")
+	result := func(subtitle string, held bool) MatchResult {
+		return MatchResult{SubtitlePath: filepath.Join(dir, subtitle), VideoPath: video, NewSubtitlePath: target, Similarity: 1, Held: held}
+	}
+
+	problems := findAmbiguities(LocalFileSystem{}, []MatchResult{result("a.srt", false), result("b.srt", false)})
+	if len(problems) != 2 || !strings.Contains(problems[1], "video "+video) {
+		t.Errorf("problems = %q, want the shared target and video", problems)
+	}
+	if problems := findAmbiguities(LocalFileSystem{}, []MatchResult{result("a.srt", false), result("b.srt", true)}); len(problems) > 0 {
+		t.Errorf("held subtitle reported: %q", problems)
+	}
+}
@@ -0,0 +1,40 @@
+package subtitlematcher
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// subsFolderName is the folder torrents commonly keep subtitles in, with one
+// subfolder per video: Subs/<video name>/2_English.srt.
+const subsFolderName = "subs"
+
+// subtitleTitle returns the title a subtitle is matched by. That is its file
+// name, except in the torrent Subs layout, where file names like 2_English
+// only number the tracks and the folder names the video.
+func subtitleTitle(path string) string {
+	if folder, ok := subsFolder(path); ok {
+		return folder
+	}
+	return titleOf(path)
+}
+
+// subsFolder returns the video folder name of a subtitle stored as
+// Subs/<video name>/<track>.
+func subsFolder(path string) (string, bool) {
+	dir := filepath.Dir(path)
+	if !strings.EqualFold(filepath.Base(filepath.Dir(dir)), subsFolderName) {
+		return "", false
+	}
+	return filepath.Base(dir), true
+}
+
+// subtitleDir returns the directory a subtitle belongs to: its own, or for
+// the torrent Subs layout the release folder that contains Subs.
+func subtitleDir(path string) string {
+	dir := filepath.Dir(path)
+	if _, ok := subsFolder(path); ok {
+		return filepath.Dir(filepath.Dir(dir))
+	}
+	return dir
+}
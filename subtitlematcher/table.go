@@ -0,0 +1,208 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// tableHeader is the header row of result tables.
+var tableHeader = []string{"SUBTITLE", "NEW NAME", "SCORE", "CONFIDENCE", "STATUS"}
+
+// Indexes of the table columns formatted specially.
+const (
+	scoreColumn  = 2
+	statusColumn = 4
+)
+
+// WriteTable writes results as tables grouped by directory, with columns
+// aligned by display width so that names mixing CJK and ASCII characters
+// line up:
+//
+//	/videos/Show
+//	  SUBTITLE                   NEW NAME               SCORE  CONFIDENCE  STATUS
+//	  extras.srt                                         0.21              no match
+//	  进击的巨人 第02集.chs.srt  进击的巨人 第02集.srt   0.85  high        would rename
+func WriteTable(w io.Writer, results []MatchResult) error {
+	return writeTable(w, results, nil)
+}
+
+// writeTable writes results as tables, coloring statuses with paint when
+// it is not nil.
+func writeTable(w io.Writer, results []MatchResult, paint func(color, text string) string) error {
+	sorted := append([]MatchResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, dj := filepath.Dir(sorted[i].SubtitlePath), filepath.Dir(sorted[j].SubtitlePath)
+		if di != dj {
+			return di < dj
+		}
+		return sorted[i].SubtitlePath < sorted[j].SubtitlePath
+	})
+
+	var b strings.Builder
+	for start := 0; start < len(sorted); {
+		dir := filepath.Dir(sorted[start].SubtitlePath)
+		end := start
+		for end < len(sorted) && filepath.Dir(sorted[end].SubtitlePath) == dir {
+			end++
+		}
+
+		if start > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(dir + "\n")
+		rows := [][]string{tableHeader}
+		for _, result := range sorted[start:end] {
+			rows = append(rows, tableRow(result))
+		}
+		writeRows(&b, rows, paint)
+		start = end
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tableRow returns the cells describing one result.
+func tableRow(result MatchResult) []string {
+	newName, score, confidence := "", fmt.Sprintf("%.2f", result.Similarity), ""
+	if result.NewSubtitlePath != "" {
+		newName = filepath.Base(result.NewSubtitlePath)
+		confidence = result.Confidence.String()
+	}
+	if result.Generated || result.Fetched {
+		// Generated and downloaded subtitles were not scored against the video
+		score, confidence = "", ""
+	}
+	return []string{
+		filepath.Base(result.SubtitlePath),
+		newName,
+		score,
+		confidence,
+		resultStatus(result),
+	}
+}
+
+// resultStatus describes in a few words what happened to a subtitle.
+func resultStatus(result MatchResult) string {
+	switch {
+	case result.Fetched:
+		return fetchStatus(result)
+	case result.DuplicateOf != "":
+		return "duplicate"
+	case result.Rejected:
+		return "rejected"
+	case result.NewSubtitlePath == "":
+		return "no match"
+	case result.Invalid:
+		return "invalid"
+	case result.Held:
+		return "held"
+	case result.Error != nil:
+		return "error"
+	case result.Generated && result.Renamed:
+		return "generated"
+	case result.Generated:
+		return "would generate"
+	case result.SubtitlePath == result.NewSubtitlePath && result.Archive == "":
+		return "correct"
+	case result.Renamed && result.Converted:
+		return "converted"
+	case result.Renamed && result.Archive != "":
+		return "extracted"
+	case result.Renamed:
+		return "renamed"
+	default:
+		return "would rename"
+	}
+}
+
+// fetchStatus describes the search for a subtitle for a video without one.
+func fetchStatus(result MatchResult) string {
+	switch {
+	case result.Error != nil:
+		return "error"
+	case result.Renamed:
+		return "downloaded"
+	case result.NewSubtitlePath != "":
+		return "would download"
+	case len(result.Remote) > 0:
+		return "found online"
+	default:
+		return "not found"
+	}
+}
+
+// writeRows writes rows with columns padded to their widest cell. The score
+// column is right-aligned, trailing blanks are trimmed and the statuses below
+// the header are colored with paint, if any.
+func writeRows(b *strings.Builder, rows [][]string, paint func(color, text string) string) {
+	widths := make([]int, len(tableHeader))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	for j, row := range rows {
+		var line strings.Builder
+		line.WriteString("  ")
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-displayWidth(cell))
+			if i == statusColumn && j > 0 && paint != nil {
+				cell = paint(statusColor(cell), cell)
+			}
+			if i == scoreColumn {
+				line.WriteString(padding + cell)
+			} else {
+				line.WriteString(cell + padding)
+			}
+			if i < len(row)-1 {
+				line.WriteString("  ")
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+}
+
+// displayWidth returns the number of terminal columns s takes up: two for
+// wide East Asian characters, none for combining marks, one otherwise.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200b':
+			// Zero width
+		case isWide(r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// isWide reports whether r is displayed two columns wide.
+func isWide(r rune) bool {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo initials
+		r >= 0x2e80 && r <= 0x303e, // CJK radicals, punctuation
+		r >= 0x3041 && r <= 0x33ff, // Kana, CJK symbols
+		r >= 0x3400 && r <= 0x4dbf, // CJK extension A
+		r >= 0x4e00 && r <= 0x9fff, // CJK unified ideographs
+		r >= 0xa000 && r <= 0xa4cf, // Yi
+		r >= 0xac00 && r <= 0xd7a3, // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff, // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f, // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60, // Fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // Emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd: // CJK extensions B and later
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Template is a yt-dlp output template, such as the default
+// "%(title)s [%(id)s].%(ext)s", used to read the title and video ID back out
+// of the names of the files it produced.
+type Template struct {
+	template string         // Template as given
+	pattern  *regexp.Regexp // Matches file titles, without extension
+	fields   []string       // Field of each capture group of pattern
+}
+
+// templateFieldPattern matches one yt-dlp template field, e.g. "%(title)s",
+// "%(playlist_index)03d" or "%(title).100B".
+var templateFieldPattern = regexp.MustCompile(`%\(([^)]*)\)[#0+ -]*\d*(?:\.\d+)?[diouxXeEfFgGcrsaBjlqDSU]`)
+
+// templateLanguagePattern matches the language yt-dlp inserts before the
+// extension of subtitle files, e.g. ".en" or ".zh-Hans".
+const templateLanguagePattern = `(?:\.[A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*)?`
+
+// ParseOutputTemplate parses a yt-dlp output template. Only the file name
+// part of the template is used, and it must contain at least one field.
+// Fields with formatting, defaults or alternatives ("%(title).50s",
+// "%(artist|Unknown)s") are recognized by their first field name.
+func ParseOutputTemplate(template string) (*Template, error) {
+	name := template[strings.LastIndexAny(template, `/\`)+1:]
+	name = strings.TrimSuffix(name, ".%(ext)s")
+
+	var b strings.Builder
+	var fields []string
+	b.WriteString("^")
+	last := 0
+	for _, loc := range templateFieldPattern.FindAllStringSubmatchIndex(name, -1) {
+		b.WriteString(regexp.QuoteMeta(strings.ReplaceAll(name[last:loc[0]], "%%", "%")))
+		b.WriteString("(.+?)")
+		fields = append(fields, templateFieldName(name[loc[2]:loc[3]]))
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(strings.ReplaceAll(name[last:], "%%", "%")))
+	b.WriteString(templateLanguagePattern + "$")
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid output template %q: no %%(field)s in file name", template)
+	}
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template %q: %w", template, err)
+	}
+	return &Template{template: template, pattern: pattern, fields: fields}, nil
+}
+
+// templateFieldName returns the name of the first field referenced by a
+// field expression such as "title", "artist|Unknown" or "release_date>%Y".
+func templateFieldName(expr string) string {
+	if i := strings.IndexAny(expr, ",|&>.+-*"); i >= 0 {
+		return expr[:i]
+	}
+	return expr
+}
+
+// String returns the template as given.
+func (t *Template) String() string {
+	return t.template
+}
+
+// Parse returns the fields of a file name produced by the template, such as
+// "title" and "id", reporting whether the name fits the template. The
+// language yt-dlp inserts in subtitle names ("Talk [id].en.vtt") is ignored.
+func (t *Template) Parse(name string) (map[string]string, bool) {
+	return t.parseTitle(titleOf(name))
+}
+
+// parseTitle returns the fields of a file title, without extension.
+func (t *Template) parseTitle(title string) (map[string]string, bool) {
+	match := t.pattern.FindStringSubmatch(title)
+	if match == nil {
+		return nil, false
+	}
+	fields := make(map[string]string, len(t.fields))
+	for i, field := range t.fields {
+		if _, ok := fields[field]; !ok {
+			fields[field] = match[i+1]
+		}
+	}
+	return fields, true
+}
+
+// expand fills the fields of the template's file name, without extension,
+// with values, each formatted by its conversion, e.g. "%(season)02d". Fields
+// without a value are left empty.
+func (t *Template) expand(values map[string]any) string {
+	name := t.template[strings.LastIndexAny(t.template, `/\`)+1:]
+	name = strings.TrimSuffix(name, ".%(ext)s")
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range templateFieldPattern.FindAllStringSubmatchIndex(name, -1) {
+		b.WriteString(strings.ReplaceAll(name[last:loc[0]], "%%", "%"))
+		if value, ok := values[templateFieldName(name[loc[2]:loc[3]])]; ok {
+			b.WriteString(formatTemplateField("%"+name[loc[3]+1:loc[1]], value))
+		}
+		last = loc[1]
+	}
+	b.WriteString(strings.ReplaceAll(name[last:], "%%", "%"))
+	return b.String()
+}
+
+// formatTemplateField formats a value by a field's conversion, such as "%s"
+// or "%02d". Conversions that do not suit the value print it as is.
+func formatTemplateField(conversion string, value any) string {
+	_, isInt := value.(int)
+	switch conversion[len(conversion)-1] {
+	case 's':
+		return fmt.Sprintf(conversion, fmt.Sprint(value))
+	case 'd', 'i':
+		if isInt {
+			return fmt.Sprintf(conversion[:len(conversion)-1]+"d", value)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// templateField returns a field of a file title named by the output
+// template, or "" when no template is set or the title does not fit it.
+func (vsm *VideoSubtitleMatcher) templateField(title, field string) string {
+	if vsm.outputTemplate == nil {
+		return ""
+	}
+	fields, _ := vsm.outputTemplate.parseTitle(title)
+	return fields[field]
+}
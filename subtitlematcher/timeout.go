@@ -0,0 +1,49 @@
+package subtitlematcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrOperationTimeout is the error of file operations that did not finish
+// within the OperationTimeout.
+var ErrOperationTimeout = errors.New("file operation timed out")
+
+// runContext bounds a run by the RunTimeout, if any.
+func (vsm *VideoSubtitleMatcher) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if vsm.runTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, vsm.runTimeout)
+}
+
+// withTimeout runs op with a context that ends after the OperationTimeout, if
+// any, or with ctx. Commands started by op are killed then. File system calls
+// cannot be interrupted, so an operation stuck in one is left running in the
+// background while the run moves on.
+func (vsm *VideoSubtitleMatcher) withTimeout(ctx context.Context, op func(context.Context) error) error {
+	opCtx := ctx
+	if vsm.operationTimeout > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, vsm.operationTimeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- op(opCtx) }()
+
+	var err error
+	select {
+	case err = <-done:
+		if err == nil || opCtx.Err() == nil {
+			return err
+		}
+		// The operation failed because its context ended, e.g. a killed command
+	case <-opCtx.Done():
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w after %v", ErrOperationTimeout, vsm.operationTimeout)
+}
@@ -0,0 +1,63 @@
+package subtitlematcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampMode controls the modification time of renamed subtitles.
+type TimestampMode int
+
+const (
+	// TimestampsUnchanged leaves modification times to the file operations:
+	// plain renames keep the original time, while conversions and rewrites
+	// (repair, markup stripping, retiming) set the current time.
+	TimestampsUnchanged TimestampMode = iota
+	// TimestampsPreserve keeps the subtitle's original modification time,
+	// even when its content was converted or rewritten.
+	TimestampsPreserve
+	// TimestampsVideo sets the subtitle's modification time to its video's.
+	TimestampsVideo
+)
+
+// sourceModTime returns the modification time the renamed subtitle should get
+// under the configured mode, or the zero time when it should not be changed.
+func (vsm *VideoSubtitleMatcher) sourceModTime(result MatchResult) time.Time {
+	var path string
+	switch vsm.timestamps {
+	case TimestampsPreserve:
+		path = result.SubtitlePath
+	case TimestampsVideo:
+		path = result.VideoPath
+	default:
+		return time.Time{}
+	}
+
+	info, err := vsm.fs.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// applyModTime sets the renamed subtitle's modification time. A zero time is a no-op.
+func (vsm *VideoSubtitleMatcher) applyModTime(result MatchResult, modTime time.Time) MatchResult {
+	if modTime.IsZero() || !result.Renamed || result.Error != nil {
+		return result
+	}
+
+	setter, ok := vsm.fs.(timeSetter)
+	if !ok {
+		result.Error = fmt.Errorf("failed to set modification time: %w", errTimesUnsupported)
+		return result
+	}
+
+	// A zero access time leaves it unchanged
+	if err := setter.Chtimes(result.NewSubtitlePath, time.Time{}, modTime); err != nil {
+		result.Error = fmt.Errorf("failed to set modification time: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error setting modification time: %v\n", err)
+		}
+	}
+	return result
+}
@@ -0,0 +1,326 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// whisperCppPath is the whisper.cpp executable used unless another is set.
+const whisperCppPath = "whisper-cli"
+
+// whisperSampleRate is the audio sample rate Whisper models expect.
+const whisperSampleRate = 16000
+
+// Transcriber generates a subtitle from the speech in a video, e.g. with
+// Whisper. See GenerateSubtitles.
+type Transcriber interface {
+	// Transcribe returns the speech of the video as SRT content, and the
+	// ISO 639-1 code of its language or "" when it is unknown.
+	Transcribe(ctx context.Context, videoPath string) (srt []byte, language string, err error)
+}
+
+// WhisperCppTranscriber transcribes videos with a local whisper.cpp build.
+// The soundtrack is decoded with ffmpeg first.
+type WhisperCppTranscriber struct {
+	Binary   string // whisper.cpp executable ("whisper-cli" if empty)
+	Model    string // ggml model file, e.g. "models/ggml-base.bin"
+	Language string // Spoken language ("" to detect it)
+}
+
+// NewWhisperCppTranscriber returns a transcriber running whisper.cpp with
+// the given model file, detecting the spoken language.
+func NewWhisperCppTranscriber(model string) *WhisperCppTranscriber {
+	return &WhisperCppTranscriber{Model: model}
+}
+
+// Transcribe transcribes the video.
+func (t *WhisperCppTranscriber) Transcribe(ctx context.Context, videoPath string) ([]byte, string, error) {
+	dir, err := os.MkdirTemp("", "subtitle-whisper-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	audio := filepath.Join(dir, "audio.wav")
+	if err := extractAudio(ctx, videoPath, audio, "-c:a", "pcm_s16le"); err != nil {
+		return nil, "", err
+	}
+
+	binary, language := t.Binary, t.Language
+	if binary == "" {
+		binary = whisperCppPath
+	}
+	if language == "" {
+		language = "auto"
+	}
+	output := filepath.Join(dir, "subtitle")
+	cmd := exec.CommandContext(ctx, binary,
+		"-m", t.Model,
+		"-f", audio,
+		"-l", language,
+		"-osrt", "-oj",
+		"-of", output,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("whisper.cpp failed: %w: %s", err, lastLine(out))
+	}
+
+	srt, err := os.ReadFile(output + ".srt")
+	if err != nil {
+		return nil, "", err
+	}
+	var transcript struct {
+		Result struct {
+			Language string `json:"language"`
+		} `json:"result"`
+	}
+	if data, err := os.ReadFile(output + ".json"); err == nil {
+		json.Unmarshal(data, &transcript)
+	}
+	return srt, spokenLanguage(transcript.Result.Language), nil
+}
+
+// WhisperAPITranscriber transcribes videos with a Whisper speech-to-text API:
+// OpenAI's, or a self-hosted server offering the same endpoint. The
+// soundtrack is decoded with ffmpeg and uploaded as Opus.
+type WhisperAPITranscriber struct {
+	URL      string // Transcriptions endpoint, e.g. "https://api.openai.com/v1/audio/transcriptions"
+	APIKey   string // Bearer token ("" for servers that need none)
+	Model    string // Model name ("whisper-1" if empty)
+	Language string // Spoken language as an ISO 639-1 code ("" to detect it)
+}
+
+// NewWhisperAPITranscriber returns a transcriber uploading soundtracks to
+// the transcriptions endpoint at url, authenticated with apiKey.
+func NewWhisperAPITranscriber(url, apiKey string) *WhisperAPITranscriber {
+	return &WhisperAPITranscriber{URL: url, APIKey: apiKey}
+}
+
+// Transcribe transcribes the video.
+func (t *WhisperAPITranscriber) Transcribe(ctx context.Context, videoPath string) ([]byte, string, error) {
+	dir, err := os.MkdirTemp("", "subtitle-whisper-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	audio := filepath.Join(dir, "audio.ogg")
+	if err := extractAudio(ctx, videoPath, audio, "-c:a", "libopus", "-b:a", "24k"); err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(audio)
+	if err != nil {
+		return nil, "", err
+	}
+
+	model := t.Model
+	if model == "" {
+		model = "whisper-1"
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", model)
+	form.WriteField("response_format", "verbose_json")
+	if t.Language != "" {
+		form.WriteField("language", t.Language)
+	}
+	file, err := form.CreateFormFile("file", filepath.Base(audio))
+	if err != nil {
+		return nil, "", err
+	}
+	file.Write(data)
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, &body)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, "", fmt.Errorf("transcription rejected: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var transcript struct {
+		Language string `json:"language"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return nil, "", fmt.Errorf("invalid transcription: %w", err)
+	}
+
+	var cues []srtCue
+	for _, segment := range transcript.Segments {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			cues = append(cues, srtCue{
+				Start: time.Duration(segment.Start * float64(time.Second)),
+				End:   time.Duration(segment.End * float64(time.Second)),
+				Lines: []string{text},
+			})
+		}
+	}
+	return []byte(formatSRT(cues, "\n")), spokenLanguage(transcript.Language), nil
+}
+
+// extractAudio decodes the first audio track of a video into a mono file
+// at the sample rate Whisper expects, encoded with the given codec options.
+func extractAudio(ctx context.Context, videoPath, audioPath string, codec ...string) error {
+	args := []string{"-v", "error", "-y", "-i", videoPath, "-map", "0:a:0", "-ac", "1", "-ar", fmt.Sprint(whisperSampleRate)}
+	args = append(append(args, codec...), audioPath)
+	if out, err := exec.CommandContext(ctx, ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, lastLine(out))
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of a command's output, which
+// usually holds its error message.
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// spokenLanguage converts a language reported by Whisper, a code such as
+// "en" or a name such as "english", into an ISO 639-1 code.
+func spokenLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[language]; ok {
+		return code
+	}
+	if len(language) == 2 {
+		return language
+	}
+	return ""
+}
+
+// plannedTranscriptions plans a generated subtitle for every video without
+// one. Generated subtitles are named like their video.
+func (vsm *VideoSubtitleMatcher) plannedTranscriptions(videoFiles, subtitleFiles []string, planned []MatchResult) []MatchResult {
+	var generated []MatchResult
+	for _, videoPath := range vsm.uncoveredVideos(videoFiles, subtitleFiles, planned) {
+		target := withExt(videoPath, ".srt")
+		generated = append(generated, MatchResult{
+			SubtitlePath:    target,
+			VideoPath:       videoPath,
+			NewSubtitlePath: target,
+			Generated:       true,
+		})
+	}
+	return generated
+}
+
+// uncoveredVideos returns the videos that neither a planned subtitle nor
+// one of the scanned subtitles, named after them, belongs to.
+func (vsm *VideoSubtitleMatcher) uncoveredVideos(videoFiles, subtitleFiles []string, planned []MatchResult) []string {
+	covered := make(map[string]bool, len(planned))
+	for _, result := range planned {
+		if result.VideoPath != "" && result.NewSubtitlePath != "" {
+			covered[result.VideoPath] = true
+		}
+	}
+	for _, subtitlePath := range subtitleFiles {
+		covered[vsm.namedVideo(subtitlePath, videoFiles)] = true
+	}
+
+	var uncovered []string
+	for _, videoPath := range videoFiles {
+		if !covered[videoPath] {
+			uncovered = append(uncovered, videoPath)
+		}
+	}
+	return uncovered
+}
+
+// namedVideo returns the video a subtitle is named after, as players pair
+// them: "Movie.srt" or "Movie.en.srt" for "Movie.mkv". The longest name wins,
+// so "Movie.Part2.srt" belongs to "Movie.Part2.mkv" rather than "Movie.mkv".
+// It returns "" when the subtitle is named after none of the videos.
+func (vsm *VideoSubtitleMatcher) namedVideo(subtitlePath string, videoFiles []string) string {
+	named := ""
+	for _, videoPath := range videoFiles {
+		base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+		if strings.HasPrefix(subtitlePath, base+".") && len(videoPath) > len(named) {
+			named = videoPath
+		}
+	}
+	return named
+}
+
+// generateSubtitle writes the subtitle transcribed from a video, named
+// "Movie.en.srt" when its language is known. An existing file is never
+// overwritten. In dry run mode the subtitle is only reported.
+func (vsm *VideoSubtitleMatcher) generateSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	if vsm.dryRun {
+		vsm.logGenerated(result)
+		return result
+	}
+	if !vsm.isLocal() {
+		result.Error = fmt.Errorf("subtitles can only be generated for videos on the local file system")
+		return result
+	}
+
+	srt, language, err := vsm.transcriber.Transcribe(ctx, result.VideoPath)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to generate subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error generating subtitle for %s: %v\n", filepath.Base(result.VideoPath), err)
+		}
+		return result
+	}
+
+	if language != "" {
+		result.NewSubtitlePath = withExt(result.VideoPath, "."+language+".srt")
+		result.SubtitlePath = result.NewSubtitlePath
+		result.Language = language
+	}
+	if _, err := vsm.fs.Stat(result.NewSubtitlePath); err == nil {
+		result.Error = fmt.Errorf("failed to generate subtitle: %s already exists", filepath.Base(result.NewSubtitlePath))
+		return result
+	}
+	if err := vsm.fs.WriteFile(result.NewSubtitlePath, srt); err != nil {
+		result.Error = fmt.Errorf("failed to generate subtitle: %w", err)
+		return result
+	}
+
+	result.Renamed = true
+	vsm.logGenerated(result)
+	return result
+}
+
+// logGenerated logs a subtitle generated for a video without one
+func (vsm *VideoSubtitleMatcher) logGenerated(result MatchResult) {
+	if !vsm.verbose {
+		return
+	}
+
+	fmt.Printf("\nNo subtitle for: %s\n", filepath.Base(result.VideoPath))
+	if vsm.dryRun {
+		fmt.Printf("  Would generate: %s\n", filepath.Base(result.NewSubtitlePath))
+		return
+	}
+	vsm.printf(colorGreen, "  ✓ Generated: %s\n", filepath.Base(result.NewSubtitlePath))
+}
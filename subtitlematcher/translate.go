@@ -0,0 +1,234 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// translateBatch is the number of cues sent to a Translator at once.
+const translateBatch = 50
+
+// deepLFreeSuffix ends the authentication keys of DeepL's free API, which
+// has its own endpoint.
+const deepLFreeSuffix = ":fx"
+
+// Translator translates subtitle text, e.g. with a machine translation
+// service. See Translate.
+type Translator interface {
+	// Translate returns texts translated from the language from into the
+	// language to, one for each text and in the same order. Languages are
+	// ISO 639-1 codes; from is "" when it is unknown.
+	Translate(ctx context.Context, texts []string, from, to string) ([]string, error)
+}
+
+// LibreTranslateTranslator translates with a LibreTranslate server, such as
+// a self-hosted one.
+type LibreTranslateTranslator struct {
+	URL    string // Translation endpoint, e.g. "http://localhost:5000/translate"
+	APIKey string // API key ("" for servers that need none)
+}
+
+// NewLibreTranslateTranslator returns a translator using the LibreTranslate
+// endpoint at url, authenticated with apiKey.
+func NewLibreTranslateTranslator(url, apiKey string) *LibreTranslateTranslator {
+	return &LibreTranslateTranslator{URL: url, APIKey: apiKey}
+}
+
+// Translate translates the texts.
+func (t *LibreTranslateTranslator) Translate(ctx context.Context, texts []string, from, to string) ([]string, error) {
+	if from == "" {
+		from = "auto"
+	}
+	body, err := json.Marshal(map[string]any{
+		"q":       texts,
+		"source":  from,
+		"target":  to,
+		"format":  "text",
+		"api_key": t.APIKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	if err := translationRequest(ctx, t.URL, "application/json", bytes.NewReader(body), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.TranslatedText, nil
+}
+
+// DeepLTranslator translates with the DeepL API.
+type DeepLTranslator struct {
+	AuthKey string // DeepL authentication key; keys of the free API end in ":fx"
+}
+
+// NewDeepLTranslator returns a translator using the DeepL API with authKey.
+func NewDeepLTranslator(authKey string) *DeepLTranslator {
+	return &DeepLTranslator{AuthKey: authKey}
+}
+
+// Translate translates the texts.
+func (t *DeepLTranslator) Translate(ctx context.Context, texts []string, from, to string) ([]string, error) {
+	endpoint := "https://api.deepl.com/v2/translate"
+	if strings.HasSuffix(t.AuthKey, deepLFreeSuffix) {
+		endpoint = "https://api-free.deepl.com/v2/translate"
+	}
+	form := url.Values{"text": texts, "target_lang": {strings.ToUpper(to)}}
+	if from != "" {
+		form.Set("source_lang", strings.ToUpper(from))
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + t.AuthKey}
+
+	var response struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := translationRequest(ctx, endpoint, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), headers, &response); err != nil {
+		return nil, err
+	}
+	translated := make([]string, len(response.Translations))
+	for i, translation := range response.Translations {
+		translated[i] = translation.Text
+	}
+	return translated, nil
+}
+
+// translationRequest POSTs a request to a translation service and decodes
+// its JSON response, failing on any non-2xx response.
+func translationRequest(ctx context.Context, endpoint, contentType string, body io.Reader, headers map[string]string, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("translation rejected: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("invalid translation response: %w", err)
+	}
+	return nil
+}
+
+// translateCues translates the text of cues in batches, keeping their
+// timing. The lines of a cue are translated together, as they usually form
+// one sentence.
+func translateCues(ctx context.Context, translator Translator, cues []srtCue, from, to string) ([]srtCue, error) {
+	translated := make([]srtCue, 0, len(cues))
+	for start := 0; start < len(cues); start += translateBatch {
+		batch := cues[start:min(start+translateBatch, len(cues))]
+		texts := make([]string, len(batch))
+		for i, cue := range batch {
+			texts[i] = strings.Join(cue.Lines, "\n")
+		}
+
+		results, err := translator.Translate(ctx, texts, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(texts) {
+			return nil, fmt.Errorf("translated %d of %d cues", len(results), len(texts))
+		}
+		for i, cue := range batch {
+			cue.Lines = strings.Split(strings.TrimSpace(results[i]), "\n")
+			translated = append(translated, cue)
+		}
+	}
+	return translated, nil
+}
+
+// translateSubtitle writes a machine-translated copy of a matched .srt
+// subtitle, or of the text read from it by OCR, next to its video as
+// "Movie.zh.srt". Subtitles already in the target language are left alone,
+// and names that exist or are planned for other subtitles are never
+// overwritten, so each video gets one translation. In dry run mode the file
+// that would be written is reported but not created.
+func (vsm *VideoSubtitleMatcher) translateSubtitle(ctx context.Context, result MatchResult) MatchResult {
+	source := result.NewSubtitlePath
+	if result.OCRSubtitlePath != "" {
+		source = result.OCRSubtitlePath
+	}
+	if !strings.EqualFold(filepath.Ext(source), ".srt") || result.DuplicateOf != "" ||
+		result.Language == vsm.translateTo {
+		return result
+	}
+
+	target := withExt(result.VideoPath, "."+vsm.translateTo+".srt")
+	if _, err := vsm.fs.Stat(target); err == nil || vsm.plannedNames[target] {
+		return result
+	}
+	vsm.plannedNames[target] = true
+
+	if vsm.dryRun {
+		result.TranslationPath = target
+		vsm.logTranslated(result)
+		return result
+	}
+
+	doc, err := readSRT(vsm.fs, source)
+	if err == nil && doc.Invalid {
+		err = fmt.Errorf("subtitle cannot be parsed")
+	}
+	var cues []srtCue
+	if err == nil {
+		cues, err = translateCues(ctx, vsm.translator, doc.Cues, result.Language, vsm.translateTo)
+	}
+	if err == nil {
+		err = vsm.fs.WriteFile(target, []byte(formatSRT(cues, "\n")))
+	}
+	if err != nil {
+		result.Error = fmt.Errorf("failed to translate subtitle: %w", err)
+		if vsm.verbose {
+			vsm.printf(colorRed, "  Error translating: %v\n", err)
+		}
+		return result
+	}
+
+	result.TranslationPath = target
+	vsm.logTranslated(result)
+	return result
+}
+
+// logTranslated logs the translated copy written for a subtitle
+func (vsm *VideoSubtitleMatcher) logTranslated(result MatchResult) {
+	if !vsm.verbose {
+		return
+	}
+
+	if vsm.dryRun {
+		fmt.Printf("  Would translate into: %s\n", filepath.Base(result.TranslationPath))
+		return
+	}
+	vsm.printf(colorGreen, "  ✓ Translated into: %s\n", filepath.Base(result.TranslationPath))
+}
+
+// addTranslated adds the name of the translation into language of every
+// planned subtitle's video that a subtitle in that language is matched to,
+// so that such videos are not given a translation.
+func (names subtitleNames) addTranslated(planned []MatchResult, language string) {
+	for _, result := range planned {
+		if result.VideoPath != "" && result.NewSubtitlePath != "" && result.Language == language {
+			names[withExt(result.VideoPath, "."+language+".srt")] = true
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package subtitlematcher
+
+import (
+	"math"
+	"strings"
+)
+
+// trigramOverlap is the share of a subtitle's trigram weight a video must
+// contain to be scored when the trigram index is used.
+const trigramOverlap = 0.25
+
+// trigramIndex maps every trigram of the normalized video titles to the
+// videos containing it, so each subtitle is only scored against videos that
+// share a good part of its title instead of against the whole library.
+// Trigrams weigh more the fewer videos contain them, so release tags shared
+// by the whole library, such as "1080p", count for nothing.
+type trigramIndex struct {
+	ids      map[string]int   // Video path → position in the scan
+	postings map[string][]int // Trigram → positions of the videos containing it
+}
+
+// trigramsOf returns the distinct trigrams of a normalized title. The title
+// is padded with spaces so short words still produce trigrams.
+func trigramsOf(title string) []string {
+	padded := " " + title + " "
+	seen := make(map[string]bool)
+	var trigrams []string
+	for i := 0; i+3 <= len(padded); i++ {
+		trigram := padded[i : i+3]
+		if !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return trigrams
+}
+
+// buildVideoIndex indexes the normalized titles of videos, including their
+// metadata aliases when those are enabled.
+func (vsm *VideoSubtitleMatcher) buildVideoIndex(videoFiles []string) *trigramIndex {
+	index := &trigramIndex{ids: make(map[string]int, len(videoFiles)), postings: make(map[string][]int)}
+	for id, videoPath := range videoFiles {
+		index.ids[videoPath] = id
+		titles := []string{vsm.strippedVideo(videoPath)}
+		for _, alias := range vsm.videoAliases(videoPath) {
+			titles = append(titles, stripPart(vsm.normalizeTitle(alias)))
+		}
+		for _, trigram := range trigramsOf(strings.Join(titles, " ")) {
+			index.postings[trigram] = append(index.postings[trigram], id)
+		}
+	}
+	return index
+}
+
+// indexedVideos narrows videoFiles down to the videos sharing at least
+// trigramOverlap of the subtitle's trigram weight. When none do, all of
+// videoFiles are kept so that the subtitle is still scored as usual.
+func (vsm *VideoSubtitleMatcher) indexedVideos(subtitlePath string, videoFiles []string) []string {
+	if vsm.videoIndex == nil {
+		return videoFiles
+	}
+
+	trigrams := trigramsOf(stripPart(vsm.normalizeTitle(subtitleTitle(subtitlePath))))
+	videoCount := float64(len(vsm.videoIndex.ids))
+	shared := make([]float64, len(vsm.videoIndex.ids))
+	var total float64
+	for _, trigram := range trigrams {
+		postings := vsm.videoIndex.postings[trigram]
+		weight := math.Log((videoCount + 1) / float64(len(postings)+1))
+		total += weight
+		for _, id := range postings {
+			shared[id] += weight
+		}
+	}
+
+	needed := trigramOverlap * total
+	var candidates []string
+	for _, videoPath := range videoFiles {
+		if id, ok := vsm.videoIndex.ids[videoPath]; ok && shared[id] > 0 && shared[id] >= needed {
+			candidates = append(candidates, videoPath)
+		}
+	}
+	if len(candidates) == 0 {
+		return videoFiles
+	}
+	return candidates
+}
@@ -0,0 +1,222 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoRename is returned when the history holds no rename of a file.
+var ErrNoRename = errors.New("no recorded rename")
+
+// ErrNotUndoable is returned for runs that did not move files on local disk,
+// such as runs on a remote file system or with a Renamer, which undo cannot
+// reverse, and for runs recorded with relative paths.
+var ErrNotUndoable = errors.New("run cannot be undone")
+
+// Reversal is the outcome of undoing one recorded rename.
+type Reversal struct {
+	SubtitlePath string // Original subtitle path, restored by the undo
+	RenamedPath  string // Path the run had renamed the subtitle to
+	Error        error  // Why the rename could not be undone, if it could not
+}
+
+// UndoRun moves every subtitle renamed by the recorded run back to its
+// original name, in reverse order, and then moves the duplicates the run
+// cleaned up back from the cleanup folder or trash. Renames that cannot be
+// undone, because the subtitle was converted to another format, has since
+// been moved or its original name is taken, are reported in their Reversal
+// and skipped. Content changes such as repairs are not reverted. Returns
+// ErrNotUndoable for runs that did not move files on local disk or were
+// recorded with relative paths.
+func UndoRun(store HistoryStore, id string) ([]Reversal, error) {
+	run, err := FindRun(store, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := run.undoable(); err != nil {
+		return nil, err
+	}
+
+	lock, err := acquireLock(run.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock directory: %w", err)
+	}
+	defer lock.release()
+
+	renames := run.Renames()
+	reversals := make([]Reversal, 0, len(renames))
+	for i := len(renames) - 1; i >= 0; i-- {
+		reversals = append(reversals, undoRename(renames[i]))
+	}
+	// Restored after the renames, which may have taken the duplicates' names
+	for _, result := range run.CleanedUp() {
+		reversals = append(reversals, undoCleanup(result))
+	}
+	return reversals, nil
+}
+
+// UndoFile undoes the most recent recorded rename of a subtitle, or move of
+// a duplicate by CleanupDuplicates, identified by either its original or its
+// new path. Returns ErrNoRename when no
+// recorded run renamed it, and ErrNotUndoable when that run did not move
+// files on local disk or was recorded with relative paths.
+func UndoFile(store HistoryStore, path string) (Reversal, error) {
+	runs, err := store.Runs()
+	if err != nil {
+		return Reversal{}, err
+	}
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		for _, result := range runs[i].Renames() {
+			if !samePath(path, result.SubtitlePath) && !samePath(path, result.NewSubtitlePath) {
+				continue
+			}
+			if err := runs[i].undoable(); err != nil {
+				return Reversal{}, err
+			}
+
+			lock, err := acquireLock(runs[i].Directory)
+			if err != nil {
+				return Reversal{}, fmt.Errorf("failed to lock directory: %w", err)
+			}
+			defer lock.release()
+
+			return undoRename(result), nil
+		}
+		for _, result := range runs[i].CleanedUp() {
+			if !samePath(path, result.SubtitlePath) && !samePath(path, result.CleanedUpPath) {
+				continue
+			}
+			if err := runs[i].undoable(); err != nil {
+				return Reversal{}, err
+			}
+
+			lock, err := acquireLock(runs[i].Directory)
+			if err != nil {
+				return Reversal{}, fmt.Errorf("failed to lock directory: %w", err)
+			}
+			defer lock.release()
+
+			return undoCleanup(result), nil
+		}
+	}
+	return Reversal{}, fmt.Errorf("%w: %s", ErrNoRename, path)
+}
+
+// undoable returns ErrNotUndoable, with the reason, unless the run moved
+// files on local disk, the only renames undo can reverse, and was recorded
+// with absolute paths. Relative paths were relative to a working directory
+// that is no longer known, so undo could act on the wrong files.
+func (r Run) undoable() error {
+	switch {
+	case r.Settings.FileSystem != "":
+		return fmt.Errorf("%w: run %s used the %s file system", ErrNotUndoable, r.ID, r.Settings.FileSystem)
+	case r.Settings.Renamer != "":
+		return fmt.Errorf("%w: run %s used the %s renamer", ErrNotUndoable, r.ID, r.Settings.Renamer)
+	case !filepath.IsAbs(r.Directory):
+		return fmt.Errorf("%w: run %s was recorded with the relative directory %s", ErrNotUndoable, r.ID, r.Directory)
+	}
+	for _, result := range append(r.Renames(), r.CleanedUp()...) {
+		for _, path := range []string{result.SubtitlePath, result.NewSubtitlePath, result.PairedPath, result.CleanedUpPath} {
+			if path != "" && !filepath.IsAbs(path) {
+				return fmt.Errorf("%w: run %s was recorded with the relative path %s", ErrNotUndoable, r.ID, path)
+			}
+		}
+	}
+	return nil
+}
+
+// undoRename moves a renamed subtitle back to its original path.
+func undoRename(result MatchResult) Reversal {
+	reversal := Reversal{SubtitlePath: result.SubtitlePath, RenamedPath: result.NewSubtitlePath}
+
+	switch {
+	case result.Archive != "":
+		// The subtitle is still in its archive, so undoing removes the copy
+		reversal.Error = os.Remove(result.NewSubtitlePath)
+	case result.Converted:
+		reversal.Error = fmt.Errorf("cannot undo conversion to %s: original file is gone", filepath.Base(result.NewSubtitlePath))
+	case !fileExists(result.NewSubtitlePath):
+		reversal.Error = fmt.Errorf("%s no longer exists", result.NewSubtitlePath)
+	case fileExists(result.SubtitlePath):
+		reversal.Error = fmt.Errorf("%s already exists", result.SubtitlePath)
+	case result.PairedPath != "":
+		reversal.Error = undoVobSub(result)
+	default:
+		reversal.Error = os.Rename(result.NewSubtitlePath, result.SubtitlePath)
+	}
+	return reversal
+}
+
+// undoCleanup moves a duplicate moved by CleanupDuplicates back to its
+// original path, along with the .sub of a VobSub index, and removes the info
+// files the trash kept for it.
+func undoCleanup(result MatchResult) Reversal {
+	reversal := Reversal{SubtitlePath: result.SubtitlePath, RenamedPath: result.CleanedUpPath}
+
+	paths := []string{result.SubtitlePath}
+	if result.PairedPath != "" {
+		paths = append(paths, result.PairedPath)
+	}
+	for _, path := range paths {
+		if fileExists(path) {
+			reversal.Error = fmt.Errorf("%s already exists", path)
+			return reversal
+		}
+	}
+	if !fileExists(result.CleanedUpPath) {
+		reversal.Error = fmt.Errorf("%s no longer exists", result.CleanedUpPath)
+		return reversal
+	}
+
+	for i, path := range paths {
+		moved := withExt(result.CleanedUpPath, filepath.Ext(path))
+		if err := moveFile(moved, path); err != nil {
+			for _, restored := range paths[:i] {
+				moveFile(restored, withExt(result.CleanedUpPath, filepath.Ext(restored)))
+			}
+			reversal.Error = err
+			return reversal
+		}
+		removeTrashInfo(moved)
+	}
+	return reversal
+}
+
+// removeTrashInfo removes the .trashinfo file of a file restored from a
+// freedesktop.org trash, which keeps it in "files" next to "info". Files from
+// anywhere else have none.
+func removeTrashInfo(path string) {
+	files := filepath.Dir(path)
+	if filepath.Base(files) != "files" {
+		return
+	}
+	info := filepath.Join(filepath.Dir(files), "info", filepath.Base(path)+".trashinfo")
+	if fileExists(info) {
+		os.Remove(info)
+	}
+}
+
+// samePath reports whether two paths name the same file location.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// undoVobSub moves both files of a renamed VobSub pair back.
+func undoVobSub(result MatchResult) error {
+	renamedData := withExt(result.NewSubtitlePath, vobSubDataExt)
+	if fileExists(result.PairedPath) {
+		return fmt.Errorf("%s already exists", result.PairedPath)
+	}
+	if err := os.Rename(renamedData, result.PairedPath); err != nil {
+		return err
+	}
+	return os.Rename(result.NewSubtitlePath, result.SubtitlePath)
+}
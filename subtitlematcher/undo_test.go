@@ -0,0 +1,163 @@
+package subtitlematcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// remoteFileSystem stands in for a remote backend; it stores files on local
+// disk but is not LocalFileSystem.
+type remoteFileSystem struct {
+	LocalFileSystem
+}
+
+func TestUndoRefusesRunsNotMovingLocalFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		undoable bool
+	}{
+		{"local", nil, true},
+		{"remote", []Option{UseFileSystem(remoteFileSystem{})}, false},
+		{"copy", []Option{UseRenamer(CopyRenamer{})}, false},
+		{"link", []Option{UseRenamer(LinkRenamer{})}, false},
+		{"recording", []Option{UseRenamer(&RecordingRenamer{})}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, "The Great Escape 1963.mkv", "great.escape.1963.srt")
+			history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+
+			options := append([]Option{Verbose(false), DryRun(false), History(history)}, tt.options...)
+			if _, err := New(dir, options...).Match(); err != nil {
+				t.Fatal(err)
+			}
+			runs, err := history.Runs()
+			if err != nil || len(runs) != 1 {
+				t.Fatalf("recorded runs = %d, %v", len(runs), err)
+			}
+			before := dirNames(t, dir)
+
+			_, fileErr := UndoFile(history, filepath.Join(dir, "great.escape.1963.srt"))
+			reversals, runErr := UndoRun(history, runs[0].ID)
+			if !tt.undoable {
+				for _, err := range []error{fileErr, runErr} {
+					if !errors.Is(err, ErrNotUndoable) {
+						t.Errorf("error = %v, want ErrNotUndoable", err)
+					}
+				}
+				if after := dirNames(t, dir); after != before {
+					t.Errorf("files = %s, want them untouched: %s", after, before)
+				}
+				return
+			}
+
+			if fileErr != nil || runErr != nil {
+				t.Fatalf("UndoFile: %v, UndoRun: %v", fileErr, runErr)
+			}
+			// UndoFile already restored the subtitle, so the run finds its original name taken
+			if len(reversals) != 1 || reversals[0].Error == nil {
+				t.Errorf("reversals = %+v, want the rename skipped", reversals)
+			}
+			if after := dirNames(t, dir); after != "The Great Escape 1963.mkv great.escape.1963.srt" {
+				t.Errorf("files = %s, want the subtitle restored", after)
+			}
+		})
+	}
+}
+
+// dirNames returns the names of the files in dir, space separated.
+func dirNames(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := ""
+	for i, entry := range entries {
+		if i > 0 {
+			names += " "
+		}
+		names += entry.Name()
+	}
+	return names
+}
+
+func TestUndoRunRestoresCleanedUpDuplicates(t *testing.T) {
+	dir := writeFiles(t, "Movie.mkv", "movie.a.srt", "movie.b.srt")
+	history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	folder := filepath.Join(t.TempDir(), "duplicates")
+
+	options := []Option{Verbose(false), DryRun(false), History(history),
+		MultiMatchPolicy(MultiMatchKeepBest, nil), CleanupDuplicates(CleanupMove, folder)}
+	if _, err := New(dir, options...).Match(); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := history.Runs()
+	if err != nil || len(runs) != 1 || len(runs[0].CleanedUp()) != 1 {
+		t.Fatalf("recorded runs = %+v, %v, want one with a cleaned up duplicate", runs, err)
+	}
+
+	reversals, err := UndoRun(history, runs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reversal := range reversals {
+		if reversal.Error != nil {
+			t.Errorf("%s not restored: %v", reversal.SubtitlePath, reversal.Error)
+		}
+	}
+	if after := dirNames(t, dir); after != "Movie.mkv movie.a.srt movie.b.srt" {
+		t.Errorf("files = %s, want the library restored", after)
+	}
+}
+
+func TestUndoRunFromAnotherWorkingDirectory(t *testing.T) {
+	dir := writeFiles(t, "The Great Escape 1963.mkv", "great.escape.1963.srt")
+	history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	chdir(t, dir)
+	if _, err := New(".", Verbose(false), DryRun(false), History(history)).Match(); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := history.Runs()
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("recorded runs = %d, %v", len(runs), err)
+	}
+
+	// Same-named files here must be left alone
+	other := writeFiles(t, "The Great Escape 1963.srt")
+	chdir(t, other)
+	reversals, err := UndoRun(history, runs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reversals) != 1 || reversals[0].Error != nil {
+		t.Errorf("reversals = %+v, want the rename undone", reversals)
+	}
+	if after := dirNames(t, dir); after != "The Great Escape 1963.mkv great.escape.1963.srt" {
+		t.Errorf("files = %s, want the subtitle restored", after)
+	}
+	if after := dirNames(t, other); after != "The Great Escape 1963.srt" {
+		t.Errorf("files in the working directory = %s, want them untouched", after)
+	}
+}
+
+func TestUndoRefusesRunsWithRelativePaths(t *testing.T) {
+	history := NewFileHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	run := Run{ID: "old", Directory: ".", Results: []MatchResult{
+		{SubtitlePath: "a.srt", NewSubtitlePath: "Movie.srt", Renamed: true},
+	}}
+	if err := history.Record(run); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UndoRun(history, "old"); !errors.Is(err, ErrNotUndoable) {
+		t.Errorf("UndoRun error = %v, want ErrNotUndoable", err)
+	}
+	if _, err := UndoFile(history, "Movie.srt"); !errors.Is(err, ErrNotUndoable) {
+		t.Errorf("UndoFile error = %v, want ErrNotUndoable", err)
+	}
+}
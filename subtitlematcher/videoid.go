@@ -0,0 +1,32 @@
+package subtitlematcher
+
+import "regexp"
+
+// videoIDPattern matches the video IDs downloaders put in file names: an
+// 11-character YouTube ID in brackets, e.g. "[dQw4w9WgXcQ]", or a Bilibili
+// BV ID, e.g. "BV1xx411c7mD".
+var videoIDPattern = regexp.MustCompile(`\[([A-Za-z0-9_-]{11})\]|(?:^|[^0-9A-Za-z])(BV1[0-9A-Za-z]{9})(?:[^0-9A-Za-z]|$)`)
+
+// titleID returns the video ID in a file title: the id field of the output
+// template when the title fits it, otherwise a YouTube or Bilibili ID token.
+// Returns "" when the title has none.
+func (vsm *VideoSubtitleMatcher) titleID(title string) string {
+	if id := vsm.templateField(title, "id"); id != "" {
+		return id
+	}
+	match := videoIDPattern.FindStringSubmatch(title)
+	if match == nil {
+		return ""
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
+
+// videoID returns the video ID in the name of a video.
+func (vsm *VideoSubtitleMatcher) videoID(videoPath string) string {
+	return vsm.videoIDs.lookup(videoPath, func() string {
+		return vsm.titleID(titleOf(videoPath))
+	})
+}
@@ -0,0 +1,100 @@
+package subtitlematcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// VobSub subtitles are image-based and come as a pair: an .idx index and the
+// .sub file holding the bitmaps. Players only find the pair when both share
+// the same base name, so the .idx is matched and the .sub follows it. Text
+// formats such as MicroDVD use the .sub extension too, so .sub files are
+// told apart by content.
+const (
+	vobSubIndexExt = ".idx"
+	vobSubDataExt  = ".sub"
+)
+
+// mpegPackHeader starts every VobSub .sub file, which is an MPEG program stream.
+var mpegPackHeader = []byte{0x00, 0x00, 0x01, 0xBA}
+
+// withExt replaces the extension of path.
+func withExt(path, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+// isVobSubIndex reports whether path is a VobSub .idx file.
+func isVobSubIndex(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), vobSubIndexExt)
+}
+
+// isVobSubData reports whether a .sub file holds VobSub bitmaps rather than
+// text. Unreadable files are reported as not VobSub.
+func isVobSubData(fsys FileSystem, path string) bool {
+	if !strings.EqualFold(filepath.Ext(path), vobSubDataExt) {
+		return false
+	}
+	file, err := fsys.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, len(mpegPackHeader))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, mpegPackHeader)
+}
+
+// vobSubData returns the VobSub .sub file paired with an .idx, if it exists.
+func vobSubData(fsys FileSystem, path string) string {
+	if !isVobSubIndex(path) {
+		return ""
+	}
+	data := withExt(path, vobSubDataExt)
+	if !isVobSubData(fsys, data) {
+		return ""
+	}
+	return data
+}
+
+// pairVobSubs replaces every scanned VobSub .sub that has an .idx next to it
+// with that .idx, so each pair is matched once. Text .sub files are kept.
+func pairVobSubs(fsys FileSystem, subtitleFiles []string) []string {
+	seen := make(map[string]bool, len(subtitleFiles))
+	paired := subtitleFiles[:0]
+	for _, path := range subtitleFiles {
+		if index := withExt(path, vobSubIndexExt); isVobSubData(fsys, path) && exists(fsys, index) {
+			path = index
+		}
+		if !seen[path] {
+			seen[path] = true
+			paired = append(paired, path)
+		}
+	}
+	return paired
+}
+
+// renameVobSub renames a VobSub pair. The .sub goes first and is moved back
+// if the .idx cannot follow, so the pair is never left split.
+func (vsm *VideoSubtitleMatcher) renameVobSub(ctx context.Context, result MatchResult) error {
+	newData := withExt(result.NewSubtitlePath, vobSubDataExt)
+	if err := vsm.withRetry(ctx, func(ctx context.Context) error { return vsm.rename(ctx, result.PairedPath, newData) }); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(result.PairedPath), err)
+	}
+
+	err := vsm.withRetry(ctx, func(ctx context.Context) error { return vsm.rename(ctx, result.SubtitlePath, result.NewSubtitlePath) })
+	if err != nil {
+		// The rollback must run even when the run was cancelled
+		if rollbackErr := vsm.rename(context.WithoutCancel(ctx), newData, result.PairedPath); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore %s: %w", filepath.Base(result.PairedPath), rollbackErr))
+		}
+	}
+	return err
+}
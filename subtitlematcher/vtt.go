@@ -0,0 +1,70 @@
+package subtitlematcher
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// vttTimingPattern matches a WebVTT cue timing line, capturing both timestamps.
+// Hours are optional in WebVTT, and cue settings may follow the end time.
+var vttTimingPattern = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})\s+-->\s+(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})`)
+
+// vttTagPattern matches WebVTT-only inline tags: voice, class, language and
+// ruby spans plus karaoke timestamps. Bold, italic and underline are kept
+// because SRT supports them.
+var vttTagPattern = regexp.MustCompile(`</?(?:v|c|lang|ruby|rt)(?:[.\s][^>]*)?>|<\d[\d:.]*>`)
+
+// parseVTT parses WebVTT content into cues, dropping the header, NOTE, STYLE
+// and REGION blocks, cue identifiers, cue settings and WebVTT-only tags.
+func parseVTT(content string) []srtCue {
+	content = strings.TrimPrefix(content, utf8BOM)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var cues []srtCue
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// Skip the cue identifier, if any, to reach the timing line
+		for len(lines) > 0 && !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		m := vttTimingPattern.FindStringSubmatch(strings.TrimSpace(lines[0]))
+		if m == nil {
+			continue
+		}
+
+		cue := srtCue{
+			Start: srtTimestamp(m[1], m[2], m[3], m[4]),
+			End:   srtTimestamp(m[5], m[6], m[7], m[8]),
+		}
+		for _, line := range lines[1:] {
+			line = strings.TrimSpace(html.UnescapeString(vttTagPattern.ReplaceAllString(line, "")))
+			if line != "" {
+				cue.Lines = append(cue.Lines, line)
+			}
+		}
+		if len(cue.Lines) > 0 {
+			cues = append(cues, cue)
+		}
+	}
+	return cues
+}
+
+// convertVTTToSRT writes a WebVTT subtitle as SRT to newPath and removes the original.
+func convertVTTToSRT(fsys FileSystem, path, newPath string) error {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return err
+	}
+
+	srt := formatSRT(parseVTT(string(data)), "\n")
+	if err := fsys.WriteFile(newPath, []byte(srt)); err != nil {
+		return err
+	}
+	return fsys.Remove(path)
+}